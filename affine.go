@@ -0,0 +1,53 @@
+package astiocr
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ConfigurationAffine represents an affine configuration
+// The proportion of images is transformed after rendering around its center, by a scale picked between
+// scale min and scale max (default is 0.9 and 1.1), a horizontal and a vertical shear of up to shear max
+// degrees (default is 10) and a translation of up to translate max times the image dimensions (default is
+// 0.05). Boxes are remapped accordingly.
+type ConfigurationAffine struct {
+	Proportion   float64 `toml:"proportion"`
+	ScaleMax     float64 `toml:"scale_max"`
+	ScaleMin     float64 `toml:"scale_min"`
+	ShearMax     float64 `toml:"shear_max"`
+	TranslateMax float64 `toml:"translate_max"`
+}
+
+// random returns the affine transformation, as the first 2 rows of its matrix, applied to images of the size
+func (c ConfigurationAffine) random(width, height int) []float64 {
+	// Pick parameters
+	s := c.ScaleMin + rand.Float64()*(c.ScaleMax-c.ScaleMin)
+	kx := math.Tan((rand.Float64()*2 - 1) * c.ShearMax * math.Pi / 180)
+	ky := math.Tan((rand.Float64()*2 - 1) * c.ShearMax * math.Pi / 180)
+	tx := (rand.Float64()*2 - 1) * c.TranslateMax * float64(width)
+	ty := (rand.Float64()*2 - 1) * c.TranslateMax * float64(height)
+
+	// Transform around the center
+	cx, cy := float64(width)/2, float64(height)/2
+	a, b, d, e := s, s*kx, s*ky, s
+	return []float64{a, b, cx + tx - a*cx - b*cy, d, e, cy + ty - d*cx - e*cy}
+}
+
+// validate applies the default affine options and checks them
+func (c *ConfigurationAffine) validate() (err error) {
+	if c.ScaleMin == 0 && c.ScaleMax == 0 {
+		c.ScaleMin, c.ScaleMax = 0.9, 1.1
+	}
+	if c.ShearMax == 0 {
+		c.ShearMax = 10
+	}
+	if c.TranslateMax == 0 {
+		c.TranslateMax = 0.05
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.ScaleMin <= 0 || c.ScaleMax < c.ScaleMin || c.ShearMax < 0 || c.ShearMax >= 90 || c.TranslateMax < 0 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid affine proportion %v with scale range [%v, %v], shear max %v and translate max %v", c.Proportion, c.ScaleMin, c.ScaleMax, c.ShearMax, c.TranslateMax))
+		return
+	}
+	return
+}
@@ -0,0 +1,285 @@
+package astiocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Below this IoU, a ground truth box is considered as not matched by any anchor. This is the default
+// matched threshold of the tensorflow object detection API.
+const anchorMatchedThreshold = 0.5
+
+// Regexps
+var (
+	regexpAnchorAspectRatio = regexp.MustCompile("aspect_ratios\\: ([\\d.]+)")
+	regexpAnchorMaxScale    = regexp.MustCompile("max_scale\\: ([\\d.]+)")
+	regexpAnchorMinScale    = regexp.MustCompile("min_scale\\: ([\\d.]+)")
+	regexpAnchorNumLayers   = regexp.MustCompile("num_layers\\: ([\\d]+)")
+	regexpResizerHeight     = regexp.MustCompile("fixed_shape_resizer \\{[^}]*height\\: ([\\d]+)")
+	regexpResizerWidth      = regexp.MustCompile("fixed_shape_resizer \\{[^}]*width\\: ([\\d]+)")
+	regexpSSDAnchor         = regexp.MustCompile("ssd_anchor_generator")
+)
+
+// AnchorReport represents an anchor report
+type AnchorReport struct {
+	Boxes     int
+	Classes   map[string]*AnchorReportClass
+	Input     AnchorReportInput
+	Layers    []AnchorReportLayer
+	Unmatched []AnchorReportBox
+}
+
+// AnchorReportInput represents the model input
+type AnchorReportInput struct {
+	Height int
+	Width  int
+}
+
+// AnchorReportLayer represents the anchors of a feature map
+type AnchorReportLayer struct {
+	// Feature map dimensions
+	Height int
+	Width  int
+
+	// Number of ground truth boxes best matched by this layer
+	Matched int
+
+	// Anchor scale relative to the input
+	Scale float64
+}
+
+// AnchorReportClass represents anchor stats for a class
+type AnchorReportClass struct {
+	Boxes        int
+	Matched      int
+	MedianHeight float64
+	MedianWidth  float64
+	heights      []float64
+	widths       []float64
+}
+
+// AnchorReportBox represents a ground truth box with no well-matched anchor
+type AnchorReportBox struct {
+	BestIoU float64
+	Height  float64
+	Image   string
+	Label   string
+	Width   float64
+}
+
+type anchor struct {
+	x1, x2, y1, y2 float64
+	layer          int
+}
+
+// Anchors maps the gathered ground truth boxes to the anchor grid of the configured model and reports
+// boxes that have no well-matched anchor. Configure and Gather must have been run beforehand.
+func (t *Trainer) Anchors(ctx context.Context) (r AnchorReport, err error) {
+	// Read config
+	p := filepath.Join(t.outputConfigDirectoryPath, "model.config")
+	var b []byte
+	if b, err = ioutil.ReadFile(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading %s failed", p)
+		return
+	}
+
+	// Generate anchors
+	var as []anchor
+	if as, err = r.parseAnchors(string(b)); err != nil {
+		err = errors.Wrapf(err, "astiocr: parsing anchors in %s failed", p)
+		return
+	}
+
+	// Loop through summaries
+	r.Classes = make(map[string]*AnchorReportClass)
+	for _, n := range []string{"training", "test"} {
+		// Check context
+		if err = ctx.Err(); err != nil {
+			err = errors.Wrap(err, "astiocr: context error")
+			return
+		}
+
+		// Read summary
+		var s GatherSummary
+		p = filepath.Join(t.outputDataDirectoryPath, n, "summary.json")
+		if s, err = readSummary(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: reading summary %s failed", p)
+			return
+		}
+
+		// Loop through boxes
+		for _, i := range s.Images {
+			for _, gb := range i.Boxes {
+				r.addBox(i, gb, as)
+			}
+		}
+	}
+
+	// Compute medians
+	for _, c := range r.Classes {
+		c.MedianHeight = median(c.heights)
+		c.MedianWidth = median(c.widths)
+	}
+
+	// Sort unmatched boxes by IoU
+	sort.Slice(r.Unmatched, func(i, j int) bool { return r.Unmatched[i].BestIoU < r.Unmatched[j].BestIoU })
+	return
+}
+
+func (r *AnchorReport) parseAnchors(c string) (as []anchor, err error) {
+	// Only SSD models are supported
+	if !regexpSSDAnchor.MatchString(c) {
+		err = fmt.Errorf("astiocr: only ssd anchor generators are supported")
+		return
+	}
+
+	// Parse
+	get := func(re *regexp.Regexp, def float64) float64 {
+		if m := re.FindStringSubmatch(c); len(m) >= 2 {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return v
+			}
+		}
+		return def
+	}
+	minScale := get(regexpAnchorMinScale, 0.2)
+	maxScale := get(regexpAnchorMaxScale, 0.95)
+	numLayers := int(get(regexpAnchorNumLayers, 6))
+	r.Input.Height = int(get(regexpResizerHeight, 300))
+	r.Input.Width = int(get(regexpResizerWidth, 300))
+	var ratios []float64
+	for _, m := range regexpAnchorAspectRatio.FindAllStringSubmatch(c, -1) {
+		if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+			ratios = append(ratios, v)
+		}
+	}
+	if len(ratios) == 0 {
+		ratios = []float64{1}
+	}
+
+	// Loop through layers
+	for l := 0; l < numLayers; l++ {
+		// Feature map dimensions are divided by 2 at each layer, starting with a stride of 16
+		fh := int(math.Ceil(float64(r.Input.Height) / 16 / math.Pow(2, float64(l))))
+		fw := int(math.Ceil(float64(r.Input.Width) / 16 / math.Pow(2, float64(l))))
+
+		// Get scales
+		scale := minScale
+		if numLayers > 1 {
+			scale = minScale + (maxScale-minScale)*float64(l)/float64(numLayers-1)
+		}
+		nextScale := 1.0
+		if l < numLayers-1 {
+			nextScale = minScale + (maxScale-minScale)*float64(l+1)/float64(numLayers-1)
+		}
+		r.Layers = append(r.Layers, AnchorReportLayer{
+			Height: fh,
+			Scale:  scale,
+			Width:  fw,
+		})
+
+		// Get shapes
+		type shape struct{ w, h float64 }
+		var ss []shape
+		for _, ratio := range ratios {
+			ss = append(ss, shape{w: scale * math.Sqrt(ratio), h: scale / math.Sqrt(ratio)})
+		}
+		ss = append(ss, shape{w: math.Sqrt(scale * nextScale), h: math.Sqrt(scale * nextScale)})
+
+		// Loop through cells
+		for y := 0; y < fh; y++ {
+			for x := 0; x < fw; x++ {
+				cx, cy := (float64(x)+0.5)/float64(fw), (float64(y)+0.5)/float64(fh)
+				for _, s := range ss {
+					as = append(as, anchor{
+						layer: l,
+						x1:    cx - s.w/2,
+						x2:    cx + s.w/2,
+						y1:    cy - s.h/2,
+						y2:    cy + s.h/2,
+					})
+				}
+			}
+		}
+	}
+	return
+}
+
+func (r *AnchorReport) addBox(i GatherSummaryImage, gb GatherSummaryBox, as []anchor) {
+	// Normalize box since images are resized to the model input
+	b := DetectionBox{
+		X1: float64(gb.X0) / float64(i.Width),
+		X2: float64(gb.X1) / float64(i.Width),
+		Y1: float64(gb.Y0) / float64(i.Height),
+		Y2: float64(gb.Y1) / float64(i.Height),
+	}
+
+	// Get best anchor
+	bestIoU, bestLayer := 0.0, -1
+	for _, a := range as {
+		if v := b.IoU(DetectionBox{X1: a.x1, X2: a.x2, Y1: a.y1, Y2: a.y2}); v > bestIoU {
+			bestIoU, bestLayer = v, a.layer
+		}
+	}
+
+	// Update class
+	c, ok := r.Classes[gb.Label]
+	if !ok {
+		c = &AnchorReportClass{}
+		r.Classes[gb.Label] = c
+	}
+	c.Boxes++
+	w, h := (b.X2-b.X1)*float64(r.Input.Width), (b.Y2-b.Y1)*float64(r.Input.Height)
+	c.heights = append(c.heights, h)
+	c.widths = append(c.widths, w)
+
+	// Update report
+	r.Boxes++
+	if bestIoU >= anchorMatchedThreshold {
+		c.Matched++
+		r.Layers[bestLayer].Matched++
+	} else {
+		r.Unmatched = append(r.Unmatched, AnchorReportBox{
+			BestIoU: bestIoU,
+			Height:  h,
+			Image:   i.Path,
+			Label:   gb.Label,
+			Width:   w,
+		})
+	}
+}
+
+func readSummary(p string) (s GatherSummary, err error) {
+	// Open file
+	var f *os.File
+	if f, err = os.Open(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Decode
+	if err = json.NewDecoder(f).Decode(&s); err != nil {
+		err = errors.Wrapf(err, "astiocr: decoding %s failed", p)
+		return
+	}
+	return
+}
+
+func median(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	sort.Float64s(vs)
+	return vs[len(vs)/2]
+}
@@ -0,0 +1,321 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	ft "golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Anonymize copies the gathered dataset to the anonymization output directory while replacing characters
+// matching the configured patterns with random characters of the same class, both in images, where
+// patches are re-rendered, and in annotations. Gather must have been run beforehand.
+func (t *Trainer) Anonymize(ctx context.Context) (n int, err error) {
+	// Init
+	rand.Seed(time.Now().UnixNano())
+
+	// No patterns
+	if len(t.anonymizationPatterns) == 0 {
+		err = fmt.Errorf("astiocr: no anonymization patterns provided")
+		return
+	}
+
+	// Create folders
+	t.l.Debugf("astiocr: removing %s", t.anonymizationOutputDirectoryPath)
+	if err = os.RemoveAll(t.anonymizationOutputDirectoryPath); err != nil {
+		err = errors.Wrapf(err, "astiocr: removeAll %s failed", t.anonymizationOutputDirectoryPath)
+		return
+	}
+	for _, p := range []string{
+		filepath.Join(t.anonymizationOutputDirectoryPath, "images"),
+		filepath.Join(t.anonymizationOutputDirectoryPath, "test"),
+		filepath.Join(t.anonymizationOutputDirectoryPath, "training"),
+	} {
+		t.l.Debugf("astiocr: creating %s", p)
+		if err = os.MkdirAll(p, 0700); err != nil {
+			err = errors.Wrapf(err, "astiocr: mkdirall %s failed", p)
+			return
+		}
+	}
+
+	// Copy label map
+	src, dst := filepath.Join(t.outputDataDirectoryPath, "label_map.pbtxt"), filepath.Join(t.anonymizationOutputDirectoryPath, "label_map.pbtxt")
+	var b []byte
+	if b, err = ioutil.ReadFile(src); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading %s failed", src)
+		return
+	}
+	if err = ioutil.WriteFile(dst, b, 0600); err != nil {
+		err = errors.Wrapf(err, "astiocr: writing %s failed", dst)
+		return
+	}
+
+	// Loop through summaries
+	for _, sn := range []string{"training", "test"} {
+		// Read summary
+		var s GatherSummary
+		p := filepath.Join(t.outputDataDirectoryPath, sn, "summary.json")
+		if s, err = readSummary(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: reading summary %s failed", p)
+			return
+		}
+
+		// Loop through images
+		for idx := range s.Images {
+			// Check context
+			if err = ctx.Err(); err != nil {
+				err = errors.Wrap(err, "astiocr: context error")
+				return
+			}
+
+			// Anonymize image
+			var c int
+			if c, err = t.anonymizeImage(&s.Images[idx]); err != nil {
+				err = errors.Wrapf(err, "astiocr: anonymizing %s failed", s.Images[idx].Path)
+				return
+			}
+			n += c
+		}
+
+		// Write summary
+		p = filepath.Join(t.anonymizationOutputDirectoryPath, sn, "summary.json")
+		if err = t.writeSummary(s, p); err != nil {
+			err = errors.Wrapf(err, "astiocr: writing summary to %s failed", p)
+			return
+		}
+	}
+
+	// Prepare data
+	if err = t.prepareData(ctx, t.anonymizationOutputDirectoryPath); err != nil {
+		err = errors.Wrap(err, "astiocr: preparing data failed")
+		return
+	}
+	return
+}
+
+func (t *Trainer) anonymizeImage(si *GatherSummaryImage) (n int, err error) {
+	// Decode image
+	var src image.Image
+	if src, err = decodeImageFile(si.Path); err != nil {
+		err = errors.Wrapf(err, "astiocr: decoding %s failed", si.Path)
+		return
+	}
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	// Group boxes into lines. Boxes with the same coordinates and label are indexed in order so that each
+	// assembled character is matched with its own box.
+	var rs []DetectionResult
+	type boxKey struct {
+		b     DetectionBox
+		label string
+	}
+	idxs := make(map[boxKey][]int)
+	for idx, b := range si.Boxes {
+		db := DetectionBox{
+			X1: float64(b.X0) / float64(si.Width),
+			X2: float64(b.X1) / float64(si.Width),
+			Y1: float64(b.Y0) / float64(si.Height),
+			Y2: float64(b.Y1) / float64(si.Height),
+		}
+		k := boxKey{b: db, label: b.Label}
+		idxs[k] = append(idxs[k], idx)
+		rs = append(rs, DetectionResult{Box: db, Label: b.Label, Probability: 1})
+	}
+	p := AssemblePage(rs, si.Width, si.Height, false)
+
+	// Keep original boxes so that words can be updated
+	obs := append([]GatherSummaryBox(nil), si.Boxes...)
+	anonymized := make(map[int]bool)
+
+	// Loop through lines so that matches can span several words
+	for _, b := range p.Blocks {
+		for _, l := range b.Lines {
+			// Get character offsets and boxes
+			var text string
+			var boxIdxs, offsets []int
+			for wi, w := range l.Words {
+				if wi > 0 {
+					text += " "
+				}
+				for _, c := range w.Chars {
+					k := boxKey{b: c.Box, label: c.Label}
+					boxIdxs = append(boxIdxs, idxs[k][0])
+					idxs[k] = idxs[k][1:]
+					offsets = append(offsets, len(text))
+					text += c.Label
+				}
+			}
+
+			// Loop through patterns
+			replaced := make(map[int]bool)
+			for _, r := range t.anonymizationPatterns {
+				for _, m := range r.FindAllStringIndex(text, -1) {
+					for ci, o := range offsets {
+						// Character is not part of the match
+						if o < m[0] || o >= m[1] || replaced[ci] {
+							continue
+						}
+
+						// Replace character
+						t.anonymizeBox(img, &si.Boxes[boxIdxs[ci]], si)
+						anonymized[boxIdxs[ci]] = true
+						replaced[ci] = true
+						n++
+					}
+				}
+			}
+		}
+	}
+
+	// Update words
+	anonymizeWords(si, obs, anonymized)
+
+	// Store image
+	pth := filepath.Join(t.anonymizationOutputDirectoryPath, "images", filepath.Base(si.Path))
+	if err = storePNG(pth, img); err != nil {
+		err = errors.Wrapf(err, "astiocr: storing %s failed", pth)
+		return
+	}
+	si.Path = pth
+	return
+}
+
+// anonymizeBox replaces the box label with a random label of the same class, re-renders it with the font the
+// box was drawn with on the same baseline and updates the box with the tight box of the new glyph
+func (t *Trainer) anonymizeBox(img *image.RGBA, b *GatherSummaryBox, si *GatherSummaryImage) {
+	// Get new label
+	var label string
+	for _, r := range b.Label {
+		label += string(t.randomRuneOfClass(r))
+	}
+
+	// Get colors
+	patch := img.SubImage(image.Rect(b.X0, b.Y0, b.X1, b.Y1)).(*image.RGBA)
+	background, fonts := profileColors(patch)
+	var fontColor color.Color = color.Black
+	if len(fonts) > 0 {
+		fontColor = fonts[0]
+	} else if luminance(background) < 0.5 {
+		fontColor = color.White
+	}
+
+	// Get font
+	f := t.summaryFont(si.Font, si.Style)
+	if len(b.Font) > 0 {
+		f = t.summaryFont(b.Font, b.Style)
+	}
+
+	// Summaries gathered before font sizes were recorded only provide the box height
+	fontSize := b.FontSize
+	if fontSize == 0 {
+		fontSize = b.Y1 - b.Y0
+	}
+
+	// Get the dot the original label was drawn at from its tight box
+	d := &ft.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fontColor),
+		Face: newFace(f, fontSize),
+	}
+	ob, _ := d.BoundString(b.Label)
+	d.Dot = fixed.P(b.X0-ob.Min.X.Floor(), b.Y1-ob.Max.Y.Ceil())
+
+	// Re-render label
+	draw.Draw(patch, patch.Bounds(), &image.Uniform{background}, image.ZP, draw.Src)
+	nb, _ := d.BoundString(label)
+	d.DrawString(label)
+
+	// Update box
+	r := glyphRect(nb).Intersect(img.Bounds())
+	b.Label = label
+	if rs := []rune(label); len(rs) == 1 {
+		if idx := t.labelIndex(rs[0]); idx > 0 {
+			b.LabelIndex = idx
+		}
+	}
+	b.X0, b.X1, b.Y0, b.Y1 = r.Min.X, r.Max.X, r.Min.Y, r.Max.Y
+}
+
+// anonymizeWords rewrites the labels of the words containing anonymized boxes. Boxes belong to the word whose
+// box contains their original center, in order. Labels that don't match their original boxes are dropped so
+// that sensitive strings don't remain in the summary.
+func anonymizeWords(si *GatherSummaryImage, obs []GatherSummaryBox, anonymized map[int]bool) {
+	for wi := range si.Words {
+		// Get boxes of the word
+		w := &si.Words[wi]
+		wr := image.Rect(w.X0, w.Y0, w.X1, w.Y1)
+		var idxs []int
+		var found bool
+		for idx, b := range obs {
+			if image.Pt((b.X0+b.X1)/2, (b.Y0+b.Y1)/2).In(wr) {
+				idxs = append(idxs, idx)
+				if anonymized[idx] {
+					found = true
+				}
+			}
+		}
+
+		// No anonymized box
+		if !found {
+			continue
+		}
+
+		// Rewrite label
+		ls := []rune(w.Label)
+		if len(ls) != len(idxs) {
+			w.Label = ""
+			continue
+		}
+		for i, idx := range idxs {
+			o, n := []rune(obs[idx].Label), []rune(si.Boxes[idx].Label)
+			if len(o) != 1 || len(n) != 1 || o[0] != ls[i] {
+				ls = nil
+				break
+			}
+			ls[i] = n[0]
+		}
+		w.Label = string(ls)
+	}
+}
+
+func (t *Trainer) randomRuneOfClass(r rune) rune {
+	// Get class
+	var class func(r rune) bool
+	switch {
+	case unicode.IsDigit(r):
+		class = unicode.IsDigit
+	case unicode.IsLower(r):
+		class = unicode.IsLower
+	case unicode.IsUpper(r):
+		class = unicode.IsUpper
+	case unicode.IsLetter(r):
+		// Letters of scripts without case, such as CJK
+		class = func(r rune) bool { return unicode.IsLetter(r) && !unicode.IsLower(r) && !unicode.IsUpper(r) }
+	default:
+		return r
+	}
+
+	// Only pick characters the model is trained on so that labels remain valid
+	var rs []rune
+	for _, c := range t.charset {
+		if class(c) {
+			rs = append(rs, c)
+		}
+	}
+	if len(rs) == 0 {
+		return r
+	}
+	return rs[rand.Intn(len(rs))]
+}
@@ -4,25 +4,35 @@ import (
 	"flag"
 
 	"context"
+	"fmt"
+	"os"
 
 	"sort"
 	"strings"
 
 	"github.com/asticode/go-astilog"
 	"github.com/asticode/go-astiocr"
+	"github.com/asticode/go-astiocr/server"
 	"github.com/asticode/go-astitools/config"
 	"github.com/asticode/go-astitools/flag"
 	"github.com/asticode/go-astitools/os"
 	"github.com/pkg/errors"
 )
 
+var addr = flag.String("a", "", "the address")
 var configPath = flag.String("c", "", "the config path")
+var model = flag.String("m", "", "the model name")
 var name = flag.String("n", "", "the name")
+var output = flag.String("o", "", "the output path")
 var path = flag.String("p", "", "the path")
+var workers = flag.Int("workers", 0, "the number of concurrent workers")
+var write = flag.Bool("w", false, "whether changes should be written")
+var timeout = flag.Duration("timeout", 0, "the timeout after which the subcommand is cancelled")
 var ctx, cancel = context.WithCancel(context.Background())
 
 type Configuration struct {
 	Detector astiocr.ConfigurationDetector `toml:"detector"`
+	HTTP     server.ConfigurationHTTP      `toml:"http"`
 	Trainer  astiocr.ConfigurationTrainer  `toml:"trainer"`
 }
 
@@ -32,6 +42,12 @@ func main() {
 	flag.Parse()
 	astilog.FlagInit()
 
+	// Timeout
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+	}
+	defer cancel()
+
 	// Handle signals
 	go astios.HandleSignals(astios.ContextSignalsFunc(cancel))
 
@@ -41,6 +57,13 @@ func main() {
 		astilog.Fatal(errors.Wrap(err, "main: parsing configuration failed"))
 	}
 	c := v.(*Configuration)
+	c.Detector.Logger = astiocr.NewAstilogLogger()
+	c.Trainer.Logger = astiocr.NewAstilogLogger()
+
+	// The detector decodes labels with the charset the model has been trained on
+	if len(c.Detector.Charset) == 0 {
+		c.Detector.Charset = c.Trainer.Charset
+	}
 
 	// Create trainer
 	t, err := astiocr.NewTrainer(c.Trainer)
@@ -50,6 +73,37 @@ func main() {
 
 	// Switch on subcommand
 	switch s {
+	case "anonymize":
+		// Anonymize
+		n, err := t.Anonymize(ctx)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: anonymizing failed"))
+		}
+		astilog.Infof("main: %d characters have been replaced", n)
+	case "anchors":
+		// Build report
+		r, err := t.Anchors(ctx)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: building anchors report failed"))
+		}
+
+		// Log
+		astilog.Infof("main: %d/%d boxes are matched by an anchor in a %dx%d input", r.Boxes-len(r.Unmatched), r.Boxes, r.Input.Width, r.Input.Height)
+		for idx, l := range r.Layers {
+			astilog.Infof("main: layer %d (%dx%d, scale %.2f) best matches %d boxes", idx, l.Width, l.Height, l.Scale, l.Matched)
+		}
+		var labels []string
+		for l := range r.Classes {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			c := r.Classes[l]
+			astilog.Infof("main: class %s: %d/%d boxes matched, median size %.1fx%.1f", l, c.Matched, c.Boxes, c.MedianWidth, c.MedianHeight)
+		}
+		for _, b := range r.Unmatched {
+			astilog.Debugf("main: unmatched box %s of size %.1fx%.1f in %s (best IoU %.2f)", b.Label, b.Width, b.Height, b.Image, b.BestIoU)
+		}
 	case "configure":
 		// Check flag
 		if len(*name) == 0 {
@@ -67,26 +121,80 @@ func main() {
 		}
 
 		// Create detectors
-		d, err := astiocr.NewDetector(c.Detector)
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
 		if err != nil {
 			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
 		}
 		defer d.Close()
 
+		// Detect in directory
+		if fi, err := os.Stat(*path); err == nil && fi.IsDir() {
+			if err = d.DetectDir(ctx, *path, *workers, func(r astiocr.DirResult) {
+				if r.Err != nil {
+					astilog.Error(errors.Wrapf(r.Err, "main: detecting in %s failed", r.Path))
+					return
+				}
+				astilog.Infof("main: %s", r.Path)
+				logResults(r.Results)
+			}); err != nil {
+				astilog.Fatal(errors.Wrapf(err, "main: detecting in %s failed", *path))
+			}
+			return
+		}
+
 		// Detect
 		var rs []astiocr.DetectionResult
-		if rs, err = d.Detect(ctx, *path); err != nil {
+		if rs, err = d.DetectWith(ctx, *model, *path); err != nil {
 			astilog.Fatal(errors.Wrapf(err, "main: detecting in %s failed", *path))
 		}
-		for _, r := range rs {
-			if r.Probability > 0.3 {
-				astilog.Infof("label: %s - probability: %.2f - box: %.2f --> %.2f --> %.2f --> %.2f", r.Label, r.Probability, r.Box.X1, r.Box.X2, r.Box.Y1, r.Box.Y2)
-			}
+		logResults(rs)
+	case "fonts":
+		ps, err := t.DownloadGoogleFonts(ctx)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: downloading google fonts failed"))
 		}
+		astilog.Infof("main: %d google font(s) are available", len(ps))
 	case "gather":
 		if err = t.Gather(ctx); err != nil {
 			astilog.Fatal(errors.Wrap(err, "main: gathering failed"))
 		}
+	case "grpc":
+		// Check flag
+		if len(*addr) == 0 {
+			astilog.Fatal("main: use -a to indicate an address")
+		}
+
+		// Create detector
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
+		}
+		defer d.Close()
+
+		// Serve
+		if err = server.NewGRPC(d).Serve(ctx, *addr); err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: serving grpc on %s failed", *addr))
+		}
+	case "heatmap":
+		// Check flags
+		if len(*path) == 0 {
+			astilog.Fatal("main: use -p to indicate a picture path")
+		}
+		if len(*output) == 0 {
+			astilog.Fatal("main: use -o to indicate an output path")
+		}
+
+		// Create detector
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
+		}
+		defer d.Close()
+
+		// Render heatmap
+		if err = d.HeatmapFile(ctx, *model, *path, *output); err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: rendering heatmap of %s failed", *path))
+		}
 	case "list":
 		var m map[string]string
 		if m, err = t.TrainedModels(ctx); err != nil {
@@ -98,7 +206,97 @@ func main() {
 		}
 		sort.Strings(models)
 		astilog.Infof("main: trained models are\n- %s", strings.Join(models, "\n- "))
+	case "preprocess":
+		// Check flags
+		if len(*path) == 0 {
+			astilog.Fatal("main: use -p to indicate a picture path")
+		}
+		if len(*output) == 0 {
+			astilog.Fatal("main: use -o to indicate an output path")
+		}
+
+		// Create detector
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
+		}
+		defer d.Close()
+
+		// Preprocess
+		if err = d.PreprocessFile(*path, *output); err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: preprocessing %s failed", *path))
+		}
+	case "profile":
+		// Check flag
+		if len(*path) == 0 {
+			astilog.Fatal("main: use -p to indicate a directory path")
+		}
+
+		// Profile
+		p, err := astiocr.ProfileImages(ctx, *path)
+		if err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: profiling images in %s failed", *path))
+		}
+		astilog.Infof("main: profiled %d images", p.Count)
+		astilog.Infof("main: font sizes are between %d and %d", p.FontSizeMin, p.FontSizeMax)
+		astilog.Infof("main: noise sigma is %.2f", p.NoiseSigma)
+		for _, c := range p.Colors {
+			var fs []string
+			for _, f := range c.Fonts {
+				fs = append(fs, fmt.Sprintf("%+v", f.RGBA))
+			}
+			astilog.Infof("main: background color %+v with font colors %s", c.Background.RGBA, strings.Join(fs, ", "))
+		}
+	case "rescore":
+		// Check flag
+		if len(*path) == 0 {
+			astilog.Fatal("main: use -p to indicate a reports directory path")
+		}
+
+		// Create detector
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
+		}
+		defer d.Close()
+
+		// Rescore
+		r, err := astiocr.Rescore(ctx, d, *path, astiocr.RescoreOptions{Write: *write})
+		if err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: rescoring reports in %s failed", *path))
+		}
+
+		// Log
+		astilog.Infof("main: %d images changed, %d unchanged, %d failed", len(r.Changed), r.Unchanged, len(r.Failed))
+		for _, i := range r.Changed {
+			astilog.Infof("main: %s changed by %.0f%% (%d added, %d removed)", i.Image, i.Difference*100, i.Added, i.Removed)
+		}
+	case "serve":
+		// Check flag
+		if len(*addr) == 0 {
+			astilog.Fatal("main: use -a to indicate an address")
+		}
+
+		// Create detector
+		d, err := astiocr.NewDetectorWithContext(ctx, c.Detector)
+		if err != nil {
+			astilog.Fatal(errors.Wrap(err, "main: creating detector failed"))
+		}
+		defer d.Close()
+
+		// Serve
+		if err = server.NewHTTP(d, c.HTTP).Serve(ctx, *addr); err != nil {
+			astilog.Fatal(errors.Wrapf(err, "main: serving http on %s failed", *addr))
+		}
 	default:
 		astilog.Fatal("main: no subcommand provided")
 	}
 }
+
+func logResults(rs []astiocr.DetectionResult) {
+	for _, r := range rs {
+		if r.Probability > 0.3 {
+			astilog.Infof("label: %s - probability: %.2f - box: %.2f --> %.2f --> %.2f --> %.2f", r.Label, r.Probability, r.Box.X1, r.Box.X2, r.Box.Y1, r.Box.Y2)
+		}
+	}
+}
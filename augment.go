@@ -0,0 +1,362 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// Augmentation names
+const (
+	augmentationAffine             = "affine"
+	augmentationBlur               = "blur"
+	augmentationColorJitter        = "color_jitter"
+	augmentationElastic            = "elastic"
+	augmentationGaussianNoise      = "gaussian_noise"
+	augmentationJPEG               = "jpeg"
+	augmentationLighting           = "lighting"
+	augmentationPerspective        = "perspective"
+	augmentationSaltAndPepperNoise = "salt_and_pepper_noise"
+)
+
+// defaultAugmentations are the augmentations applied, in that order, when no augmentation step is configured
+var defaultAugmentations = []string{
+	augmentationAffine,
+	augmentationPerspective,
+	augmentationElastic,
+	augmentationLighting,
+	augmentationColorJitter,
+	augmentationBlur,
+	augmentationGaussianNoise,
+	augmentationSaltAndPepperNoise,
+	augmentationJPEG,
+}
+
+// Augmenter applies a custom effect to a rendered image. The summary of the image, including its boxes and
+// words, can be updated in place when the image geometry changes. Since only the names of augmenters are part
+// of the store fingerprint, an augmenter must be deterministic: it must only draw random numbers from the
+// global math/rand source, which is seeded for each image when a seed is set, so that images can be
+// reproduced from the seed.
+type Augmenter func(img *image.RGBA, si *GatherSummaryImage) error
+
+// registeredAugmenter represents an augmenter registered on the trainer
+type registeredAugmenter struct {
+	a    Augmenter
+	name string
+}
+
+// RegisterAugmenter makes a custom augmenter available under the provided name so that it can be listed in
+// ConfigurationTrainer.Augmentations. When no augmentation step is configured, it is applied to all images
+// after the built-in augmentations, in the order of registration. It must be called before Gather and
+// panics if the augmenter is nil or if the name is already used.
+func (t *Trainer) RegisterAugmenter(name string, a Augmenter) {
+	if a == nil {
+		panic(fmt.Sprintf("astiocr: augmenter %s is nil", name))
+	}
+	if t.isAugmentation(name) {
+		panic(fmt.Sprintf("astiocr: augmenter name %s is already used", name))
+	}
+	t.augmenters = append(t.augmenters, registeredAugmenter{
+		a:    a,
+		name: name,
+	})
+}
+
+// isAugmentation checks whether the name is a built-in augmentation or a registered augmenter
+func (t *Trainer) isAugmentation(name string) bool {
+	for _, n := range defaultAugmentations {
+		if n == name {
+			return true
+		}
+	}
+	return t.augmenter(name) != nil
+}
+
+// augmenterNames returns the names of the registered augmenters
+func (t *Trainer) augmenterNames() (ns []string) {
+	for _, a := range t.augmenters {
+		ns = append(ns, a.name)
+	}
+	return
+}
+
+// augmenter returns the registered augmenter of the name, or nil
+func (t *Trainer) augmenter(name string) Augmenter {
+	for _, a := range t.augmenters {
+		if a.name == name {
+			return a.a
+		}
+	}
+	return nil
+}
+
+// checkAugmentations checks that the augmentation steps are either built-in augmentations or registered
+// augmenters, which can only be done once augmenters have been registered
+func (t *Trainer) checkAugmentations() (err error) {
+	for idx, a := range t.augmentations {
+		if !t.isAugmentation(a.Name) {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid name %s of augmentation step %d", a.Name, idx))
+			return
+		}
+	}
+	return
+}
+
+// ConfigurationAugmentation represents an augmentation step configuration
+// Steps are applied in order to the proportion of rendered images (default is 100), with the options of the
+// section of the same name, unless they are overridden by the step. The proportion of the section is then
+// ignored. The same augmentation can be listed several times.
+type ConfigurationAugmentation struct {
+	Affine        *ConfigurationAffine        `toml:"affine"`
+	Blur          *ConfigurationBlur          `toml:"blur"`
+	ColorJitter   *ConfigurationColorJitter   `toml:"color_jitter"`
+	Elastic       *ConfigurationElastic       `toml:"elastic"`
+	GaussianNoise *ConfigurationGaussianNoise `toml:"gaussian_noise"`
+	JPEG          *ConfigurationJPEG          `toml:"jpeg"`
+	Lighting      *ConfigurationLighting      `toml:"lighting"`
+	// "affine", "blur", "color_jitter", "elastic", "gaussian_noise", "jpeg", "lighting", "perspective",
+	// "salt_and_pepper_noise" or the name of a registered augmenter
+	Name               string                           `toml:"name"`
+	Perspective        *ConfigurationPerspective        `toml:"perspective"`
+	Proportion         float64                          `toml:"proportion"`
+	SaltAndPepperNoise *ConfigurationSaltAndPepperNoise `toml:"salt_and_pepper_noise"`
+}
+
+// augmentationStep represents an augmentation applied to a proportion of images
+type augmentationStep struct {
+	apply      func(img *image.RGBA, si *GatherSummaryImage) error
+	name       string
+	proportion float64
+}
+
+// validateAugmentations applies the default options of the augmentation steps and checks them
+func validateAugmentations(as []ConfigurationAugmentation) (err error) {
+	for idx := range as {
+		// Check proportion
+		a := &as[idx]
+		if a.Proportion == 0 {
+			a.Proportion = 100
+		}
+		if a.Proportion < 0 || a.Proportion > 100 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid proportion %v of augmentation step %d", a.Proportion, idx))
+			return
+		}
+
+		// Check options
+		switch a.Name {
+		case augmentationAffine:
+			if a.Affine != nil {
+				err = a.Affine.validate()
+			}
+		case augmentationBlur:
+			if a.Blur != nil {
+				err = a.Blur.validate()
+			}
+		case augmentationColorJitter:
+			if a.ColorJitter != nil {
+				err = a.ColorJitter.validate()
+			}
+		case augmentationElastic:
+			if a.Elastic != nil {
+				err = a.Elastic.validate()
+			}
+		case augmentationGaussianNoise:
+			if a.GaussianNoise != nil {
+				err = a.GaussianNoise.validate()
+			}
+		case augmentationJPEG:
+			if a.JPEG != nil {
+				err = a.JPEG.validate()
+			}
+		case augmentationLighting:
+			if a.Lighting != nil {
+				err = a.Lighting.validate()
+			}
+		case augmentationPerspective:
+			if a.Perspective != nil {
+				err = a.Perspective.validate()
+			}
+		case augmentationSaltAndPepperNoise:
+			if a.SaltAndPepperNoise != nil {
+				err = a.SaltAndPepperNoise.validate()
+			}
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "astiocr: validating augmentation step %d failed", idx)
+			return
+		}
+	}
+	return
+}
+
+// augmentationSteps returns the configured augmentation steps, or the default ones using the proportions of
+// the sections
+func (t *Trainer) augmentationSteps() (ss []augmentationStep) {
+	// Configured steps
+	if len(t.augmentations) > 0 {
+		for _, a := range t.augmentations {
+			ss = append(ss, t.newAugmentationStep(a))
+		}
+		return
+	}
+
+	// Default steps
+	proportions := map[string]float64{
+		augmentationAffine:             t.affine.Proportion,
+		augmentationBlur:               t.blur.Proportion,
+		augmentationColorJitter:        t.colorJitter.Proportion,
+		augmentationElastic:            t.elastic.Proportion,
+		augmentationGaussianNoise:      t.gaussianNoise.Proportion,
+		augmentationJPEG:               t.jpeg.Proportion,
+		augmentationLighting:           t.lighting.Proportion,
+		augmentationPerspective:        t.perspective.Proportion,
+		augmentationSaltAndPepperNoise: t.saltAndPepperNoise.Proportion,
+	}
+	for _, n := range defaultAugmentations {
+		ss = append(ss, t.newAugmentationStep(ConfigurationAugmentation{
+			Name:       n,
+			Proportion: proportions[n],
+		}))
+	}
+
+	// Registered augmenters
+	for _, a := range t.augmenters {
+		ss = append(ss, t.newAugmentationStep(ConfigurationAugmentation{
+			Name:       a.name,
+			Proportion: 100,
+		}))
+	}
+	return
+}
+
+// newAugmentationStep creates the augmentation step, falling back on the options of the section when the
+// step doesn't override them
+func (t *Trainer) newAugmentationStep(a ConfigurationAugmentation) (s augmentationStep) {
+	s = augmentationStep{
+		name:       a.Name,
+		proportion: a.Proportion,
+	}
+	switch a.Name {
+	case augmentationAffine:
+		c := t.affine
+		if a.Affine != nil {
+			c = *a.Affine
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			if si.Affine = c.random(si.Width, si.Height); si.Affine != nil {
+				m := si.Affine
+				warpHomography(img, homography{m[0], m[1], m[2], m[3], m[4], m[5], 0, 0, 1}, si)
+			}
+			return nil
+		}
+	case augmentationBlur:
+		c := t.blur
+		if a.Blur != nil {
+			c = *a.Blur
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.Blur = c.random()
+			blurImage(img, si.Blur)
+			return nil
+		}
+	case augmentationColorJitter:
+		c := t.colorJitter
+		if a.ColorJitter != nil {
+			c = *a.ColorJitter
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.ColorJitter = c.random()
+			jitterColors(img, si.ColorJitter)
+			return nil
+		}
+	case augmentationElastic:
+		c := t.elastic
+		if a.Elastic != nil {
+			c = *a.Elastic
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.Elastic = c.random()
+			distortElastic(img, si.Elastic, si)
+			return nil
+		}
+	case augmentationGaussianNoise:
+		c := t.gaussianNoise
+		if a.GaussianNoise != nil {
+			c = *a.GaussianNoise
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.NoiseSigma = c.random()
+			addGaussianNoise(img, si.NoiseSigma)
+			return nil
+		}
+	case augmentationJPEG:
+		c := t.jpeg
+		if a.JPEG != nil {
+			c = *a.JPEG
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.JPEGQuality = c.random()
+			return compressJPEG(img, si.JPEGQuality)
+		}
+	case augmentationLighting:
+		c := t.lighting
+		if a.Lighting != nil {
+			c = *a.Lighting
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.Lighting = c.random()
+			lightImage(img, si.Lighting)
+			return nil
+		}
+	case augmentationPerspective:
+		c := t.perspective
+		if a.Perspective != nil {
+			c = *a.Perspective
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			if si.Perspective = c.random(si.Width, si.Height); si.Perspective != nil {
+				var h homography
+				copy(h[:], si.Perspective)
+				warpHomography(img, h, si)
+			}
+			return nil
+		}
+	case augmentationSaltAndPepperNoise:
+		c := t.saltAndPepperNoise
+		if a.SaltAndPepperNoise != nil {
+			c = *a.SaltAndPepperNoise
+		}
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.SaltAndPepperDensity = c.random()
+			addSaltAndPepperNoise(img, si.SaltAndPepperDensity)
+			return nil
+		}
+	default:
+		fn := t.augmenter(a.Name)
+		s.apply = func(img *image.RGBA, si *GatherSummaryImage) error {
+			si.Augmenters = append(si.Augmenters, a.Name)
+			return fn(img, si)
+		}
+	}
+	return
+}
+
+// augmentImage applies the augmentation steps to the rendered image and records them in the summary,
+// remapping boxes when the image geometry changes
+func (t *Trainer) augmentImage(img *image.RGBA, si *GatherSummaryImage) (err error) {
+	for _, s := range t.augmentationSteps() {
+		// Check proportion
+		if s.proportion <= 0 || rand.Float64()*100 >= s.proportion {
+			continue
+		}
+
+		// Apply
+		if err = s.apply(img, si); err != nil {
+			err = errors.Wrapf(err, "astiocr: applying %s augmentation failed", s.name)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+)
+
+// Backend represents an engine capable of detecting OCR in an image
+// Returned boxes must be normalized between 0 and 1.
+type Backend interface {
+	io.Closer
+	Detect(ctx context.Context, img image.Image) ([]DetectionResult, error)
+}
+
+// Default backend name
+const defaultBackend = "tensorflow"
+
+// BackendFactory creates a backend based on the detector configuration
+type BackendFactory func(c ConfigurationDetector) (Backend, error)
+
+// Backends available in this build. Some backends are only available when using the proper build tags.
+var (
+	backends = map[string]BackendFactory{
+		defaultBackend: newTensorFlowBackend,
+	}
+	backendsMutex = &sync.Mutex{}
+)
+
+// RegisterBackend makes a backend available under the provided name so that it can be selected through
+// ConfigurationDetector.Backend. It panics if the factory is nil or if the name is already registered.
+func RegisterBackend(name string, f BackendFactory) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+	if f == nil {
+		panic(fmt.Sprintf("astiocr: backend %s factory is nil", name))
+	}
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("astiocr: backend %s is already registered", name))
+	}
+	backends[name] = f
+}
+
+func newBackend(c ConfigurationDetector) (b Backend, err error) {
+	// Get name
+	n := c.Backend
+	if len(n) == 0 {
+		n = defaultBackend
+	}
+
+	// Get factory
+	backendsMutex.Lock()
+	f, ok := backends[n]
+	backendsMutex.Unlock()
+	if !ok {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: backend %s is not available", n))
+		return
+	}
+	return f(c)
+}
@@ -0,0 +1,107 @@
+//go:build aws
+// +build aws
+
+package astiocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterBackend("aws_textract", newAWSTextractBackend)
+}
+
+type awsTextractBackend struct {
+	blockType types.BlockType
+	c         *textract.Client
+}
+
+func newAWSTextractBackend(c ConfigurationDetector) (Backend, error) {
+	// Create backend
+	b := &awsTextractBackend{blockType: types.BlockTypeWord}
+
+	// Level
+	switch c.AWSTextract.Level {
+	case "", "word":
+	case "line":
+		b.blockType = types.BlockTypeLine
+	default:
+		return nil, withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid aws textract level %s", c.AWSTextract.Level))
+	}
+
+	// Options
+	var opts []func(*config.LoadOptions) error
+	if len(c.AWSTextract.Region) > 0 {
+		opts = append(opts, config.WithRegion(c.AWSTextract.Region))
+	}
+	if len(c.AWSTextract.AccessKeyID) > 0 {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(c.AWSTextract.AccessKeyID, c.AWSTextract.SecretAccessKey, c.AWSTextract.SessionToken)))
+	}
+
+	// Load config
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "astiocr: loading aws config failed")
+	}
+
+	// Create client
+	b.c = textract.NewFromConfig(cfg)
+	return b, nil
+}
+
+// Close implements the Backend interface
+func (b *awsTextractBackend) Close() error {
+	return nil
+}
+
+// Detect implements the Backend interface
+func (b *awsTextractBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	// Encode image
+	buf := &bytes.Buffer{}
+	if err = png.Encode(buf, img); err != nil {
+		err = errors.Wrap(err, "astiocr: encoding image failed")
+		return
+	}
+
+	// Detect
+	var o *textract.DetectDocumentTextOutput
+	if o, err = b.c.DetectDocumentText(ctx, &textract.DetectDocumentTextInput{
+		Document: &types.Document{Bytes: buf.Bytes()},
+	}); err != nil {
+		err = errors.Wrap(err, "astiocr: detecting document text failed")
+		return
+	}
+
+	// Loop through blocks
+	for _, bl := range o.Blocks {
+		// Invalid block
+		if bl.BlockType != b.blockType || bl.Geometry == nil || bl.Geometry.BoundingBox == nil {
+			continue
+		}
+
+		// Textract boxes are already normalized
+		bb := bl.Geometry.BoundingBox
+		rs = append(rs, DetectionResult{
+			Box: DetectionBox{
+				X1: float64(bb.Left),
+				X2: float64(bb.Left + bb.Width),
+				Y1: float64(bb.Top),
+				Y2: float64(bb.Top + bb.Height),
+			},
+			Label:       aws.ToString(bl.Text),
+			Probability: float64(aws.ToFloat32(bl.Confidence)) / 100,
+		})
+	}
+	return
+}
@@ -0,0 +1,145 @@
+//go:build googlecloud
+// +build googlecloud
+
+package astiocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+
+	vision "cloud.google.com/go/vision/v2/apiv1"
+	"cloud.google.com/go/vision/v2/apiv1/visionpb"
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+)
+
+func init() {
+	RegisterBackend("google_cloud_vision", newGoogleCloudVisionBackend)
+}
+
+type googleCloudVisionBackend struct {
+	c     *vision.ImageAnnotatorClient
+	words bool
+}
+
+func newGoogleCloudVisionBackend(c ConfigurationDetector) (Backend, error) {
+	// Create backend
+	b := &googleCloudVisionBackend{}
+
+	// Level
+	switch c.GoogleCloudVision.Level {
+	case "", "symbol":
+	case "word":
+		b.words = true
+	default:
+		return nil, withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid google cloud vision level %s", c.GoogleCloudVision.Level))
+	}
+
+	// Options
+	var opts []option.ClientOption
+	if len(c.GoogleCloudVision.CredentialsFile) > 0 {
+		opts = append(opts, option.WithCredentialsFile(c.GoogleCloudVision.CredentialsFile))
+	}
+
+	// Create client
+	var err error
+	if b.c, err = vision.NewImageAnnotatorClient(context.Background(), opts...); err != nil {
+		return nil, errors.Wrap(err, "astiocr: creating google cloud vision client failed")
+	}
+	return b, nil
+}
+
+// Close implements the Backend interface
+func (b *googleCloudVisionBackend) Close() error {
+	return b.c.Close()
+}
+
+// Detect implements the Backend interface
+func (b *googleCloudVisionBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	// Encode image
+	buf := &bytes.Buffer{}
+	if err = png.Encode(buf, img); err != nil {
+		err = errors.Wrap(err, "astiocr: encoding image failed")
+		return
+	}
+
+	// Detect
+	var resp *visionpb.BatchAnnotateImagesResponse
+	if resp, err = b.c.BatchAnnotateImages(ctx, &visionpb.BatchAnnotateImagesRequest{
+		Requests: []*visionpb.AnnotateImageRequest{{
+			Features: []*visionpb.Feature{{Type: visionpb.Feature_DOCUMENT_TEXT_DETECTION}},
+			Image:    &visionpb.Image{Content: buf.Bytes()},
+		}},
+	}); err != nil {
+		err = errors.Wrap(err, "astiocr: annotating image failed")
+		return
+	}
+
+	// Process response
+	if len(resp.Responses) == 0 {
+		return
+	}
+	if e := resp.Responses[0].Error; e != nil {
+		err = fmt.Errorf("astiocr: annotating image failed with code %d: %s", e.Code, e.Message)
+		return
+	}
+
+	// No text
+	a := resp.Responses[0].FullTextAnnotation
+	if a == nil {
+		return
+	}
+
+	// Loop through words
+	r := img.Bounds()
+	for _, p := range a.Pages {
+		for _, bl := range p.Blocks {
+			for _, pa := range bl.Paragraphs {
+				for _, w := range pa.Words {
+					// Word level
+					if b.words {
+						var label []string
+						for _, s := range w.Symbols {
+							label = append(label, s.Text)
+						}
+						rs = append(rs, DetectionResult{
+							Box:         googleCloudVisionBox(w.BoundingBox, r),
+							Label:       strings.Join(label, ""),
+							Probability: float64(w.Confidence),
+						})
+						continue
+					}
+
+					// Symbol level
+					for _, s := range w.Symbols {
+						rs = append(rs, DetectionResult{
+							Box:         googleCloudVisionBox(s.BoundingBox, r),
+							Label:       s.Text,
+							Probability: float64(s.Confidence),
+						})
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// googleCloudVisionBox converts a bounding polygon to a normalized box containing it
+func googleCloudVisionBox(p *visionpb.BoundingPoly, r image.Rectangle) (b DetectionBox) {
+	if p == nil || len(p.Vertices) == 0 {
+		return
+	}
+	b = DetectionBox{X1: math.MaxFloat64, Y1: math.MaxFloat64}
+	for _, v := range p.Vertices {
+		x, y := float64(v.X)/float64(r.Dx()), float64(v.Y)/float64(r.Dy())
+		b.X1, b.X2 = math.Min(b.X1, x), math.Max(b.X2, x)
+		b.Y1, b.Y2 = math.Min(b.Y1, y), math.Max(b.Y2, y)
+	}
+	return
+}
@@ -0,0 +1,393 @@
+package astiocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// Color modes
+const (
+	colorModeGrayscale       = "grayscale"
+	colorModeGrayscaleSingle = "grayscale_single"
+	colorModeRGB             = "rgb"
+)
+
+type tensorFlowBackend struct {
+	c         ConfigurationTensorFlow
+	colorMode string
+	g         *tf.Graph
+	labels    []string
+	o         tensorFlowOperations
+	s         *tf.Session
+}
+
+type tensorFlowOperations struct {
+	boxes, classes, input, numDetections, scores *tf.Operation
+}
+
+func newTensorFlowBackend(c ConfigurationDetector) (Backend, error) {
+	// Check color mode
+	b := &tensorFlowBackend{
+		c:         c.TensorFlow,
+		colorMode: c.ColorMode,
+		labels:    c.Labels,
+	}
+	if len(b.labels) == 0 {
+		cs, err := parseCharset(c.Charset)
+		if err != nil {
+			return nil, errors.Wrap(err, "astiocr: parsing charset failed")
+		}
+		b.labels = charsetLabels(cs)
+	}
+	if b.c.InputStd == 0 {
+		b.c.InputStd = 1
+	}
+	switch b.colorMode {
+	case "":
+		b.colorMode = colorModeRGB
+	case colorModeGrayscale, colorModeGrayscaleSingle, colorModeRGB:
+	default:
+		return nil, withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid color mode %s", b.colorMode))
+	}
+
+	// Load the model
+	if err := b.loadModel(c.ModelPath); err != nil {
+		return nil, errors.Wrapf(err, "astiocr: loading model %s failed", c.ModelPath)
+	}
+
+	// Get operations
+	if err := b.getOperations(c.TensorFlow); err != nil {
+		b.Close()
+		return nil, errors.Wrapf(err, "astiocr: getting operations of model %s failed", c.ModelPath)
+	}
+	return b, nil
+}
+
+func (b *tensorFlowBackend) getOperations(c ConfigurationTensorFlow) (err error) {
+	// Loop through operations
+	for _, o := range []struct {
+		defaultName string
+		name        string
+		op          **tf.Operation
+	}{
+		{defaultName: "detection_boxes", name: c.BoxesOperation, op: &b.o.boxes},
+		{defaultName: "detection_classes", name: c.ClassesOperation, op: &b.o.classes},
+		{defaultName: "image_tensor", name: c.InputOperation, op: &b.o.input},
+		{defaultName: "num_detections", name: c.NumDetectionsOperation, op: &b.o.numDetections},
+		{defaultName: "detection_scores", name: c.ScoresOperation, op: &b.o.scores},
+	} {
+		// Get name
+		n := o.name
+		if len(n) == 0 {
+			n = o.defaultName
+		}
+
+		// Get operation
+		if *o.op = b.g.Operation(n); *o.op == nil {
+			err = fmt.Errorf("astiocr: operation %s not found", n)
+			return
+		}
+	}
+	return
+}
+
+func (b *tensorFlowBackend) loadModel(p string) (err error) {
+	// Make sure to record the load duration
+	defer func(start time.Time) {
+		if err == nil {
+			metricModelLoadDuration.Set(time.Since(start).Seconds())
+		}
+	}(time.Now())
+
+	// TFLite models can't be loaded by the tensorflow go bindings
+	if strings.EqualFold(filepath.Ext(p), ".tflite") {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: tflite model %s is not supported, only frozen graphs are", p))
+		return
+	}
+
+	// Read the model
+	var m []byte
+	if m, err = ioutil.ReadFile(p); err != nil {
+		if os.IsNotExist(err) {
+			err = withKind(ErrModelNotFound, err)
+		}
+		err = errors.Wrapf(err, "astiocr: reading %s failed", p)
+		return
+	}
+
+	// Create the graph
+	b.g = tf.NewGraph()
+	if err = b.g.Import(m, ""); err != nil {
+		err = errors.Wrapf(err, "astiocr: importing model %s failed", p)
+		return
+	}
+
+	// Create the session
+	if b.s, err = tf.NewSession(b.g, b.c.SessionOptions); err != nil {
+		err = errors.Wrap(err, "astiocr: creating session failed")
+		return
+	}
+	return
+}
+
+// Close implements the Backend interface
+func (b *tensorFlowBackend) Close() error {
+	return b.s.Close()
+}
+
+// Detect implements the Backend interface
+func (b *tensorFlowBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	rs, _, err = b.DetectRaw(ctx, img)
+	return
+}
+
+// DetectRaw implements the RawBackend interface
+func (b *tensorFlowBackend) DetectRaw(ctx context.Context, img image.Image) (rs []DetectionResult, o RawOutput, err error) {
+	// Create tensor
+	var t *tf.Tensor
+	_, end := startSpan(ctx, "astiocr.CreateTensor")
+	t, err = tensorFromImage(img, b.colorMode, b.o.input.Output(0).DataType(), b.c.InputMean, b.c.InputStd)
+	end(err)
+	if err != nil {
+		err = errors.Wrap(err, "astiocr: creating tensor from image failed")
+		return
+	}
+
+	// Make sure the context is still valid before running a session
+	if err = ctx.Err(); err != nil {
+		err = errors.Wrap(err, "astiocr: context error")
+		return
+	}
+
+	// Run inference
+	_, end = startSpan(ctx, "astiocr.RunSession")
+	o, err = b.runInferenceContext(ctx, t)
+	end(err)
+	if err != nil {
+		err = errors.Wrap(err, "astiocr: running inference failed")
+		return
+	}
+
+	// Loop through results
+	_, end = startSpan(ctx, "astiocr.PostProcess")
+	defer end(nil)
+	for idx := 0; idx < len(o.Scores); idx++ {
+		// Get label
+		class := int(o.Classes[idx])
+		if class < 1 || class > len(b.labels) {
+			err = fmt.Errorf("astiocr: class %d is not in the %d labels", class, len(b.labels))
+			return
+		}
+
+		// Append result
+		rs = append(rs, DetectionResult{
+			Box: DetectionBox{
+				X1: float64(o.Boxes[idx][1]),
+				X2: float64(o.Boxes[idx][3]),
+				Y1: float64(o.Boxes[idx][0]),
+				Y2: float64(o.Boxes[idx][2]),
+			},
+			Label:       b.labels[class-1],
+			Probability: float64(o.Scores[idx]),
+		})
+	}
+	return
+}
+
+// tensorFromImage creates a [1, height, width, 3] tensor, or a [1, height, width, 1] tensor in
+// "grayscale_single" color mode. Uint8 tensors contain raw pixel values whereas float tensors contain
+// (value - mean) / std.
+func tensorFromImage(img image.Image, colorMode string, dt tf.DataType, mean, std float64) (t *tf.Tensor, err error) {
+	// Get channels
+	channels := 3
+	if colorMode == colorModeGrayscaleSingle {
+		channels = 1
+	}
+
+	// Loop through pixels
+	r := img.Bounds()
+	b := make([]byte, 0, r.Dx()*r.Dy()*channels)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			switch colorMode {
+			case colorModeGrayscale:
+				l := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+				b = append(b, l, l, l)
+			case colorModeGrayscaleSingle:
+				b = append(b, color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			default:
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				b = append(b, c.R, c.G, c.B)
+			}
+		}
+	}
+
+	// Convert to float
+	shape := []int64{1, int64(r.Dy()), int64(r.Dx()), int64(channels)}
+	switch dt {
+	case tf.Uint8:
+	case tf.Float:
+		buf := &bytes.Buffer{}
+		for _, v := range b {
+			if err = binary.Write(buf, binary.LittleEndian, float32((float64(v)-mean)/std)); err != nil {
+				err = errors.Wrap(err, "astiocr: writing float failed")
+				return
+			}
+		}
+		b = buf.Bytes()
+	default:
+		err = fmt.Errorf("astiocr: unsupported input data type %v", dt)
+		return
+	}
+
+	// Create tensor
+	if t, err = tf.ReadTensor(dt, shape, bytes.NewReader(b)); err != nil {
+		err = errors.Wrap(err, "astiocr: reading tensor failed")
+		return
+	}
+	return
+}
+
+type inferenceResult struct {
+	err error
+	o   RawOutput
+}
+
+// runInferenceContext returns as soon as the context is done. Sessions can't be interrupted, therefore the
+// inference keeps running in the background and its results are dropped. Closing the session waits for it
+// to finish.
+func (b *tensorFlowBackend) runInferenceContext(ctx context.Context, t *tf.Tensor) (o RawOutput, err error) {
+	// Run inference
+	c := make(chan inferenceResult, 1)
+	go func() {
+		var r inferenceResult
+		r.o, r.err = b.runInference(t)
+		c <- r
+	}()
+
+	// Wait
+	select {
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "astiocr: context error")
+		return
+	case r := <-c:
+		return r.o, r.err
+	}
+}
+
+func (b *tensorFlowBackend) runInference(t *tf.Tensor) (o RawOutput, err error) {
+	// Run
+	var os []*tf.Tensor
+	if os, err = b.s.Run(
+		map[tf.Output]*tf.Tensor{b.o.input.Output(0): t},
+		[]tf.Output{
+			b.o.boxes.Output(0),
+			b.o.scores.Output(0),
+			b.o.classes.Output(0),
+			b.o.numDetections.Output(0),
+		},
+		nil,
+	); err != nil {
+		err = errors.Wrap(err, "astiocr: running session failed")
+		return
+	}
+
+	// Get results. Outputs may be quantized, hence they're flattened and dequantized regardless of their
+	// data type.
+	if o.Scores, err = flattenTensorValue(os[1].Value(), b.c.ScoresQuantization); err != nil {
+		err = errors.Wrap(err, "astiocr: flattening scores failed")
+		return
+	}
+	if o.Classes, err = flattenTensorValue(os[2].Value(), b.c.ClassesQuantization); err != nil {
+		err = errors.Wrap(err, "astiocr: flattening classes failed")
+		return
+	}
+	var fs []float32
+	if fs, err = flattenTensorValue(os[0].Value(), b.c.BoxesQuantization); err != nil {
+		err = errors.Wrap(err, "astiocr: flattening boxes failed")
+		return
+	}
+	for idx := 0; idx+4 <= len(fs); idx += 4 {
+		o.Boxes = append(o.Boxes, fs[idx:idx+4])
+	}
+	if fs, err = flattenTensorValue(os[3].Value(), ConfigurationQuantization{}); err != nil {
+		err = errors.Wrap(err, "astiocr: flattening num detections failed")
+		return
+	} else if len(fs) > 0 {
+		o.NumDetections = int(fs[0])
+	}
+
+	// Check lengths
+	if len(o.Classes) != len(o.Scores) || len(o.Boxes) != len(o.Scores) {
+		err = fmt.Errorf("astiocr: %d scores, %d classes and %d boxes don't match", len(o.Scores), len(o.Classes), len(o.Boxes))
+		return
+	}
+	return
+}
+
+// Name of the optional operation whose string value(s), formatted as "key=value", are returned as model
+// metadata
+const tensorFlowMetadataOperation = "astiocr_metadata"
+
+func (b *tensorFlowBackend) metadata(o *tf.Operation) (m map[string]string, err error) {
+	// Run
+	var os []*tf.Tensor
+	if os, err = b.s.Run(nil, []tf.Output{o.Output(0)}, nil); err != nil {
+		err = errors.Wrap(err, "astiocr: running session failed")
+		return
+	}
+
+	// Get values
+	var vs []string
+	switch v := os[0].Value().(type) {
+	case string:
+		vs = strings.Split(v, "\n")
+	case []string:
+		vs = v
+	default:
+		err = fmt.Errorf("astiocr: invalid metadata type %T", v)
+		return
+	}
+
+	// Parse
+	m = make(map[string]string)
+	for _, v := range vs {
+		if kv := strings.SplitN(v, "=", 2); len(kv) == 2 {
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return
+}
+
+func dataTypeName(dt tf.DataType) string {
+	switch dt {
+	case tf.Float:
+		return "float32"
+	case tf.Double:
+		return "float64"
+	case tf.Int8:
+		return "int8"
+	case tf.Int32:
+		return "int32"
+	case tf.Int64:
+		return "int64"
+	case tf.String:
+		return "string"
+	case tf.Uint8:
+		return "uint8"
+	default:
+		return fmt.Sprintf("%d", dt)
+	}
+}
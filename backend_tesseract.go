@@ -0,0 +1,113 @@
+//go:build tesseract
+// +build tesseract
+
+package astiocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"sync"
+
+	"github.com/otiai10/gosseract/v2"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterBackend("tesseract", newTesseractBackend)
+}
+
+type tesseractBackend struct {
+	c     *gosseract.Client
+	level gosseract.PageIteratorLevel
+	// gosseract clients are not goroutine-safe
+	m *sync.Mutex
+}
+
+func newTesseractBackend(c ConfigurationDetector) (Backend, error) {
+	// Create backend
+	b := &tesseractBackend{
+		c:     gosseract.NewClient(),
+		level: gosseract.RIL_SYMBOL,
+		m:     &sync.Mutex{},
+	}
+
+	// Level
+	switch c.Tesseract.Level {
+	case "", "symbol":
+	case "word":
+		b.level = gosseract.RIL_WORD
+	default:
+		b.c.Close()
+		return nil, withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid tesseract level %s", c.Tesseract.Level))
+	}
+
+	// Languages
+	ls := c.Tesseract.Languages
+	if len(ls) == 0 {
+		ls = []string{"eng"}
+	}
+	if err := b.c.SetLanguage(ls...); err != nil {
+		b.c.Close()
+		return nil, errors.Wrap(err, "astiocr: setting tesseract languages failed")
+	}
+
+	// Whitelist
+	if len(c.Tesseract.Whitelist) > 0 {
+		if err := b.c.SetWhitelist(c.Tesseract.Whitelist); err != nil {
+			b.c.Close()
+			return nil, errors.Wrap(err, "astiocr: setting tesseract whitelist failed")
+		}
+	}
+	return b, nil
+}
+
+// Close implements the Backend interface
+func (b *tesseractBackend) Close() error {
+	return b.c.Close()
+}
+
+// Detect implements the Backend interface
+func (b *tesseractBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	// Encode image
+	buf := &bytes.Buffer{}
+	if err = png.Encode(buf, img); err != nil {
+		err = errors.Wrap(err, "astiocr: encoding image failed")
+		return
+	}
+
+	// Lock
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// Set image
+	if err = b.c.SetImageFromBytes(buf.Bytes()); err != nil {
+		err = errors.Wrap(err, "astiocr: setting tesseract image failed")
+		return
+	}
+
+	// Get boxes
+	var bs []gosseract.BoundingBox
+	if bs, err = b.c.GetBoundingBoxes(b.level); err != nil {
+		err = errors.Wrap(err, "astiocr: getting tesseract bounding boxes failed")
+		return
+	}
+
+	// Loop through boxes
+	r := img.Bounds()
+	for _, bb := range bs {
+		rs = append(rs, DetectionResult{
+			Box: DetectionBox{
+				X1: float64(bb.Box.Min.X) / float64(r.Dx()),
+				X2: float64(bb.Box.Max.X) / float64(r.Dx()),
+				Y1: float64(bb.Box.Min.Y) / float64(r.Dy()),
+				Y2: float64(bb.Box.Max.Y) / float64(r.Dy()),
+			},
+			Label:       bb.Word,
+			Probability: bb.Confidence / 100,
+		})
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/draw"
+)
+
+// ConfigurationBackgrounds represents a backgrounds configuration
+// The background of the proportion of images is a random crop of a random image of the directory, such as
+// photos or screenshots, randomly scaled, instead of a flat color.
+type ConfigurationBackgrounds struct {
+	DirectoryPath string  `toml:"directory_path"`
+	Proportion    float64 `toml:"proportion"`
+}
+
+// backgroundMaxZoom is the maximum scale of background images relative to the smallest scale at which they
+// cover the generated image
+const backgroundMaxZoom = 2
+
+// backgroundExtensions are the extensions of the background images
+var backgroundExtensions = map[string]bool{
+	".bmp":  true,
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+}
+
+// listBackgrounds returns the paths of the background images of the directory, sorted so that picking them
+// is reproducible
+func listBackgrounds(dirPath string) (ps []string, err error) {
+	// Read dir
+	var fis []os.FileInfo
+	if fis, err = ioutil.ReadDir(dirPath); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading dir %s failed", dirPath)
+		return
+	}
+
+	// Loop through files
+	for _, fi := range fis {
+		if !fi.IsDir() && backgroundExtensions[strings.ToLower(filepath.Ext(fi.Name()))] {
+			ps = append(ps, filepath.Join(dirPath, fi.Name()))
+		}
+	}
+	return
+}
+
+// drawBackground draws the flat background color, then covers it with a background image, a gradient or a
+// texture according to the configured proportions
+func (t *Trainer) drawBackground(img *image.RGBA, backgroundColor color.RGBA, si *GatherSummaryImage) {
+	// Draw flat color
+	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.ZP, draw.Src)
+
+	// No other backgrounds
+	if t.backgrounds.Proportion <= 0 && t.gradient.Proportion <= 0 && t.texture.Proportion <= 0 {
+		return
+	}
+
+	// Pick background
+	r := rand.Float64() * 100
+	switch {
+	case r < t.backgrounds.Proportion:
+		si.Background = t.drawBackgroundImage(img)
+	case r < t.backgrounds.Proportion+t.gradient.Proportion:
+		si.Gradient = t.drawGradient(img, backgroundColor)
+	case r < t.backgrounds.Proportion+t.gradient.Proportion+t.texture.Proportion:
+		si.Texture = t.drawTexture(img, backgroundColor)
+	}
+}
+
+// drawBackgroundImage covers the image with a random crop of a random background image, randomly scaled,
+// and returns its path, or "" if it couldn't be decoded in which case the image is left untouched
+func (t *Trainer) drawBackgroundImage(img *image.RGBA) (p string) {
+	// Pick scale and crop before decoding so that random numbers don't depend on decoding errors
+	p = t.backgroundPaths[rand.Intn(len(t.backgroundPaths))]
+	zoom, rx, ry := 1+rand.Float64()*(backgroundMaxZoom-1), rand.Float64(), rand.Float64()
+
+	// Decode
+	src, err := decodeImageFile(p)
+	if err != nil {
+		t.l.Debugf("astiocr: skipping background %s: %s", p, err)
+		return ""
+	}
+
+	// Get crop
+	sb, db := src.Bounds(), img.Bounds()
+	scale := math.Max(float64(db.Dx())/float64(sb.Dx()), float64(db.Dy())/float64(sb.Dy())) * zoom
+	w := int(math.Min(float64(sb.Dx()), math.Ceil(float64(db.Dx())/scale)))
+	h := int(math.Min(float64(sb.Dy()), math.Ceil(float64(db.Dy())/scale)))
+	x, y := sb.Min.X+int(rx*float64(sb.Dx()-w)), sb.Min.Y+int(ry*float64(sb.Dy()-h))
+
+	// Draw
+	draw.BiLinear.Scale(img, db, src, image.Rect(x, y, x+w, y+h), draw.Src, nil)
+	return
+}
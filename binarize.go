@@ -0,0 +1,140 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Binarization constants
+const (
+	// Default Sauvola sensitivity
+	binarizeDefaultK = 0.2
+	// Default Sauvola window size, in pixels
+	binarizeDefaultWindowSize = 15
+	// Dynamic range of the standard deviation of 8-bit luminances
+	binarizeSauvolaR = 128
+)
+
+func newBinarizeStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	switch c.Method {
+	case "", "otsu":
+		s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+			ls, w, h := luminances(img)
+			t := otsuThreshold(ls)
+			return binarize(ls, w, h, func(int, int) float64 { return t }), identityBox
+		}
+	case "sauvola":
+		// Default values
+		k, ws := c.K, c.WindowSize
+		if k == 0 {
+			k = binarizeDefaultK
+		}
+		if ws == 0 {
+			ws = binarizeDefaultWindowSize
+		}
+		if k < 0 || ws < 0 {
+			err = fmt.Errorf("astiocr: invalid k %v and window size %d", k, ws)
+			return
+		}
+		s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+			ls, w, h := luminances(img)
+			return binarize(ls, w, h, sauvolaThreshold(ls, w, h, ws, k)), identityBox
+		}
+	default:
+		err = fmt.Errorf("astiocr: invalid binarization method %s", c.Method)
+	}
+	return
+}
+
+// luminances returns the image luminances, between 0 and 255, row by row
+func luminances(img image.Image) (ls []float64, w, h int) {
+	r := img.Bounds()
+	w, h = r.Dx(), r.Dy()
+	ls = make([]float64, 0, w*h)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ls = append(ls, luminance(img.At(x, y))*255)
+		}
+	}
+	return
+}
+
+// binarize creates a black and white image where pixels darker than their threshold are black
+func binarize(ls []float64, w, h int, threshold func(x, y int) float64) image.Image {
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if ls[y*w+x] > threshold(x, y) {
+				dst.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return dst
+}
+
+// otsuThreshold returns the global threshold maximizing the variance between dark and bright pixels
+func otsuThreshold(ls []float64) float64 {
+	// Build histogram
+	var hs [256]int
+	var sum float64
+	for _, l := range ls {
+		hs[int(math.Min(255, l))]++
+		sum += float64(int(math.Min(255, l)))
+	}
+
+	// Loop through thresholds
+	var best, bestVariance, sumB float64
+	var countB int
+	for t := 0; t < 256; t++ {
+		// Update background
+		countB += hs[t]
+		if countB == 0 {
+			continue
+		}
+		countF := len(ls) - countB
+		if countF == 0 {
+			break
+		}
+		sumB += float64(t * hs[t])
+
+		// Compute variance
+		mB, mF := sumB/float64(countB), (sum-sumB)/float64(countF)
+		if v := float64(countB) * float64(countF) * (mB - mF) * (mB - mF); v > bestVariance {
+			best, bestVariance = float64(t), v
+		}
+	}
+	return best
+}
+
+// sauvolaThreshold returns per pixel thresholds based on the mean and standard deviation of luminances
+// in a window around each pixel, which copes with uneven lighting. Integral images make it independent
+// of the window size.
+func sauvolaThreshold(ls []float64, w, h, windowSize int, k float64) func(x, y int) float64 {
+	// Build integral images
+	s := make([]float64, (w+1)*(h+1))
+	sq := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := ls[y*w+x]
+			i := (y+1)*(w+1) + x + 1
+			s[i] = l + s[i-1] + s[i-w-1] - s[i-w-2]
+			sq[i] = l*l + sq[i-1] + sq[i-w-1] - sq[i-w-2]
+		}
+	}
+
+	return func(x, y int) float64 {
+		// Get window
+		x1, x2 := int(math.Max(0, float64(x-windowSize/2))), int(math.Min(float64(w), float64(x+windowSize/2+1)))
+		y1, y2 := int(math.Max(0, float64(y-windowSize/2))), int(math.Min(float64(h), float64(y+windowSize/2+1)))
+		n := float64((x2 - x1) * (y2 - y1))
+
+		// Compute mean and standard deviation
+		sum := s[y2*(w+1)+x2] - s[y1*(w+1)+x2] - s[y2*(w+1)+x1] + s[y1*(w+1)+x1]
+		sumSq := sq[y2*(w+1)+x2] - sq[y1*(w+1)+x2] - sq[y2*(w+1)+x1] + sq[y1*(w+1)+x1]
+		m := sum / n
+		sd := math.Sqrt(math.Max(0, sumSq/n-m*m))
+		return m * (1 + k*(sd/binarizeSauvolaR-1))
+	}
+}
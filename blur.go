@@ -0,0 +1,145 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+)
+
+// Blur kinds
+const (
+	blurKindGaussian = "gaussian"
+	blurKindMotion   = "motion"
+)
+
+// ConfigurationBlur represents a blur configuration
+// The proportion of images is blurred after rendering, either by a gaussian blur whose standard deviation
+// in pixels is picked between sigma min and sigma max (default is 0.5 and 1.5), or by a motion blur, like
+// video frames of moving text, whose direction is random and whose length in pixels is picked between
+// length min and length max (default is 3 and 9).
+type ConfigurationBlur struct {
+	// "gaussian" or "motion". Default is both.
+	Kinds      []string `toml:"kinds"`
+	LengthMax  int      `toml:"length_max"`
+	LengthMin  int      `toml:"length_min"`
+	Proportion float64  `toml:"proportion"`
+	SigmaMax   float64  `toml:"sigma_max"`
+	SigmaMin   float64  `toml:"sigma_min"`
+}
+
+// GatherSummaryBlur represents the blur applied to a summary image
+type GatherSummaryBlur struct {
+	// Direction of the motion blur, in degrees counterclockwise
+	Angle  float64 `json:"angle,omitempty"`
+	Kind   string  `json:"kind"`
+	Length int     `json:"length,omitempty"`
+	Sigma  float64 `json:"sigma,omitempty"`
+}
+
+// random returns the blur applied to images
+func (c ConfigurationBlur) random() *GatherSummaryBlur {
+	// Pick kind
+	b := &GatherSummaryBlur{Kind: c.Kinds[rand.Intn(len(c.Kinds))]}
+	switch b.Kind {
+	case blurKindMotion:
+		b.Angle = rand.Float64() * 180
+		b.Length = c.LengthMin + rand.Intn(c.LengthMax-c.LengthMin+1)
+	default:
+		b.Sigma = c.SigmaMin + rand.Float64()*(c.SigmaMax-c.SigmaMin)
+	}
+	return b
+}
+
+// blurTap represents a weighted offset of a convolution kernel
+type blurTap struct {
+	dx, dy int
+	w      float64
+}
+
+// blurImage applies the blur to the image
+func blurImage(img *image.RGBA, b *GatherSummaryBlur) {
+	switch b.Kind {
+	case blurKindMotion:
+		// Average the pixels along a segment of the length centered on each pixel
+		var ts []blurTap
+		sin, cos := math.Sincos(-b.Angle * math.Pi / 180)
+		for idx := 0; idx < b.Length; idx++ {
+			d := float64(idx) - float64(b.Length-1)/2
+			ts = append(ts, blurTap{dx: int(math.Round(d * cos)), dy: int(math.Round(d * sin)), w: 1 / float64(b.Length)})
+		}
+		convolveRGBA(img, ts)
+	default:
+		// Gaussian blur is separable
+		radius := int(math.Ceil(3 * b.Sigma))
+		var hs, vs []blurTap
+		var total float64
+		for d := -radius; d <= radius; d++ {
+			w := math.Exp(-float64(d*d) / (2 * b.Sigma * b.Sigma))
+			hs = append(hs, blurTap{dx: d, w: w})
+			vs = append(vs, blurTap{dy: d, w: w})
+			total += w
+		}
+		for idx := range hs {
+			hs[idx].w /= total
+			vs[idx].w /= total
+		}
+		convolveRGBA(img, hs)
+		convolveRGBA(img, vs)
+	}
+}
+
+// convolveRGBA replaces each pixel of the image with the weighted sum of the pixels located at the offsets,
+// pixels outside the image being replaced with the closest pixel inside the image
+func convolveRGBA(img *image.RGBA, ts []blurTap) {
+	r := img.Bounds()
+	src := image.NewRGBA(r)
+	draw.Draw(src, r, img, r.Min, draw.Src)
+	clamp := func(v, min, max int) int {
+		if v < min {
+			return min
+		} else if v >= max {
+			return max - 1
+		}
+		return v
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var cr, cg, cb, ca float64
+			for _, t := range ts {
+				c := src.RGBAAt(clamp(x+t.dx, r.Min.X, r.Max.X), clamp(y+t.dy, r.Min.Y, r.Max.Y))
+				cr += t.w * float64(c.R)
+				cg += t.w * float64(c.G)
+				cb += t.w * float64(c.B)
+				ca += t.w * float64(c.A)
+			}
+			img.SetRGBA(x, y, color.RGBA{R: uint8(math.Round(cr)), G: uint8(math.Round(cg)), B: uint8(math.Round(cb)), A: uint8(math.Round(ca))})
+		}
+	}
+}
+
+// validate applies the default blur options and checks them
+func (c *ConfigurationBlur) validate() (err error) {
+	if len(c.Kinds) == 0 {
+		c.Kinds = []string{blurKindGaussian, blurKindMotion}
+	}
+	for _, k := range c.Kinds {
+		if k != blurKindGaussian && k != blurKindMotion {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid blur kind %s", k))
+			return
+		}
+	}
+	if c.LengthMin == 0 && c.LengthMax == 0 {
+		c.LengthMin, c.LengthMax = 3, 9
+	}
+	if c.SigmaMin == 0 && c.SigmaMax == 0 {
+		c.SigmaMin, c.SigmaMax = 0.5, 1.5
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.LengthMin <= 0 || c.LengthMax < c.LengthMin || c.SigmaMin <= 0 || c.SigmaMax < c.SigmaMin {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid blur proportion %v with length range [%d, %d] and sigma range [%v, %v]", c.Proportion, c.LengthMin, c.LengthMax, c.SigmaMin, c.SigmaMax))
+		return
+	}
+	return
+}
@@ -0,0 +1,75 @@
+package astiocr
+
+import (
+	"image"
+	"math"
+)
+
+// Area returns the box area
+func (b DetectionBox) Area() float64 {
+	if b.X2 <= b.X1 || b.Y2 <= b.Y1 {
+		return 0
+	}
+	return (b.X2 - b.X1) * (b.Y2 - b.Y1)
+}
+
+// Center returns the box center
+func (b DetectionBox) Center() (x, y float64) {
+	return (b.X1 + b.X2) / 2, (b.Y1 + b.Y2) / 2
+}
+
+// Contains checks whether o is entirely inside the box
+func (b DetectionBox) Contains(o DetectionBox) bool {
+	return o.X1 >= b.X1 && o.X2 <= b.X2 && o.Y1 >= b.Y1 && o.Y2 <= b.Y2
+}
+
+// Intersect returns the intersection of both boxes, or a zero box if they don't overlap
+func (b DetectionBox) Intersect(o DetectionBox) DetectionBox {
+	if !b.Overlaps(o) {
+		return DetectionBox{}
+	}
+	return DetectionBox{
+		X1: math.Max(b.X1, o.X1),
+		X2: math.Min(b.X2, o.X2),
+		Y1: math.Max(b.Y1, o.Y1),
+		Y2: math.Min(b.Y2, o.Y2),
+	}
+}
+
+// IoU returns the intersection over union of both boxes
+func (b DetectionBox) IoU(o DetectionBox) float64 {
+	i := b.Intersect(o).Area()
+	if i == 0 {
+		return 0
+	}
+	u := b.Area() + o.Area() - i
+	if u <= 0 {
+		return 0
+	}
+	return i / u
+}
+
+// Overlaps checks whether both boxes overlap
+func (b DetectionBox) Overlaps(o DetectionBox) bool {
+	return b.X1 < o.X2 && o.X1 < b.X2 && b.Y1 < o.Y2 && o.Y1 < b.Y2
+}
+
+// Rect converts the normalized box to the smallest pixel rectangle containing it in the provided bounds
+func (b DetectionBox) Rect(bounds image.Rectangle) image.Rectangle {
+	return image.Rect(
+		bounds.Min.X+int(math.Floor(b.X1*float64(bounds.Dx()))),
+		bounds.Min.Y+int(math.Floor(b.Y1*float64(bounds.Dy()))),
+		bounds.Min.X+int(math.Ceil(b.X2*float64(bounds.Dx()))),
+		bounds.Min.Y+int(math.Ceil(b.Y2*float64(bounds.Dy()))),
+	)
+}
+
+// Union returns the smallest box containing both boxes
+func (b DetectionBox) Union(o DetectionBox) DetectionBox {
+	return DetectionBox{
+		X1: math.Min(b.X1, o.X1),
+		X2: math.Max(b.X2, o.X2),
+		Y1: math.Min(b.Y1, o.Y1),
+		Y2: math.Max(b.Y2, o.Y2),
+	}
+}
@@ -0,0 +1,55 @@
+package astiocr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Charsets
+const (
+	CharsetDigits      = "0123456789"
+	CharsetLowercase   = "abcdefghijklmnopqrstuvwxyz"
+	CharsetPunctuation = ".,:;!?-/()%€$"
+	CharsetUppercase   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// DefaultCharset is the charset used when none is configured. Letters come first so that class indexes of
+// models trained on letters only remain valid.
+const DefaultCharset = CharsetLowercase + CharsetUppercase + CharsetDigits + CharsetPunctuation
+
+// charsetClasses are placeholders that can be used in charsets instead of listing all their characters
+var charsetClasses = strings.NewReplacer(
+	"[:digit:]", CharsetDigits,
+	"[:lower:]", CharsetLowercase,
+	"[:punct:]", CharsetPunctuation,
+	"[:upper:]", CharsetUppercase,
+)
+
+// parseCharset returns the characters of the charset, or of the default charset if it's empty, and makes
+// sure there are no duplicates since each character is a class
+func parseCharset(s string) (cs []rune, err error) {
+	// Default
+	if len(s) == 0 {
+		s = DefaultCharset
+	}
+
+	// Loop through characters
+	m := make(map[rune]bool)
+	for _, c := range charsetClasses.Replace(s) {
+		if m[c] {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: duplicate character %q in charset", c))
+			return
+		}
+		m[c] = true
+		cs = append(cs, c)
+	}
+	return
+}
+
+// charsetLabels returns the labels of the charset classes, the nth character being the label of class n+1
+func charsetLabels(cs []rune) (ls []string) {
+	for _, c := range cs {
+		ls = append(ls, string(c))
+	}
+	return
+}
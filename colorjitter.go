@@ -0,0 +1,115 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// ConfigurationColorJitter represents a color jitter configuration
+// The colors of the proportion of images are jittered after rendering: brightness, contrast and saturation
+// are multiplied by a random factor between 1 - max and 1 + max and hue is rotated by a random angle in
+// degrees between -hue max and hue max. When none of them is set, max is 0.2 and hue max is 10.
+type ConfigurationColorJitter struct {
+	BrightnessMax float64 `toml:"brightness_max"`
+	ContrastMax   float64 `toml:"contrast_max"`
+	HueMax        float64 `toml:"hue_max"`
+	Proportion    float64 `toml:"proportion"`
+	SaturationMax float64 `toml:"saturation_max"`
+}
+
+// GatherSummaryColorJitter represents the color jitter applied to a summary image
+type GatherSummaryColorJitter struct {
+	Brightness float64 `json:"brightness"`
+	Contrast   float64 `json:"contrast"`
+	// Hue rotation in degrees
+	Hue        float64 `json:"hue"`
+	Saturation float64 `json:"saturation"`
+}
+
+// random returns the color jitter applied to images
+func (c ConfigurationColorJitter) random() *GatherSummaryColorJitter {
+	factor := func(max float64) float64 { return 1 + (rand.Float64()*2-1)*max }
+	return &GatherSummaryColorJitter{
+		Brightness: factor(c.BrightnessMax),
+		Contrast:   factor(c.ContrastMax),
+		Hue:        (rand.Float64()*2 - 1) * c.HueMax,
+		Saturation: factor(c.SaturationMax),
+	}
+}
+
+// jitterColors applies the color jitter to each pixel of the image
+func jitterColors(img *image.RGBA, j *GatherSummaryColorJitter) {
+	// Contrast is adjusted around the mean luminance of the image
+	r := img.Bounds()
+	luminance := func(r, g, b float64) float64 { return 0.299*r + 0.587*g + 0.114*b }
+	var mean, count float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := img.RGBAAt(x, y); c.A > 0 {
+				a := float64(c.A)
+				mean += luminance(float64(c.R)/a, float64(c.G)/a, float64(c.B)/a)
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		mean /= count
+	}
+
+	// Hue is rotated around the gray axis
+	sin, cos := math.Sincos(j.Hue * math.Pi / 180)
+	rotate := func(v [3]float64) (o [3]float64) {
+		// Rodrigues' rotation formula with the normalized (1, 1, 1) axis
+		k := (1 - cos) / 3 * (v[0] + v[1] + v[2])
+		s := sin / math.Sqrt(3)
+		o[0] = v[0]*cos + s*(v[2]-v[1]) + k
+		o[1] = v[1]*cos + s*(v[0]-v[2]) + k
+		o[2] = v[2]*cos + s*(v[1]-v[0]) + k
+		return
+	}
+
+	// Loop through pixels
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(1, v)) }
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Colors are alpha-premultiplied
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			a := float64(c.A)
+			v := [3]float64{float64(c.R) / a, float64(c.G) / a, float64(c.B) / a}
+
+			// Brightness, contrast and saturation
+			for idx := range v {
+				v[idx] = clamp(v[idx] * j.Brightness)
+			}
+			for idx := range v {
+				v[idx] = clamp(mean + (v[idx]-mean)*j.Contrast)
+			}
+			l := luminance(v[0], v[1], v[2])
+			for idx := range v {
+				v[idx] = clamp(l + (v[idx]-l)*j.Saturation)
+			}
+
+			// Hue
+			v = rotate(v)
+			c.R, c.G, c.B = uint8(math.Round(clamp(v[0])*a)), uint8(math.Round(clamp(v[1])*a)), uint8(math.Round(clamp(v[2])*a))
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// validate applies the default color jitter options and checks them
+func (c *ConfigurationColorJitter) validate() (err error) {
+	if c.BrightnessMax == 0 && c.ContrastMax == 0 && c.HueMax == 0 && c.SaturationMax == 0 {
+		c.BrightnessMax, c.ContrastMax, c.HueMax, c.SaturationMax = 0.2, 0.2, 10, 0.2
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.BrightnessMax < 0 || c.BrightnessMax > 1 || c.ContrastMax < 0 || c.ContrastMax > 1 || c.HueMax < 0 || c.HueMax > 180 || c.SaturationMax < 0 || c.SaturationMax > 1 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid color jitter proportion %v with brightness max %v, contrast max %v, hue max %v and saturation max %v", c.Proportion, c.BrightnessMax, c.ContrastMax, c.HueMax, c.SaturationMax))
+		return
+	}
+	return
+}
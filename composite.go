@@ -0,0 +1,37 @@
+package astiocr
+
+import (
+	"image"
+	"image/draw"
+	"math/rand"
+)
+
+// ConfigurationComposite represents a composite configuration
+// The "composite" strategy draws words at random positions onto images of the directory, such as photos or
+// screenshots, which keep their own size.
+type ConfigurationComposite struct {
+	DirectoryPath string `toml:"directory_path"`
+}
+
+func (t *Trainer) createImageStrategyComposite(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontColor, font := p.FontColor, p.font
+	path := t.compositePaths[rand.Intn(len(t.compositePaths))]
+
+	// Decode
+	src, err := decodeImageFile(path)
+	if err != nil {
+		t.l.Debugf("astiocr: skipping composite image %s: %s", path, err)
+		return
+	}
+
+	// Create image
+	b := src.Bounds()
+	img, si = t.newImage(font, b.Dy(), b.Dx())
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	si.Background = path
+
+	// Scatter words
+	t.scatterWords(img, font, fontColor, &si)
+	return
+}
@@ -13,7 +13,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/asticode/go-astilog"
 	"github.com/asticode/go-astitools/archive"
 	"github.com/asticode/go-astitools/http"
 	"github.com/asticode/go-astitools/os"
@@ -77,7 +76,8 @@ func (t *Trainer) TrainedModels(ctx context.Context) (models map[string]string,
 	return
 }
 
-// Configure configures the model
+// Configure configures the model. If it fails or is cancelled, partially configured folders are removed
+// so that no inconsistent state is left behind.
 func (t *Trainer) Configure(ctx context.Context, modelName string) (err error) {
 	// Create configure folders
 	if err = t.createConfigureFolders(); err != nil {
@@ -85,6 +85,16 @@ func (t *Trainer) Configure(ctx context.Context, modelName string) (err error) {
 		return
 	}
 
+	// Clean up partial progress
+	defer func() {
+		if err == nil {
+			return
+		}
+		if errDefer := t.removeConfigureFolders(); errDefer != nil {
+			t.l.Error(errors.Wrap(errDefer, "astiocr: removing configure folders failed"))
+		}
+	}()
+
 	// Get trained models
 	var trainedModels map[string]string
 	if trainedModels, err = t.TrainedModels(ctx); err != nil {
@@ -95,7 +105,12 @@ func (t *Trainer) Configure(ctx context.Context, modelName string) (err error) {
 	// Requested model doesn't exist
 	url, ok := trainedModels[modelName]
 	if !ok {
-		err = fmt.Errorf("astiocr: model %s doesn't exist", modelName)
+		err = withKind(ErrModelNotFound, fmt.Errorf("astiocr: model %s doesn't exist", modelName))
+		return
+	}
+
+	// Check context
+	if err = checkContext(ctx, "creating train scripts"); err != nil {
 		return
 	}
 
@@ -105,32 +120,44 @@ func (t *Trainer) Configure(ctx context.Context, modelName string) (err error) {
 		return
 	}
 
+	// Check context
+	if err = checkContext(ctx, "creating config file"); err != nil {
+		return
+	}
+
 	// Create config file
 	if err = t.createConfigFile(ctx, modelName); err != nil {
 		err = errors.Wrap(err, "astiocr: creating config file failed")
 		return
 	}
 
+	// Check context
+	if err = checkContext(ctx, "setting up trained model"); err != nil {
+		return
+	}
+
 	// Set up trained model
-	if err = t.setUpTrainedModel(ctx, url); err != nil {
+	if err = t.setUpTrainedModel(ctx, url, t.trainedModelsIntegrity[modelName]); err != nil {
 		err = errors.Wrapf(err, "astiocr: setting up trained model %s failed", modelName)
 		return
 	}
 	return
 }
 
+// checkContext returns an error if the context is done before the stage starts
+func checkContext(ctx context.Context, stage string) (err error) {
+	if err = ctx.Err(); err != nil {
+		err = errors.Wrapf(err, "astiocr: context error before %s", stage)
+		return
+	}
+	return
+}
+
 func (t *Trainer) createConfigureFolders() (err error) {
 	// Remove folders
-	for _, p := range []string{
-		t.outputConfigDirectoryPath,
-		t.outputOutputDirectoryPath,
-		t.outputScriptsDirectoryPath,
-	} {
-		astilog.Debugf("astiocr: removing %s", p)
-		if err = os.RemoveAll(p); err != nil {
-			err = errors.Wrapf(err, "astiocr: removeAll %s failed", p)
-			return
-		}
+	if err = t.removeConfigureFolders(); err != nil {
+		err = errors.Wrap(err, "astiocr: removing configure folders failed")
+		return
 	}
 
 	// Loop through folders to create
@@ -143,9 +170,26 @@ func (t *Trainer) createConfigureFolders() (err error) {
 		filepath.Join(t.outputOutputDirectoryPath, "training"),
 		t.outputScriptsDirectoryPath,
 	} {
-		astilog.Debugf("astiocr: creating %s", p)
+		t.l.Debugf("astiocr: creating %s", p)
 		if err = os.MkdirAll(p, 0700); err != nil {
 			err = errors.Wrapf(err, "astiocr: mkdirall %s failed", p)
+			return
+		}
+	}
+	return
+}
+
+func (t *Trainer) removeConfigureFolders() (err error) {
+	// Loop through folders
+	for _, p := range []string{
+		t.outputConfigDirectoryPath,
+		t.outputOutputDirectoryPath,
+		t.outputScriptsDirectoryPath,
+	} {
+		t.l.Debugf("astiocr: removing %s", p)
+		if err = os.RemoveAll(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: removeAll %s failed", p)
+			return
 		}
 	}
 	return
@@ -162,9 +206,15 @@ func (t *Trainer) createTrainScripts(ctx context.Context) (err error) {
 		"eval",
 		"export_inference_graph",
 	} {
+		// Check context
+		if err = checkContext(ctx, "copying "+n+" script"); err != nil {
+			return
+		}
+
+		// Copy
 		src := filepath.Join(t.tensorFlowModelsDirectoryPath, "research", "object_detection", n+".py")
 		dst := filepath.Join(t.outputScriptsDirectoryPath, n+".py")
-		astilog.Debugf("astiocr: copying %s to %s", src, dst)
+		t.l.Debugf("astiocr: copying %s to %s", src, dst)
 		if err = astios.Copy(ctx, src, dst); err != nil {
 			err = errors.Wrap(err, "astiocr: copying train script failed")
 			return
@@ -182,7 +232,7 @@ func (t *Trainer) createTrainScripts(ctx context.Context) (err error) {
 			".sh",
 		} {
 			p := filepath.Join(t.outputScriptsDirectoryPath, n+ext)
-			astilog.Debugf("astiocr: creating %s script in %s", n, p)
+			t.l.Debugf("astiocr: creating %s script in %s", n, p)
 			if err = t.createScript(ctx, p, s); err != nil {
 				err = errors.Wrapf(err, "astiocr: creating %s script in %s failed", n, p)
 				return
@@ -223,7 +273,7 @@ var (
 func (t *Trainer) createConfigFile(ctx context.Context, modelName string) (err error) {
 	// Open file
 	src := filepath.Join(t.tensorFlowModelsDirectoryPath, "research", "object_detection", "samples", "configs", modelName+".config")
-	astilog.Debugf("astiocr: opening %s", src)
+	t.l.Debugf("astiocr: opening %s", src)
 	var srcFile *os.File
 	if srcFile, err = os.Open(src); err != nil {
 		err = errors.Wrapf(err, "astiocr: opening %s failed", src)
@@ -233,7 +283,7 @@ func (t *Trainer) createConfigFile(ctx context.Context, modelName string) (err e
 
 	// Create file
 	dst := filepath.Join(t.outputConfigDirectoryPath, "model.config")
-	astilog.Debugf("astiocr: creating %s", dst)
+	t.l.Debugf("astiocr: creating %s", dst)
 	var dstFile *os.File
 	if dstFile, err = os.Create(dst); err != nil {
 		err = errors.Wrapf(err, "astiocr: creating %s failed", dst)
@@ -245,14 +295,14 @@ func (t *Trainer) createConfigFile(ctx context.Context, modelName string) (err e
 	r := bufio.NewReader(srcFile)
 
 	// Loop through lines
-	astilog.Debugf("astiocr: updating %s", dst)
+	t.l.Debugf("astiocr: updating %s", dst)
 	var inEvalReader bool
 	for {
 		// Read line
 		var l string
 		var errRead error
 		if l, errRead = r.ReadString('\n'); errRead != nil && errRead != io.EOF {
-			err = errors.Wrap(err, "astiocr: reading line failed")
+			err = errors.Wrap(errRead, "astiocr: reading line failed")
 			return
 		}
 
@@ -263,7 +313,7 @@ func (t *Trainer) createConfigFile(ctx context.Context, modelName string) (err e
 
 		// Loop through regexp
 		for r, v := range map[*regexp.Regexp]string{
-			regexpNumClasses:         "2", // strconv.Itoa(len(characters)),
+			regexpNumClasses:         strconv.Itoa(len(t.charset)),
 			regexpBatchSize:          "10",
 			regexpFineTuneCheckpoint: "\"config/model.ckpt\"",
 			regexpNumSteps:           numSteps,
@@ -307,41 +357,62 @@ func (t *Trainer) createConfigFile(ctx context.Context, modelName string) (err e
 	return
 }
 
-func (t *Trainer) setUpTrainedModel(ctx context.Context, url string) (err error) {
+func (t *Trainer) setUpTrainedModel(ctx context.Context, url string, i ConfigurationIntegrity) (err error) {
 	// Create temp dir
 	var tempDirPath string
 	if tempDirPath, err = ioutil.TempDir(os.TempDir(), "astiocr_trainer_"); err != nil {
 		err = errors.Wrap(err, "astiocr: creating temp dir failed")
 		return
 	}
-	astilog.Debugf("astiocr: created temp dir %s", tempDirPath)
+	t.l.Debugf("astiocr: created temp dir %s", tempDirPath)
 
 	// Make sure to remove the temp dir at the end of the process
 	defer func() {
 		// Remove
-		astilog.Debugf("astiocr: removing %s", tempDirPath)
+		t.l.Debugf("astiocr: removing %s", tempDirPath)
 		if errDefer := os.RemoveAll(tempDirPath); errDefer != nil {
-			astilog.Error(errors.Wrapf(errDefer, "astiocr: removing %s failed", tempDirPath))
+			t.l.Error(errors.Wrapf(errDefer, "astiocr: removing %s failed", tempDirPath))
 			return
 		}
 	}()
 
-	// Download
+	// Download to a temporary file first so that an interrupted download doesn't end up in the cache
 	p := filepath.Join(t.cacheDirectoryPath, filepath.Base(url))
 	if _, err = os.Stat(p); err != nil && !os.IsNotExist(err) {
 		err = errors.Wrapf(err, "astiocr: stating %s failed", p)
+		return
 	} else if os.IsNotExist(err) {
-		astilog.Debugf("astiocr: downloading %s to %s", url, p)
-		if err = astihttp.Download(ctx, &http.Client{}, url, p); err != nil {
-			err = errors.Wrapf(err, "astiocr: downloading %s to %s failed", url, p)
+		pp := p + ".part"
+		t.l.Debugf("astiocr: downloading %s to %s", url, pp)
+		if err = astihttp.Download(ctx, &http.Client{}, url, pp); err != nil {
+			os.Remove(pp)
+			err = errors.Wrapf(err, "astiocr: downloading %s to %s failed", url, pp)
+			return
+		}
+		if err = verifyFile(pp, i); err != nil {
+			os.Remove(pp)
+			err = errors.Wrapf(err, "astiocr: verifying %s failed", url)
+			return
+		}
+		if err = os.Rename(pp, p); err != nil {
+			err = errors.Wrapf(err, "astiocr: renaming %s to %s failed", pp, p)
 			return
 		}
 	} else {
-		astilog.Debugf("astiocr: %s already exists, skipping download of %s", p, url)
+		t.l.Debugf("astiocr: %s already exists, skipping download of %s", p, url)
+		if err = verifyFile(p, i); err != nil {
+			err = errors.Wrapf(err, "astiocr: verifying cached %s failed, remove it to download it again", p)
+			return
+		}
+	}
+
+	// Check context
+	if err = checkContext(ctx, "untaring"); err != nil {
+		return
 	}
 
 	// Untar
-	astilog.Debugf("astiocr: untaring %s into %s", p, tempDirPath)
+	t.l.Debugf("astiocr: untaring %s into %s", p, tempDirPath)
 	if err = astiarchive.Untar(ctx, p, tempDirPath); err != nil {
 		err = errors.Wrapf(err, "astiocr: untaring %s into %s failed", p, tempDirPath)
 		return
@@ -354,6 +425,11 @@ func (t *Trainer) setUpTrainedModel(ctx context.Context, url string) (err error)
 			return e
 		}
 
+		// Check context
+		if err = checkContext(ctx, "copying "+path); err != nil {
+			return
+		}
+
 		// Only process files which contain ".ckpt."
 		b := filepath.Base(info.Name())
 		if info.IsDir() || strings.Index(b, ".ckpt.") == -1 {
@@ -362,7 +438,7 @@ func (t *Trainer) setUpTrainedModel(ctx context.Context, url string) (err error)
 
 		// Copy file
 		dst := filepath.Join(t.outputConfigDirectoryPath, b)
-		astilog.Debugf("astiocr: copying %s to %s", path, dst)
+		t.l.Debugf("astiocr: copying %s to %s", path, dst)
 		if err = astios.Copy(ctx, path, dst); err != nil {
 			err = errors.Wrapf(err, "astiocr: copying %s into %s failed", path, dst)
 			return
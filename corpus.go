@@ -0,0 +1,108 @@
+package astiocr
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Minimum proportion of characters of a corpus sentence that must be in the charset for it to be kept
+const corpusMinCharsetProportion = 0.5
+
+// loadCorpus splits the text of the file, or of all the files of the directory, into sentences whose
+// characters outside the charset are removed
+func (t *Trainer) loadCorpus(p string) (ss [][]string, err error) {
+	// Get paths
+	var ps []string
+	if err = filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			ps = append(ps, path)
+		}
+		return nil
+	}); err != nil {
+		err = errors.Wrapf(err, "astiocr: walking %s failed", p)
+		return
+	}
+
+	// Loop through paths
+	var dropped int
+	for _, path := range ps {
+		// Read file
+		var b []byte
+		if b, err = ioutil.ReadFile(path); err != nil {
+			err = errors.Wrapf(err, "astiocr: reading %s failed", path)
+			return
+		}
+
+		// Loop through sentences
+		for _, s := range splitSentences(string(b)) {
+			if ws, ok := t.filterSentence(s); ok {
+				ss = append(ss, ws)
+			} else {
+				dropped++
+			}
+		}
+	}
+	t.l.Debugf("astiocr: %d sentences loaded from %s, %d dropped since they contain too many characters outside the charset", len(ss), p, dropped)
+	return
+}
+
+// splitSentences splits the text into sentences, each being a list of words. Sentences end with a word
+// ending with ".", "!" or "?", or with an empty line.
+func splitSentences(text string) (ss [][]string) {
+	var s []string
+	for _, l := range strings.Split(text, "\n") {
+		// Empty lines end sentences
+		fs := strings.Fields(l)
+		if len(fs) == 0 && len(s) > 0 {
+			ss = append(ss, s)
+			s = nil
+		}
+
+		// Loop through words
+		for _, f := range fs {
+			s = append(s, f)
+			if strings.ContainsAny(f[len(f)-1:], ".!?") {
+				ss = append(ss, s)
+				s = nil
+			}
+		}
+	}
+	if len(s) > 0 {
+		ss = append(ss, s)
+	}
+	return
+}
+
+// filterSentence removes characters outside the charset and drops words left empty. It returns false if
+// too many characters have been removed.
+func (t *Trainer) filterSentence(s []string) (ws []string, ok bool) {
+	var total, kept int
+	for _, w := range s {
+		var rs []rune
+		for _, r := range w {
+			total++
+			if t.labelIndex(r) > 0 {
+				rs = append(rs, r)
+			}
+		}
+		if len(rs) > 0 {
+			kept += len(rs)
+			ws = append(ws, string(rs))
+		}
+	}
+	ok = len(ws) > 0 && float64(kept) >= corpusMinCharsetProportion*float64(total)
+	return
+}
+
+// randomCorpusSentence returns a copy of a random corpus sentence
+func (t *Trainer) randomCorpusSentence() []string {
+	return append([]string(nil), t.sentences[rand.Intn(len(t.sentences))]...)
+}
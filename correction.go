@@ -0,0 +1,120 @@
+package astiocr
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default maximum edit distance between a word and its correction
+const defaultCorrectionMaxDistance = 2
+
+// Correct replaces, in the corrected text of each word, words not found in the dictionary with the
+// closest dictionary word within maxDistance edits. Raw text is left untouched. The correction
+// confidence decreases with the edit distance and with the number of equally close dictionary words.
+func (p *DocumentPage) Correct(d *Dictionary, maxDistance int) {
+	for bi := range p.Blocks {
+		for li := range p.Blocks[bi].Lines {
+			for wi := range p.Blocks[bi].Lines[li].Words {
+				correctWord(&p.Blocks[bi].Lines[li].Words[wi], d, maxDistance)
+			}
+		}
+	}
+}
+
+func correctWord(w *DocumentWord, d *Dictionary, maxDistance int) {
+	// Word is in the dictionary
+	t := w.Text()
+	rs := []rune(strings.ToLower(t))
+	if len(rs) == 0 || d.Contains(t) {
+		w.Corrected = t
+		w.CorrectionConfidence = 1
+		return
+	}
+
+	// Loop through dictionary words with a close enough length
+	best, count := maxDistance+1, 0
+	var correction []rune
+	for l := len(rs) - maxDistance; l <= len(rs)+maxDistance; l++ {
+		for _, c := range d.byLength[l] {
+			if dist := levenshtein(rs, c, best+1); dist < best {
+				best, count, correction = dist, 1, c
+			} else if dist == best && correction != nil {
+				count++
+			}
+		}
+	}
+
+	// No correction found
+	if correction == nil {
+		w.Corrected = t
+		w.CorrectionConfidence = 0
+		return
+	}
+
+	// Update word
+	w.Corrected = matchCase(string(correction), t)
+	w.CorrectionConfidence = (1 - float64(best)/float64(len(rs))) / float64(count)
+	if w.CorrectionConfidence < 0 {
+		w.CorrectionConfidence = 0
+	}
+}
+
+// levenshtein returns the edit distance between a and b, or max if it is greater than or equal to max
+func levenshtein(a, b []rune, max int) int {
+	// Lengths are too different
+	if d := len(a) - len(b); d >= max || -d >= max {
+		return max
+	}
+
+	// Loop through rows
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if v := prev[j] + 1; v < cur[j] {
+				cur[j] = v
+			}
+			if v := cur[j-1] + 1; v < cur[j] {
+				cur[j] = v
+			}
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+
+		// Distance can only grow from now on
+		if rowMin >= max {
+			return max
+		}
+		prev, cur = cur, prev
+	}
+	if prev[len(b)] > max {
+		return max
+	}
+	return prev[len(b)]
+}
+
+// matchCase applies the case of the reference to the word: upper case if the reference is in upper case,
+// capitalized if its first letter is in upper case
+func matchCase(w, ref string) string {
+	rs := []rune(ref)
+	switch {
+	case len(rs) > 1 && strings.ToUpper(ref) == ref && strings.ToLower(ref) != ref:
+		return strings.ToUpper(w)
+	case len(rs) > 0 && unicode.IsUpper(rs[0]):
+		ws := []rune(w)
+		ws[0] = unicode.ToUpper(ws[0])
+		return string(ws)
+	}
+	return w
+}
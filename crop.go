@@ -0,0 +1,52 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Crop returns the region of the image delimited by each result's box, extended by padding pixels on each
+// side and clipped to the image bounds. Crops are copies and can be modified safely.
+func Crop(img image.Image, rs []DetectionResult, padding int) (cs []image.Image) {
+	for _, r := range rs {
+		cs = append(cs, cropBox(img, r.Box, padding))
+	}
+	return
+}
+
+// WriteCrops writes the crop of each result as a png file in the directory, which is created if needed,
+// and returns the paths in the same order as the results
+func WriteCrops(img image.Image, rs []DetectionResult, padding int, dirPath string) (ps []string, err error) {
+	// Create dir
+	if err = os.MkdirAll(dirPath, 0755); err != nil {
+		err = errors.Wrapf(err, "astiocr: mkdirall %s failed", dirPath)
+		return
+	}
+
+	// Loop through crops
+	for idx, c := range Crop(img, rs, padding) {
+		p := filepath.Join(dirPath, fmt.Sprintf("%d.png", idx+1))
+		if err = storePNG(p, c); err != nil {
+			err = errors.Wrapf(err, "astiocr: storing crop %s failed", p)
+			return
+		}
+		ps = append(ps, p)
+	}
+	return
+}
+
+func cropBox(img image.Image, b DetectionBox, padding int) image.Image {
+	// Get rectangle
+	ib := img.Bounds()
+	r := b.Rect(ib).Inset(-padding).Intersect(ib)
+
+	// Copy
+	c := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(c, c.Bounds(), img, r.Min, draw.Src)
+	return c
+}
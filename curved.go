@@ -0,0 +1,326 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+
+	"golang.org/x/image/draw"
+	ft "golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+)
+
+// Curved text layout constants
+const (
+	// Maximum number of paths per image
+	curvedMaxPaths = 3
+	// Number of segments curves are approximated with
+	curvedPathSegments = 200
+)
+
+// textPath is a polyline approximating a curve along which text is laid out
+type textPath struct {
+	// Distance of each point from the start of the path
+	lengths []float64
+	points  [][2]float64
+}
+
+// newTextPath approximates the curve, f mapping [0, 1] to points of the curve
+func newTextPath(f func(u float64) (x, y float64)) (p textPath) {
+	for idx := 0; idx <= curvedPathSegments; idx++ {
+		x, y := f(float64(idx) / curvedPathSegments)
+		var l float64
+		if idx > 0 {
+			prev := p.points[idx-1]
+			l = p.lengths[idx-1] + math.Hypot(x-prev[0], y-prev[1])
+		}
+		p.points = append(p.points, [2]float64{x, y})
+		p.lengths = append(p.lengths, l)
+	}
+	return
+}
+
+func (p textPath) length() float64 {
+	return p.lengths[len(p.lengths)-1]
+}
+
+// at returns the point located at the distance from the start of the path, and the angle of the path at
+// this point, in radians
+func (p textPath) at(d float64) (x, y, angle float64) {
+	// Get segment
+	idx := sort.SearchFloat64s(p.lengths, d)
+	if idx < 1 {
+		idx = 1
+	} else if idx > len(p.lengths)-1 {
+		idx = len(p.lengths) - 1
+	}
+	a, b := p.points[idx-1], p.points[idx]
+
+	// Interpolate
+	var r float64
+	if l := p.lengths[idx] - p.lengths[idx-1]; l > 0 {
+		r = (d - p.lengths[idx-1]) / l
+	}
+	x, y = a[0]+r*(b[0]-a[0]), a[1]+r*(b[1]-a[1])
+	angle = math.Atan2(b[1]-a[1], b[0]-a[0])
+	return
+}
+
+// randomTextPath returns either a circular arc, read clockwise on top of its circle or counterclockwise
+// below it like on stamps and watch faces, or a cubic bezier curve whose control points are sorted
+// horizontally so that it's read from left to right. Paths are inside the rectangle.
+func randomTextPath(r image.Rectangle) textPath {
+	// Bezier curve
+	w, h := float64(r.Dx()), float64(r.Dy())
+	if rand.Intn(2) == 0 {
+		var ps [4][2]float64
+		for idx := range ps {
+			ps[idx] = [2]float64{float64(r.Min.X) + rand.Float64()*w, float64(r.Min.Y) + rand.Float64()*h}
+		}
+		sort.Slice(ps[:], func(i, j int) bool { return ps[i][0] < ps[j][0] })
+		return newTextPath(func(u float64) (x, y float64) {
+			a, b, c, d := (1-u)*(1-u)*(1-u), 3*(1-u)*(1-u)*u, 3*(1-u)*u*u, u*u*u
+			return a*ps[0][0] + b*ps[1][0] + c*ps[2][0] + d*ps[3][0], a*ps[0][1] + b*ps[1][1] + c*ps[2][1] + d*ps[3][1]
+		})
+	}
+
+	// Arc whose circle fits in the rectangle
+	radius := math.Min(w, h) / 2 * (0.5 + rand.Float64()/2)
+	cx := float64(r.Min.X) + radius + rand.Float64()*(w-2*radius)
+	cy := float64(r.Min.Y) + radius + rand.Float64()*(h-2*radius)
+	span := math.Pi/2 + rand.Float64()*math.Pi
+	middle, direction := -math.Pi/2, 1.0
+	if rand.Intn(2) == 0 {
+		middle, direction = math.Pi/2, -1
+	}
+	middle += (rand.Float64() - 0.5) * math.Pi / 2
+	return newTextPath(func(u float64) (x, y float64) {
+		a := middle + direction*(u-0.5)*span
+		return cx + radius*math.Cos(a), cy + radius*math.Sin(a)
+	})
+}
+
+// rotatedGlyph represents a glyph whose baseline middle is located at (x, y) and which is rotated around
+// it by the angle, in radians
+type rotatedGlyph struct {
+	angle float64
+	face  ft.Face
+	font  *font
+	r     rune
+	x, y  float64
+}
+
+// transform returns the transformation from glyph coordinates, whose origin is the glyph dot, to image
+// coordinates, as well as the glyph bounds in glyph coordinates
+func (g rotatedGlyph) transform() (m f64.Aff3, b image.Rectangle) {
+	gb, adv, ok := g.face.GlyphBounds(g.r)
+	if !ok {
+		return
+	}
+	b = glyphRect(gb)
+	ox := float64(adv) / 64 / 2
+	sin, cos := math.Sincos(g.angle)
+	m = f64.Aff3{
+		cos, -sin, g.x - cos*ox,
+		sin, cos, g.y - sin*ox,
+	}
+	return
+}
+
+// bounds returns the smallest rectangle containing the rotated glyph bounds, which contains the glyph ink
+func (g rotatedGlyph) bounds() (o image.Rectangle) {
+	m, b := g.transform()
+	if b.Empty() {
+		return
+	}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{float64(b.Min.X), float64(b.Min.Y)}, {float64(b.Max.X), float64(b.Min.Y)}, {float64(b.Min.X), float64(b.Max.Y)}, {float64(b.Max.X), float64(b.Max.Y)}} {
+		x, y := m[0]*p[0]+m[1]*p[1]+m[2], m[3]*p[0]+m[4]*p[1]+m[5]
+		minX, minY, maxX, maxY = math.Min(minX, x), math.Min(minY, y), math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// draw draws the rotated glyph, with the effects of the summary image if any, and returns the tight box of
+// its ink clipped to the image
+func (g rotatedGlyph) draw(img draw.Image, fontColor color.Color, si *GatherSummaryImage) (o image.Rectangle) {
+	// Get destination, leaving room for the effects
+	m, b := g.transform()
+	dr := g.bounds()
+	if dr.Empty() {
+		return
+	}
+	dr = dr.Inset(-effectsPadding(si))
+	if dr = dr.Intersect(img.Bounds()); dr.Empty() {
+		return
+	}
+
+	// Draw glyph mask
+	mask := image.NewAlpha(b)
+	d := &ft.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: g.face,
+	}
+	d.DrawString(string(g.r))
+
+	// Rotate mask
+	ink := image.NewAlpha(dr)
+	draw.BiLinear.Transform(ink, m, mask, b, draw.Src, nil)
+
+	// Draw
+	drawEffects(img, ink, si)
+	draw.DrawMask(img, dr, image.NewUniform(inkColor(fontColor, si)), image.ZP, ink, dr.Min, draw.Over)
+
+	// Get tight box
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			if ink.AlphaAt(x, y).A > 0 {
+				o = o.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return
+}
+
+func (t *Trainer) createImageStrategyCurved(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
+	fc := newFaceCache(fontSize)
+	space := float64(ft.MeasureString(fc.face(font), " ")) / 64
+
+	// Create image
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
+
+	// Paths are far enough from the edges for glyphs not to be clipped
+	margin := 2 * fontSize
+	area := image.Rect(margin, margin, t.image.Width-margin, t.image.Height-margin)
+	if area.Empty() {
+		return
+	}
+
+	// Loop through paths
+	var occupied []image.Rectangle
+	for count := 1 + rand.Intn(curvedMaxPaths); count > 0; count-- {
+		// Get words fitting in the path
+		p := randomTextPath(area)
+		var ws []spacedWord
+		var width float64
+		for {
+			rw := t.randomWord()
+			w := t.spaceWord(fc, rw, t.wordFonts(font, rw))
+			l := float64(w.width)
+			if len(ws) > 0 {
+				l += space
+			}
+			if width+l > p.length() {
+				break
+			}
+			ws = append(ws, w)
+			width += l
+		}
+
+		// No word fits
+		if len(ws) == 0 {
+			continue
+		}
+
+		// Lay out glyphs along the path
+		gs, bs := t.layoutTextPath(fc, p, ws, space, rand.Float64()*(p.length()-width), &si)
+
+		// Path overlaps previous paths
+		if overlapsRectangles(bs, occupied) {
+			continue
+		}
+		occupied = append(occupied, bs...)
+
+		// Loop through words
+		for idx, wgs := range gs {
+			// Loop through glyphs
+			var wr image.Rectangle
+			for _, g := range wgs {
+				// Glyph has no ink
+				b := drawGlyph(img, g, fontColor, &si)
+				if b.Empty() {
+					continue
+				}
+				wr = wr.Union(b)
+
+				// Show box
+				if t.showBox {
+					t.drawBox(b.Min.X, b.Max.X, b.Min.Y, b.Max.Y, img, fontColor)
+				}
+
+				// Add box to summary
+				sb := t.summaryBox(string(g.r), t.labelIndex(g.r), fontSize, b, g.font)
+				sb.Angle = -g.angle * 180 / math.Pi
+				si.Boxes = append(si.Boxes, sb)
+			}
+
+			// Add word to summary
+			si.Words = append(si.Words, GatherSummaryWord{
+				Label: ws[idx].word,
+				X0:    wr.Min.X,
+				X1:    wr.Max.X,
+				Y0:    wr.Min.Y,
+				Y1:    wr.Max.Y,
+			})
+		}
+	}
+	return
+}
+
+// layoutTextPath places the glyphs of the words along the path starting at the distance from its start,
+// and returns the glyphs of each word as well as the bounds of all glyphs
+func (t *Trainer) layoutTextPath(c *faceCache, p textPath, ws []spacedWord, space, pos float64, si *GatherSummaryImage) (gs [][]rotatedGlyph, bs []image.Rectangle) {
+	for _, w := range ws {
+		// Loop through characters
+		var wgs []rotatedGlyph
+		rs := []rune(w.word)
+		for idx, r := range rs {
+			// Kern and space
+			face := c.face(w.fonts[idx])
+			if idx > 0 {
+				if w.fonts[idx] == w.fonts[idx-1] {
+					pos += float64(face.Kern(rs[idx-1], r)) / 64
+				}
+				pos += float64(w.spacings[idx-1]) / 64
+			}
+
+			// Place glyph
+			adv, _ := face.GlyphAdvance(r)
+			x, y, angle := p.at(pos + float64(adv)/64/2)
+			g := rotatedGlyph{
+				angle: angle,
+				face:  face,
+				font:  w.fonts[idx],
+				r:     r,
+				x:     x,
+				y:     y,
+			}
+			if t.rotates() {
+				g = t.rotateGlyph(g, si)
+			}
+			wgs = append(wgs, g)
+			bs = append(bs, g.bounds())
+			pos += float64(adv) / 64
+		}
+		gs = append(gs, wgs)
+		pos += space
+	}
+	return
+}
+
+// overlapsRectangles checks whether any rectangle of a overlaps any rectangle of b
+func overlapsRectangles(a, b []image.Rectangle) bool {
+	for _, ra := range a {
+		for _, rb := range b {
+			if ra.Overlaps(rb) {
+				return true
+			}
+		}
+	}
+	return false
+}
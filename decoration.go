@@ -0,0 +1,94 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+)
+
+// Decorations
+const (
+	decorationStrikethrough = "strikethrough"
+	decorationUnderline     = "underline"
+)
+
+// Decoration layout constants
+const (
+	// Distance between the baseline and the underline, as a proportion of the font size
+	decorationUnderlineOffset = 0.12
+	// Thickness of decoration lines, as a proportion of the font size
+	decorationThickness = 0.07
+)
+
+// randomDecoration returns the decoration of a word according to the configured proportions, or "" if
+// the word is not decorated
+func (t *Trainer) randomDecoration() string {
+	// No decorations
+	if t.decorations == (ConfigurationDecorations{}) {
+		return ""
+	}
+
+	// Pick decoration
+	r := rand.Float64() * 100
+	switch {
+	case r < t.decorations.StrikethroughProportion:
+		return decorationStrikethrough
+	case r < t.decorations.StrikethroughProportion+t.decorations.UnderlineProportion:
+		return decorationUnderline
+	}
+	return ""
+}
+
+// drawDecoration draws the decoration of the word whose baseline goes from (x0, y) to (x1, y) before
+// rotation
+func (t *Trainer) drawDecoration(img draw.Image, c *faceCache, f *font, fontColor color.Color, decoration string, x0, x1, y int, si *GatherSummaryImage) {
+	// Get line position
+	fy := float64(y)
+	switch decoration {
+	case decorationStrikethrough:
+		m := c.face(f).Metrics()
+		xHeight := float64(m.XHeight) / 64
+		if xHeight <= 0 {
+			xHeight = float64(m.Ascent) / 64 / 2
+		}
+		fy -= xHeight / 2
+	case decorationUnderline:
+		fy += float64(c.fontSize) * decorationUnderlineOffset
+	}
+
+	// Rotate line
+	ax, ay, bx, by := float64(x0), fy, float64(x1), fy
+	if t.rotates() {
+		ax, ay = rotatePoint(ax, ay, si)
+		bx, by = rotatePoint(bx, by, si)
+	}
+
+	// Draw line
+	drawLine(img, ax, ay, bx, by, math.Max(1, float64(c.fontSize)*decorationThickness), inkColor(fontColor, si))
+}
+
+// drawLine draws the segment with the thickness, filling the pixels whose center is close enough to it
+func drawLine(img draw.Image, ax, ay, bx, by, thickness float64, c color.Color) {
+	// Get bounds
+	h := thickness / 2
+	r := image.Rect(int(math.Floor(math.Min(ax, bx)-h)), int(math.Floor(math.Min(ay, by)-h)), int(math.Ceil(math.Max(ax, bx)+h)), int(math.Ceil(math.Max(ay, by)+h))).Intersect(img.Bounds())
+
+	// Loop through pixels
+	dx, dy := bx-ax, by-ay
+	l := dx*dx + dy*dy
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Get the closest point of the segment
+			px, py := float64(x)+0.5, float64(y)+0.5
+			var u float64
+			if l > 0 {
+				u = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/l))
+			}
+			if math.Hypot(px-ax-u*dx, py-ay-u*dy) <= h {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Denoise constants
+const (
+	// Default kernel size, in pixels
+	denoiseDefaultKernelSize = 3
+	// Default bilateral sigma color, in 8-bit color levels
+	denoiseDefaultSigmaColor = 30
+)
+
+func newDenoiseStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	// Default values
+	kernelSize, sigmaColor := c.KernelSize, c.SigmaColor
+	if kernelSize == 0 {
+		kernelSize = denoiseDefaultKernelSize
+	}
+	if sigmaColor == 0 {
+		sigmaColor = denoiseDefaultSigmaColor
+	}
+	if kernelSize < 0 || kernelSize%2 == 0 || sigmaColor < 0 {
+		err = fmt.Errorf("astiocr: invalid kernel size %d and sigma color %v", kernelSize, sigmaColor)
+		return
+	}
+
+	// Get filter
+	var f func(ns []color.RGBA, ds []image.Point) color.RGBA
+	switch c.Method {
+	case "", "median":
+		f = medianFilter
+	case "bilateral":
+		f = bilateralFilter(float64(kernelSize)/2, sigmaColor)
+	default:
+		err = fmt.Errorf("astiocr: invalid denoise method %s", c.Method)
+		return
+	}
+
+	s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		return filterKernel(img, kernelSize, f), identityBox
+	}
+	return
+}
+
+// filterKernel creates a new image whose pixels are the result of f applied to the pixels of the
+// kernel around each source pixel. f receives the neighbors with their offset to the center, the center
+// being first.
+func filterKernel(src image.Image, kernelSize int, f func(ns []color.RGBA, ds []image.Point) color.RGBA) image.Image {
+	// Get offsets
+	ds := []image.Point{{}}
+	for dy := -kernelSize / 2; dy <= kernelSize/2; dy++ {
+		for dx := -kernelSize / 2; dx <= kernelSize/2; dx++ {
+			if dx != 0 || dy != 0 {
+				ds = append(ds, image.Pt(dx, dy))
+			}
+		}
+	}
+
+	// Loop through pixels
+	r := src.Bounds()
+	dst := image.NewRGBA(r)
+	ns := make([]color.RGBA, 0, len(ds))
+	nds := make([]image.Point, 0, len(ds))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Get neighbors
+			ns, nds = ns[:0], nds[:0]
+			for _, d := range ds {
+				if p := image.Pt(x+d.X, y+d.Y); p.In(r) {
+					ns = append(ns, color.RGBAModel.Convert(src.At(p.X, p.Y)).(color.RGBA))
+					nds = append(nds, d)
+				}
+			}
+
+			// Filter
+			dst.SetRGBA(x, y, f(ns, nds))
+		}
+	}
+	return dst
+}
+
+// medianFilter keeps the median of each channel, which removes salt and pepper noise while keeping edges
+func medianFilter(ns []color.RGBA, _ []image.Point) color.RGBA {
+	median := func(v func(c color.RGBA) uint8) uint8 {
+		vs := make([]int, 0, len(ns))
+		for _, n := range ns {
+			vs = append(vs, int(v(n)))
+		}
+		sort.Ints(vs)
+		return uint8(vs[len(vs)/2])
+	}
+	return color.RGBA{
+		R: median(func(c color.RGBA) uint8 { return c.R }),
+		G: median(func(c color.RGBA) uint8 { return c.G }),
+		B: median(func(c color.RGBA) uint8 { return c.B }),
+		A: ns[0].A,
+	}
+}
+
+// bilateralFilter averages neighbors weighted by both their distance and their color difference to the
+// center, which smooths noise without blurring edges
+func bilateralFilter(sigmaSpace, sigmaColor float64) func(ns []color.RGBA, ds []image.Point) color.RGBA {
+	return func(ns []color.RGBA, ds []image.Point) color.RGBA {
+		var r, g, b, sum float64
+		c := ns[0]
+		for idx, n := range ns {
+			dr, dg, db := float64(n.R)-float64(c.R), float64(n.G)-float64(c.G), float64(n.B)-float64(c.B)
+			w := math.Exp(-float64(ds[idx].X*ds[idx].X+ds[idx].Y*ds[idx].Y)/(2*sigmaSpace*sigmaSpace) -
+				(dr*dr+dg*dg+db*db)/(2*sigmaColor*sigmaColor))
+			r += w * float64(n.R)
+			g += w * float64(n.G)
+			b += w * float64(n.B)
+			sum += w
+		}
+		return color.RGBA{R: uint8(math.Round(r / sum)), G: uint8(math.Round(g / sum)), B: uint8(math.Round(b / sum)), A: c.A}
+	}
+}
@@ -0,0 +1,139 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Deskew constants
+const (
+	// Default maximum skew, in degrees, that is corrected
+	deskewDefaultMaxAngle = 5
+	// Precision, in degrees, of the skew estimation
+	deskewAngleStep = 0.25
+	// Images are subsampled so that the skew estimation processes at most this number of pixels per side
+	deskewMaxSamples = 800
+)
+
+func newDeskewStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	// Max angle
+	maxAngle := c.MaxAngle
+	if maxAngle == 0 {
+		maxAngle = deskewDefaultMaxAngle
+	}
+	if maxAngle < 0 || maxAngle >= 45 {
+		err = fmt.Errorf("astiocr: invalid max angle %v", maxAngle)
+		return
+	}
+
+	s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		// Estimate skew
+		a := estimateSkew(img, maxAngle)
+		if a == 0 {
+			return img, identityBox
+		}
+		return rotateImage(img, a)
+	}
+	return
+}
+
+// estimateSkew returns the angle, in degrees, of the text lines of the image using projection profiles:
+// foreground pixels are projected on the axis perpendicular to each candidate angle, and the angle whose
+// profile has the sharpest transitions is kept. Positive angles mean lines go down from left to right.
+func estimateSkew(img image.Image, maxAngle float64) float64 {
+	// Get background luminance
+	r := img.Bounds()
+	if r.Empty() {
+		return 0
+	}
+	step := int(math.Max(1, math.Ceil(math.Max(float64(r.Dx()), float64(r.Dy()))/deskewMaxSamples)))
+	var sum float64
+	var count int
+	for y := r.Min.Y; y < r.Max.Y; y += step {
+		for x := r.Min.X; x < r.Max.X; x += step {
+			sum += luminance(img.At(x, y))
+			count++
+		}
+	}
+	background := sum / float64(count)
+
+	// Get foreground pixels
+	type point struct{ x, y float64 }
+	var ps []point
+	for y := r.Min.Y; y < r.Max.Y; y += step {
+		for x := r.Min.X; x < r.Max.X; x += step {
+			if math.Abs(luminance(img.At(x, y))-background) >= profileMinContrast {
+				ps = append(ps, point{x: float64(x - r.Min.X), y: float64(y - r.Min.Y)})
+			}
+		}
+	}
+	if len(ps) == 0 {
+		return 0
+	}
+
+	// Loop through angles
+	diag := math.Hypot(float64(r.Dx()), float64(r.Dy()))
+	bins := make([]int, int(2*diag/float64(step))+2)
+	bestAngle, bestScore := 0.0, -1.0
+	for a := -maxAngle; a <= maxAngle+1e-9; a += deskewAngleStep {
+		// Build profile
+		for idx := range bins {
+			bins[idx] = 0
+		}
+		sin, cos := math.Sincos(a * math.Pi / 180)
+		for _, p := range ps {
+			bins[int((-p.x*sin+p.y*cos+diag)/float64(step))]++
+		}
+
+		// Score
+		var score float64
+		for idx := 1; idx < len(bins); idx++ {
+			d := float64(bins[idx] - bins[idx-1])
+			score += d * d
+		}
+		if score > bestScore || (score == bestScore && math.Abs(a) < math.Abs(bestAngle)) {
+			bestAngle, bestScore = a, score
+		}
+	}
+	return bestAngle
+}
+
+// rotateImage rotates the image around its center so that lines with the provided angle become
+// horizontal, and returns a func mapping normalized boxes of the rotated image back to the original image
+func rotateImage(img image.Image, angle float64) (image.Image, func(b DetectionBox) DetectionBox) {
+	// Get rotation
+	r := img.Bounds()
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	cx, cy := float64(r.Min.X)+float64(r.Dx())/2, float64(r.Min.Y)+float64(r.Dy())/2
+
+	// Fill with the background
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	background, _ := profileColors(img)
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{background}, image.ZP, draw.Src)
+
+	// Rotate
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+	draw.ApproxBiLinear.Transform(dst, f64.Aff3{
+		cos, sin, cx - ox - cos*cx - sin*cy,
+		-sin, cos, cy - oy + sin*cx - cos*cy,
+	}, img, r, draw.Src, nil)
+
+	// Map boxes back
+	w, h := float64(r.Dx()), float64(r.Dy())
+	return dst, func(b DetectionBox) DetectionBox {
+		o := DetectionBox{X1: math.Inf(1), X2: math.Inf(-1), Y1: math.Inf(1), Y2: math.Inf(-1)}
+		for _, p := range [][2]float64{{b.X1, b.Y1}, {b.X2, b.Y1}, {b.X1, b.Y2}, {b.X2, b.Y2}} {
+			dx, dy := p[0]*w-w/2, p[1]*h-h/2
+			x, y := (cos*dx-sin*dy+w/2)/w, (sin*dx+cos*dy+h/2)/h
+			o.X1, o.X2 = math.Min(o.X1, x), math.Max(o.X2, x)
+			o.Y1, o.Y2 = math.Min(o.Y1, y), math.Max(o.Y2, y)
+		}
+		o.X1, o.X2 = math.Max(0, o.X1), math.Min(1, o.X2)
+		o.Y1, o.Y2 = math.Max(0, o.Y1), math.Min(1, o.Y2)
+		return o
+	}
+}
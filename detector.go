@@ -1,197 +1,728 @@
 package astiocr
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io/ioutil"
+	"math"
+	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	tf "github.com/tensorflow/tensorflow/tensorflow/go"
-	"github.com/tensorflow/tensorflow/tensorflow/go/op"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
 )
 
 // ConfigurationDetector represents a detector configuration
 type ConfigurationDetector struct {
-	// Path to the model
+	// AWS textract backend options
+	AWSTextract ConfigurationAWSTextract `toml:"aws_textract"`
+
+	// Name of the backend used to detect OCR. Default is "tensorflow".
+	Backend string `toml:"backend"`
+
+	// Detections whose box doesn't fit those constraints are dropped
+	BoxSize ConfigurationBoxSize `toml:"box_size"`
+
+	// Characters the model has been trained on, the nth character being the label of class n+1. It
+	// accepts the same placeholders as the trainer charset. Default is letters, digits and common
+	// punctuation.
+	Charset string `toml:"charset"`
+
+	// Color mode of tensors fed to the tensorflow backend: "rgb", "grayscale" which replicates the
+	// luminance in the 3 channels, or "grayscale_single" which creates 1-channel tensors for models trained
+	// on grayscale images. Default is "rgb".
+	ColorMode string `toml:"color_mode"`
+
+	// Ensemble options
+	Ensemble ConfigurationEnsemble `toml:"ensemble"`
+
+	// If true, the orientation of each box is estimated
+	EstimateAngles bool `toml:"estimate_angles"`
+
+	// Google cloud vision backend options
+	GoogleCloudVision ConfigurationGoogleCloudVision `toml:"google_cloud_vision"`
+
+	// Labels of the model classes, the nth label being the label of class n+1. Default is the charset
+	// characters.
+	Labels []string `toml:"labels"`
+
+	// Logger, which can only be set programmatically. Nothing is logged by default.
+	Logger Logger `toml:"-"`
+
+	// Number of seconds after which an idle backend is unloaded, freeing its resources (e.g. GPU
+	// memory). It is transparently reloaded on the next detection. 0 disables unloading.
+	IdleUnloadDelay int `toml:"idle_unload_delay"`
+
+	// How images bigger than the max input dimensions are downscaled: "fit" keeps the aspect ratio whereas
+	// "stretch" downscales each dimension independently. Default is "fit".
+	InputScaling string `toml:"input_scaling"`
+
+	// Max dimensions, in pixels, of images fed to the backend. Bigger images are downscaled right before
+	// inference which keeps memory and latency in check. Returned boxes are relative to the original image.
+	// 0 disables the constraint.
+	MaxInputHeight int `toml:"max_input_height"`
+	MaxInputWidth  int `toml:"max_input_width"`
+
+	// Detections below this probability are dropped. 0 keeps all detections.
+	MinProbability float64 `toml:"min_probability"`
+
+	// Path to the directory where models are downloaded. Default is "<temp dir>/astiocr_models".
+	ModelCacheDirectoryPath string `toml:"model_cache_directory_path"`
+
+	// Integrity of the model located at ModelPath, which is checked before loading it
+	ModelIntegrity ConfigurationIntegrity `toml:"model_integrity"`
+
+	// Path to the model. If it is an http(s) url, the model is downloaded into the model cache directory
+	// first.
 	ModelPath string `toml:"model_path"`
+
+	// Additional models, indexed by name, that can be selected per detection. They share the rest of the
+	// configuration with the default model.
+	Models map[string]string `toml:"models"`
+
+	// Integrity of the additional models, indexed by name, which is checked before loading them
+	ModelsIntegrity map[string]ConfigurationIntegrity `toml:"models_integrity"`
+
+	// If true, goroutines are labeled with the detection stage they're processing, which makes stages show
+	// up in pprof profiles
+	ProfilingLabels bool `toml:"profiling_labels"`
+
+	// Steps applied, in order, to images before detection. Returned boxes are relative to the original
+	// image.
+	Preprocessing []ConfigurationPreprocessingStep `toml:"preprocessing"`
+
+	// Quality gate options
+	Quality ConfigurationQuality `toml:"quality"`
+
+	// Regions of interest. If set, only those regions are analyzed, which is faster and avoids false
+	// positives when text is expected at known locations (e.g. the scoreboard of a video frame).
+	// Preprocessing steps are applied to each region independently.
+	Regions []ConfigurationRegion `toml:"regions"`
+
+	// Scales at which images are processed before merging results. This helps detecting
+	// characters whose size differs greatly from the training data. Default is [1].
+	Scales []float64 `toml:"scales"`
+
+	// Tensorflow backend options
+	TensorFlow ConfigurationTensorFlow `toml:"tensorflow"`
+
+	// Tesseract backend options
+	Tesseract ConfigurationTesseract `toml:"tesseract"`
+
+	// Tiles options
+	Tiles ConfigurationTiles `toml:"tiles"`
+}
+
+// ConfigurationAWSTextract represents an aws textract backend configuration
+// The aws textract backend is only available when building with the "aws" tag.
+type ConfigurationAWSTextract struct {
+	// Credentials. If empty, default credentials are used.
+	AccessKeyID     string `toml:"access_key_id"`
+	SecretAccessKey string `toml:"secret_access_key"`
+	SessionToken    string `toml:"session_token"`
+
+	// Level at which boxes are returned: "word" or "line". Default is "word".
+	Level string `toml:"level"`
+
+	// Region. If empty, the default region is used.
+	Region string `toml:"region"`
+}
+
+// ConfigurationBoxSize represents a box size configuration
+// Dimensions are in pixels unless Relative is true, in which case they are fractions of the image
+// dimensions. A zero value disables the constraint.
+type ConfigurationBoxSize struct {
+	MaxHeight float64 `toml:"max_height"`
+	MaxWidth  float64 `toml:"max_width"`
+	MinHeight float64 `toml:"min_height"`
+	MinWidth  float64 `toml:"min_width"`
+	Relative  bool    `toml:"relative"`
+}
+
+// ConfigurationEnsemble represents an ensemble configuration
+// When several models are provided, they are all run on each image and their results are merged.
+type ConfigurationEnsemble struct {
+	// Minimum number of models that must agree on a detection in "vote" mode. Default is the majority.
+	MinVotes int `toml:"min_votes"`
+
+	// Merge mode: "max" keeps the most probable of overlapping detections whereas "vote" keeps
+	// detections enough models agree on. Default is "max".
+	Mode string `toml:"mode"`
+
+	// Integrities of the models, the nth integrity being checked against the nth model before loading it
+	ModelIntegrities []ConfigurationIntegrity `toml:"model_integrities"`
+
+	// Paths to the models. If set, ModelPath is ignored.
+	ModelPaths []string `toml:"model_paths"`
+}
+
+// ConfigurationGoogleCloudVision represents a google cloud vision backend configuration
+// The google cloud vision backend is only available when building with the "googlecloud" tag.
+type ConfigurationGoogleCloudVision struct {
+	// Path to the credentials file. If empty, application default credentials are used.
+	CredentialsFile string `toml:"credentials_file"`
+
+	// Level at which boxes are returned: "symbol" or "word". Default is "symbol".
+	Level string `toml:"level"`
+}
+
+// ConfigurationTensorFlow represents a tensorflow backend configuration
+// Operation names default to the ones of graphs exported with the tensorflow object detection API.
+// Quantized graphs are supported: uint8 inputs are fed as is, float inputs are fed (value - input mean)
+// / input std, and outputs are dequantized with their quantization parameters.
+// Models must be frozen graphs: TFLite models (.tflite) are not supported and are rejected with
+// ErrConfigInvalid.
+type ConfigurationTensorFlow struct {
+	BoxesOperation         string                    `toml:"boxes_operation"`
+	BoxesQuantization      ConfigurationQuantization `toml:"boxes_quantization"`
+	ClassesOperation       string                    `toml:"classes_operation"`
+	ClassesQuantization    ConfigurationQuantization `toml:"classes_quantization"`
+	InputMean              float64                   `toml:"input_mean"`
+	InputOperation         string                    `toml:"input_operation"`
+	InputStd               float64                   `toml:"input_std"`
+	NumDetectionsOperation string                    `toml:"num_detections_operation"`
+	ScoresOperation        string                    `toml:"scores_operation"`
+	ScoresQuantization     ConfigurationQuantization `toml:"scores_quantization"`
+	// Session options, which can only be set programmatically
+	SessionOptions *tf.SessionOptions `toml:"-"`
+}
+
+// ConfigurationTesseract represents a tesseract backend configuration
+// The tesseract backend is only available when building with the "tesseract" tag.
+type ConfigurationTesseract struct {
+	// Languages used by tesseract. Default is ["eng"].
+	Languages []string `toml:"languages"`
+
+	// Level at which boxes are returned: "symbol" or "word". Default is "symbol".
+	Level string `toml:"level"`
+
+	// Characters tesseract is restricted to
+	Whitelist string `toml:"whitelist"`
 }
 
 // Detector represents an object capable of detecting OCR
 type Detector struct {
-	g *tf.Graph
-	s *tf.Session
+	boxSize            ConfigurationBoxSize
+	c                  ConfigurationDetector
+	estimateAngles     bool
+	inputScaling       string
+	l                  Logger
+	m                  *sync.Mutex
+	maxInputHeight     int
+	maxInputWidth      int
+	minProbability     float64
+	named              map[string]*backendRef
+	onStats            func(s DetectionStats)
+	postProcessors     []PostProcessor
+	preprocessingSteps []preprocessingStep
+	profilingLabels    bool
+	quality            ConfigurationQuality
+	r                  *backendRef
+	regions            []ConfigurationRegion
+	scales             []float64
+	tiles              ConfigurationTiles
 }
 
 // NewDetector creates a new detector
-func NewDetector(c ConfigurationDetector) (d *Detector, err error) {
+func NewDetector(c ConfigurationDetector) (*Detector, error) {
+	return NewDetectorWithContext(context.Background(), c)
+}
+
+// NewDetectorWithContext creates a new detector, models being downloaded until the context is done
+func NewDetectorWithContext(ctx context.Context, c ConfigurationDetector) (d *Detector, err error) {
 	// Init
-	d = &Detector{}
+	d = &Detector{
+		boxSize:         c.BoxSize,
+		c:               c,
+		estimateAngles:  c.EstimateAngles,
+		inputScaling:    c.InputScaling,
+		l:               newLogger(c.Logger),
+		m:               &sync.Mutex{},
+		maxInputHeight:  c.MaxInputHeight,
+		maxInputWidth:   c.MaxInputWidth,
+		minProbability:  c.MinProbability,
+		named:           make(map[string]*backendRef),
+		profilingLabels: c.ProfilingLabels,
+		quality:         c.Quality,
+		regions:         c.Regions,
+		scales:          c.Scales,
+		tiles:           c.Tiles,
+	}
 
-	// Read the model
-	var b []byte
-	if b, err = ioutil.ReadFile(c.ModelPath); err != nil {
-		err = errors.Wrapf(err, "astiocr: reading %s failed", c.ModelPath)
+	// Scales
+	if len(d.scales) == 0 {
+		d.scales = []float64{1}
+	}
+	for _, scale := range d.scales {
+		if scale <= 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid scale %v", scale))
+			return
+		}
+	}
+
+	// Input size
+	switch d.inputScaling {
+	case "":
+		d.inputScaling = inputScalingFit
+	case inputScalingFit, inputScalingStretch:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid input scaling %s", d.inputScaling))
+		return
+	}
+	if d.maxInputHeight < 0 || d.maxInputWidth < 0 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid max input dimensions %dx%d", d.maxInputWidth, d.maxInputHeight))
+		return
+	}
+
+	// Quality
+	switch d.quality.Mode {
+	case "":
+		d.quality.Mode = qualityModeReject
+	case qualityModeFlag, qualityModeReject:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid quality mode %s", d.quality.Mode))
 		return
 	}
 
-	// Create the graph
-	d.g = tf.NewGraph()
-	if err = d.g.Import(b, ""); err != nil {
-		err = errors.Wrapf(err, "astiocr: importing model %s failed", c.ModelPath)
+	// Preprocessing
+	if d.preprocessingSteps, err = newPreprocessingSteps(c.Preprocessing); err != nil {
+		err = errors.Wrap(err, "astiocr: creating preprocessing steps failed")
 		return
 	}
 
-	// Create the session
-	if d.s, err = tf.NewSession(d.g, nil); err != nil {
-		err = errors.Wrap(err, "astiocr: creating session failed")
+	// Regions
+	for idx, r := range d.regions {
+		if err = r.validate(); err != nil {
+			err = withKind(ErrConfigInvalid, err)
+			err = errors.Wrapf(err, "astiocr: region #%d is invalid", idx+1)
+			return
+		}
+	}
+
+	// Tiles
+	if d.tiles.Overlap < 0 || (d.tiles.Width > 0 && d.tiles.Overlap >= d.tiles.Width) || (d.tiles.Height > 0 && d.tiles.Overlap >= d.tiles.Height) {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid tiles overlap %d", d.tiles.Overlap))
 		return
 	}
+
+	// Prepare models
+	if err = prepareModels(ctx, &c, d.l); err != nil {
+		err = errors.Wrap(err, "astiocr: preparing models failed")
+		return
+	}
+	d.c = c
+
+	// Create backend
+	var b Backend
+	if b, err = newDetectorBackend(c); err != nil {
+		err = errors.Wrap(err, "astiocr: creating backend failed")
+		return
+	}
+	d.r = newBackendRef(b)
+
+	// Loop through named models
+	for n, p := range c.Models {
+		// Create backend
+		mc := c
+		mc.Ensemble = ConfigurationEnsemble{}
+		mc.ModelPath = p
+		if b, err = newDetectorBackend(mc); err != nil {
+			d.Close()
+			err = errors.Wrapf(err, "astiocr: creating backend for model %s failed", n)
+			return
+		}
+		d.named[n] = newBackendRef(b)
+	}
+	return
+}
+
+// prepareModels replaces model urls with the path of the downloaded models and verifies the integrity of
+// all models
+func prepareModels(ctx context.Context, c *ConfigurationDetector, l Logger) (err error) {
+	// Default model
+	if c.ModelPath, err = prepareModel(ctx, l, *c, c.ModelPath, c.ModelIntegrity); err != nil {
+		err = errors.Wrap(err, "astiocr: preparing default model failed")
+		return
+	}
+
+	// Ensemble models. The slice is copied so that the caller's configuration is left untouched.
+	if len(c.Ensemble.ModelIntegrities) > len(c.Ensemble.ModelPaths) {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: %d ensemble model integrities for %d models", len(c.Ensemble.ModelIntegrities), len(c.Ensemble.ModelPaths)))
+		return
+	}
+	c.Ensemble.ModelPaths = append([]string(nil), c.Ensemble.ModelPaths...)
+	for idx, p := range c.Ensemble.ModelPaths {
+		var i ConfigurationIntegrity
+		if idx < len(c.Ensemble.ModelIntegrities) {
+			i = c.Ensemble.ModelIntegrities[idx]
+		}
+		if c.Ensemble.ModelPaths[idx], err = prepareModel(ctx, l, *c, p, i); err != nil {
+			err = errors.Wrapf(err, "astiocr: preparing ensemble model #%d failed", idx+1)
+			return
+		}
+	}
+
+	// Named models
+	for n := range c.ModelsIntegrity {
+		if _, ok := c.Models[n]; !ok {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: integrity of unknown model %s", n))
+			return
+		}
+	}
+	ms := make(map[string]string, len(c.Models))
+	for n, p := range c.Models {
+		if ms[n], err = prepareModel(ctx, l, *c, p, c.ModelsIntegrity[n]); err != nil {
+			err = errors.Wrapf(err, "astiocr: preparing model %s failed", n)
+			return
+		}
+	}
+	c.Models = ms
 	return
 }
 
+// prepareModel downloads the model if p is an url and returns the path of the model once its integrity
+// has been verified
+func prepareModel(ctx context.Context, l Logger, c ConfigurationDetector, p string, i ConfigurationIntegrity) (string, error) {
+	// No model
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	// Download model, which verifies it
+	if isURL(p) {
+		// Get cache directory path
+		dirPath := c.ModelCacheDirectoryPath
+		if len(dirPath) == 0 {
+			dirPath = filepath.Join(os.TempDir(), "astiocr_models")
+		}
+
+		// Download
+		lp, err := downloadModel(ctx, l, p, dirPath, i)
+		if err != nil {
+			return "", errors.Wrapf(err, "astiocr: downloading %s failed", p)
+		}
+		return lp, nil
+	}
+
+	// Verify model
+	if err := verifyFile(p, i); err != nil {
+		return "", errors.Wrapf(err, "astiocr: verifying model %s failed", p)
+	}
+	return p, nil
+}
+
+func newDetectorBackend(c ConfigurationDetector) (Backend, error) {
+	if len(c.Ensemble.ModelPaths) > 0 {
+		return newEnsembleBackend(c)
+	}
+	if c.IdleUnloadDelay > 0 {
+		return newIdleBackend(c, time.Duration(c.IdleUnloadDelay)*time.Second)
+	}
+	return newBackend(c)
+}
+
 // Close implements the io.Closer interface
-func (d *Detector) Close() error {
-	return d.s.Close()
+func (d *Detector) Close() (err error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	err = d.r.b.Close()
+	for n, r := range d.named {
+		if errC := r.b.Close(); errC != nil && err == nil {
+			err = errors.Wrapf(errC, "astiocr: closing model %s failed", n)
+		}
+	}
+	return
+}
+
+// Models returns the names of the models that can be selected per detection
+func (d *Detector) Models() (ns []string) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	for n := range d.named {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return
 }
 
 // DetectionResult represents a detection result
 type DetectionResult struct {
-	Box         DetectionBox
-	Label       string
-	Probability float64
+	// Estimated rotation of the box content, in degrees between -45 and 45, counterclockwise. It is only
+	// set when angles estimation is enabled.
+	Angle float64 `json:"angle"`
+
+	Box         DetectionBox `json:"box"`
+	Label       string       `json:"label"`
+	Probability float64      `json:"probability"`
+
+	// Reasons why the image failed the quality gate in flag mode. It is set on every result of the
+	// image.
+	QualityFlags []string `json:"quality_flags,omitempty"`
 }
 
 // DetectionBox represents a detection box
 type DetectionBox struct {
-	X1, X2 float64
-	Y1, Y2 float64
+	X1 float64 `json:"x1"`
+	X2 float64 `json:"x2"`
+	Y1 float64 `json:"y1"`
+	Y2 float64 `json:"y2"`
 }
 
 // Detect detects OCR on an image
-func (d *Detector) Detect(ctx context.Context, src string) (rs []DetectionResult, err error) {
-	// Create tensor
-	var t *tf.Tensor
-	if t, err = d.tensorFromImage(src); err != nil {
-		err = errors.Wrapf(err, "astiocr: creating tensor for image %s failed", src)
-		return
-	}
+func (d *Detector) Detect(ctx context.Context, src string) ([]DetectionResult, error) {
+	return d.DetectWith(ctx, "", src)
+}
+
+// DetectWith detects OCR on an image with the named model. An empty name selects the default model.
+func (d *Detector) DetectWith(ctx context.Context, model, src string) (rs []DetectionResult, err error) {
+	// Stats
+	ctx, endStats := d.startStats(ctx, model)
+	defer endStats()
 
-	// Run inference
-	var probabilities, classes []float32
-	var boxes [][]float32
-	if probabilities, classes, boxes, err = d.runInference(t); err != nil {
-		err = errors.Wrap(err, "astiocr: running inference failed")
+	// Read image
+	var b []byte
+	if b, err = ioutil.ReadFile(src); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading %s failed", src)
 		return
 	}
 
-	// Loop through results
-	for idx := 0; idx < len(probabilities); idx++ {
-		rs = append(rs, DetectionResult{
-			Box: DetectionBox{
-				X1: float64(boxes[idx][1]),
-				X2: float64(boxes[idx][3]),
-				Y1: float64(boxes[idx][0]),
-				Y2: float64(boxes[idx][2]),
-			},
-			Label:       string(characters[int(classes[idx])-1]),
-			Probability: float64(probabilities[idx]),
-		})
+	// Detect
+	if rs, err = d.DetectBytesWith(ctx, model, b); err != nil {
+		err = errors.Wrapf(err, "astiocr: detecting in %s failed", src)
+		return
 	}
 	return
 }
 
-func (d *Detector) tensorFromImage(src string) (t *tf.Tensor, err error) {
-	// Read image
-	var b []byte
-	if b, err = ioutil.ReadFile(src); err != nil {
-		err = errors.Wrapf(err, "astiocr: reading %s failed", src)
+// DetectBytes detects OCR on an encoded image
+func (d *Detector) DetectBytes(ctx context.Context, b []byte) ([]DetectionResult, error) {
+	return d.DetectBytesWith(ctx, "", b)
+}
+
+// DetectBytesWith detects OCR on an encoded image with the named model. An empty name selects the default
+// model.
+func (d *Detector) DetectBytesWith(ctx context.Context, model string, b []byte) (rs []DetectionResult, err error) {
+	// Stats
+	ctx, endStats := d.startStats(ctx, model)
+	defer endStats()
+
+	// Decode image
+	var img image.Image
+	_, end := startSpan(ctx, "astiocr.Decode")
+	endStage := d.startStage(ctx, stageDecode)
+	img, _, err = image.Decode(bytes.NewReader(b))
+	endStage()
+	end(err)
+	if err != nil {
+		err = errors.Wrap(decodeError(err), "astiocr: decoding image failed")
 		return
 	}
 
-	// Create basic tensor
-	if t, err = tf.NewTensor(string(b)); err != nil {
-		err = errors.Wrap(err, "astiocr: creating basic tensor failed")
+	// Detect
+	if rs, err = d.DetectImageWith(ctx, model, img); err != nil {
+		err = errors.Wrap(err, "astiocr: detecting in image failed")
 		return
 	}
+	return
+}
 
-	// Create scope
-	s := op.NewScope()
-	input := op.Placeholder(s, tf.String)
+// DetectImage detects OCR on a decoded image
+func (d *Detector) DetectImage(ctx context.Context, img image.Image) ([]DetectionResult, error) {
+	return d.DetectImageWith(ctx, "", img)
+}
 
-	// Create output
-	var o tf.Output
-	switch filepath.Ext(src) {
-	case ".jpg", ".jpeg":
-		o = op.DecodeJpeg(s, input, op.DecodeJpegChannels(3))
-	case ".png":
-		o = op.DecodePng(s, input, op.DecodePngChannels(3))
-	default:
-		o = op.DecodeBmp(s, input, op.DecodeBmpChannels(3))
-	}
-	output := op.ExpandDims(
-		s,
-		o,
-		op.Const(s.SubScope("make_batch"), int32(0)),
-	)
+// DetectImageWith detects OCR on a decoded image with the named model. An empty name selects the default
+// model.
+func (d *Detector) DetectImageWith(ctx context.Context, model string, img image.Image) (rs []DetectionResult, err error) {
+	// Metrics
+	metricDetectionsInFlight.Inc()
+	defer func() {
+		metricDetectionsInFlight.Dec()
+		if err != nil {
+			metricErrors.Inc()
+			return
+		}
+		for _, r := range rs {
+			metricDetections.WithLabelValues(r.Label).Inc()
+		}
+	}()
 
-	// Create graph
-	var graph *tf.Graph
-	if graph, err = s.Finalize(); err != nil {
-		err = errors.Wrap(err, "astiocr: finalizing failed")
+	// Trace
+	ctx, end := startSpan(ctx, "astiocr.Detect")
+	defer func() { end(err) }()
+
+	// Stats
+	ctx, endStats := d.startStats(ctx, model)
+	defer endStats()
+
+	// Check quality
+	var flags []string
+	if flags, err = d.quality.check(img, d.l); err != nil {
 		return
 	}
 
-	// Create session
-	var sess *tf.Session
-	if sess, err = tf.NewSession(graph, nil); err != nil {
-		err = errors.Wrap(err, "astiocr: creating session failed")
+	// Acquire backend so that the same model is used for the whole image even if it is reloaded
+	var b *backendRef
+	if b, err = d.acquireBackend(model); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
 		return
 	}
-	defer sess.Close()
+	defer b.release()
 
-	// Normalize
-	var ts []*tf.Tensor
-	if ts, err = sess.Run(
-		map[tf.Output]*tf.Tensor{input: t},
-		[]tf.Output{output},
-		nil,
-	); err != nil {
-		err = errors.Wrap(err, "astiocr: normalizing failed")
+	// Detect
+	if rs, err = d.detectRegions(ctx, b.b, img); err != nil {
 		return
 	}
-	t = ts[0]
+
+	// Filter probabilities and box sizes
+	_, endPostProcess := startSpan(ctx, "astiocr.PostProcess")
+	endStage := d.startStage(ctx, stagePostProcess)
+	if d.minProbability > 0 {
+		rs = MinProbabilityPostProcessor(d.minProbability).PostProcess(rs)
+	}
+	rs = d.filterBoxSize(rs, img.Bounds())
+
+	// Estimate angles
+	if d.estimateAngles {
+		estimateAngles(img, rs)
+	}
+
+	// Custom post-processors
+	rs = d.postProcess(rs)
+	endStage()
+	endPostProcess(nil)
+
+	// Flag quality
+	if len(flags) > 0 {
+		for idx := range rs {
+			rs[idx].QualityFlags = flags
+		}
+	}
 	return
 }
 
-func (d *Detector) runInference(t *tf.Tensor) (probabilities, classes []float32, boxes [][]float32, err error) {
-	// Input
-	i := d.g.Operation("image_tensor")
-
-	// Outputs
-	o1 := d.g.Operation("detection_boxes")
-	o2 := d.g.Operation("detection_scores")
-	o3 := d.g.Operation("detection_classes")
-	o4 := d.g.Operation("num_detections")
-
-	// Run
-	var os []*tf.Tensor
-	if os, err = d.s.Run(
-		map[tf.Output]*tf.Tensor{i.Output(0): t},
-		[]tf.Output{
-			o1.Output(0),
-			o2.Output(0),
-			o3.Output(0),
-			o4.Output(0),
-		},
-		nil,
-	); err != nil {
-		err = errors.Wrap(err, "astiocr: running session failed")
-		return
-	}
-
-	// Get results
-	probabilities = os[1].Value().([][]float32)[0]
-	classes = os[2].Value().([][]float32)[0]
-	boxes = os[0].Value().([][][]float32)[0]
+// detectImage preprocesses the image, detects at every scale and returns boxes relative to the image
+func (d *Detector) detectImage(ctx context.Context, bk Backend, img image.Image) (rs []DetectionResult, err error) {
+	// Preprocess
+	pimg, unmapBox := img, identityBox
+	if len(d.preprocessingSteps) > 0 {
+		_, endPreprocess := startSpan(ctx, "astiocr.Preprocess")
+		endStage := d.startStage(ctx, stagePreprocess)
+		pimg, unmapBox = d.preprocess(img)
+		endStage()
+		endPreprocess(nil)
+	}
+
+	// Loop through scales
+	for _, scale := range d.scales {
+		// Scale image
+		simg := pimg
+		if scale != 1 {
+			simg = scaleImage(pimg, scale)
+		}
+
+		// Detect
+		var srs []DetectionResult
+		if srs, err = d.detectTiled(ctx, bk, simg); err != nil {
+			err = errors.Wrapf(err, "astiocr: detecting at scale %v failed", scale)
+			return
+		}
+
+		// Boxes are normalized which means results at different scales can be merged as is
+		rs = append(rs, srs...)
+	}
+
+	// Merge results
+	if len(d.scales) > 1 {
+		rs = mergeResults(rs, mergeIoUThreshold)
+	}
+
+	// Make boxes relative to the original image
+	for idx := range rs {
+		rs[idx].Box = unmapBox(rs[idx].Box)
+	}
 	return
 }
+
+func (d *Detector) filterBoxSize(rs []DetectionResult, b image.Rectangle) (o []DetectionResult) {
+	// Boxes are normalized, therefore relative constraints can be used as is while pixel constraints need
+	// to be converted
+	rw, rh := 1.0, 1.0
+	if !d.boxSize.Relative {
+		rw, rh = float64(b.Dx()), float64(b.Dy())
+	}
+
+	// Loop through results
+	o = rs[:0]
+	for _, r := range rs {
+		w, h := (r.Box.X2-r.Box.X1)*rw, (r.Box.Y2-r.Box.Y1)*rh
+		if (d.boxSize.MinWidth > 0 && w < d.boxSize.MinWidth) ||
+			(d.boxSize.MaxWidth > 0 && w > d.boxSize.MaxWidth) ||
+			(d.boxSize.MinHeight > 0 && h < d.boxSize.MinHeight) ||
+			(d.boxSize.MaxHeight > 0 && h > d.boxSize.MaxHeight) {
+			continue
+		}
+		o = append(o, r)
+	}
+	return
+}
+
+func (d *Detector) detect(ctx context.Context, b Backend, img image.Image) (rs []DetectionResult, err error) {
+	defer func(start time.Time) { metricInferenceDuration.Observe(time.Since(start).Seconds()) }(time.Now())
+	defer d.startStage(ctx, stageInference)()
+	if rs, err = b.Detect(ctx, d.limitInputSize(img)); err != nil {
+		err = inferenceError(err)
+		return
+	}
+	return
+}
+
+// Input scalings
+const (
+	inputScalingFit     = "fit"
+	inputScalingStretch = "stretch"
+)
+
+// limitInputSize downscales images bigger than the max input dimensions. Boxes are normalized which means
+// they don't need to be mapped back.
+func (d *Detector) limitInputSize(img image.Image) image.Image {
+	// Get scales
+	r := img.Bounds()
+	sx, sy := 1.0, 1.0
+	if d.maxInputWidth > 0 && r.Dx() > d.maxInputWidth {
+		sx = float64(d.maxInputWidth) / float64(r.Dx())
+	}
+	if d.maxInputHeight > 0 && r.Dy() > d.maxInputHeight {
+		sy = float64(d.maxInputHeight) / float64(r.Dy())
+	}
+	if sx == 1 && sy == 1 {
+		return img
+	}
+	if d.inputScaling == inputScalingFit {
+		sx = math.Min(sx, sy)
+		sy = sx
+	}
+
+	// Downscale
+	dst := image.NewRGBA(image.Rect(0, 0, int(math.Max(1, float64(r.Dx())*sx)), int(math.Max(1, float64(r.Dy())*sy))))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, r, draw.Src, nil)
+	return dst
+}
+
+// scaleImage resizes the image by the provided factor
+func scaleImage(src image.Image, scale float64) image.Image {
+	r := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, int(math.Max(1, float64(r.Dx())*scale)), int(math.Max(1, float64(r.Dy())*scale))))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, r, draw.Src, nil)
+	return dst
+}
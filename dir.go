@@ -0,0 +1,90 @@
+package astiocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Extensions of the files processed when detecting in a directory
+var imageExtensions = map[string]bool{
+	".bmp":  true,
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+}
+
+// DirResult represents the detection results of a file of a directory
+type DirResult struct {
+	Err     error
+	Path    string
+	Results []DetectionResult
+}
+
+// DetectDir walks the directory and detects OCR in its images using at most workers concurrent
+// detections (default is the number of CPUs). fn is invoked with the results of each image as soon as
+// they're available, one call at a time. Errors of individual images are reported to fn and don't stop the
+// walk, whereas DetectDir returns as soon as the context is done.
+func (d *Detector) DetectDir(ctx context.Context, dirPath string, workers int, fn func(r DirResult)) (err error) {
+	// Default workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Start workers
+	ps := make(chan string)
+	m := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < workers; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ps {
+				// Detect
+				r := DirResult{Path: p}
+				r.Results, r.Err = d.Detect(ctx, p)
+
+				// Callback
+				m.Lock()
+				fn(r)
+				m.Unlock()
+			}
+		}()
+	}
+
+	// Walk
+	err = filepath.Walk(dirPath, func(p string, fi os.FileInfo, e error) error {
+		// Process error
+		if e != nil {
+			return e
+		}
+
+		// Only process images
+		if fi.IsDir() || !imageExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+
+		// Dispatch
+		select {
+		case ps <- p:
+			return nil
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "astiocr: context error")
+		}
+	})
+
+	// Wait for workers
+	close(ps)
+	wg.Wait()
+	if err != nil {
+		err = errors.Wrapf(err, "astiocr: walking %s failed", dirPath)
+		return
+	}
+	return
+}
@@ -0,0 +1,803 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Default minimum probability of detections used to build documents
+const defaultDocumentMinProbability = 0.3
+
+// Line orientations
+const (
+	LineOrientationHorizontal = "horizontal"
+	LineOrientationRotated    = "rotated"
+	LineOrientationVertical   = "vertical"
+)
+
+// Minimum baseline angle, in degrees, for a horizontal line to be considered as rotated
+const rotatedLineMinAngle = 5
+
+// Document represents a structured OCR result
+type Document struct {
+	Pages []DocumentPage
+}
+
+// DocumentPage represents a document page
+type DocumentPage struct {
+	Blocks []DocumentBlock
+	Height int
+	Width  int
+}
+
+// DocumentBlock represents a group of consecutive lines
+type DocumentBlock struct {
+	Box        DetectionBox
+	Confidence float64
+	Lines      []DocumentLine
+}
+
+// DocumentLine represents a line of words
+type DocumentLine struct {
+	// Angle of the baseline in degrees. Positive angles are counterclockwise. For vertical lines, it is
+	// the angle between the line and the vertical axis.
+	Angle float64
+
+	Box        DetectionBox
+	Confidence float64
+
+	// Either "horizontal", "rotated" or "vertical"
+	Orientation string
+
+	// Whether the line text doesn't match any of the patterns. It is only set when patterns are flagged.
+	PatternMismatch bool
+
+	Words []DocumentWord
+}
+
+// DocumentWord represents a group of characters not separated by a space
+type DocumentWord struct {
+	Box        DetectionBox
+	Chars      []DocumentChar
+	Confidence float64
+
+	// Corrected text and confidence in the correction. They are only set if the document has been
+	// corrected.
+	Corrected            string
+	CorrectionConfidence float64
+}
+
+// DocumentChar represents a character
+type DocumentChar struct {
+	Box DetectionBox
+
+	// Confidence, adjusted if confidences have been smoothed
+	Confidence float64
+
+	Label string
+
+	// Confidence as returned by the detector
+	RawConfidence float64
+}
+
+// Text returns the document text, pages being separated by a form feed
+func (d Document) Text() string {
+	var ss []string
+	for _, p := range d.Pages {
+		ss = append(ss, p.Text())
+	}
+	return strings.Join(ss, "\f")
+}
+
+// Text returns the page text, blocks being separated by an empty line
+func (p DocumentPage) Text() string {
+	var ss []string
+	for _, b := range p.Blocks {
+		ss = append(ss, b.Text())
+	}
+	return strings.Join(ss, "\n\n")
+}
+
+// Text returns the block text, lines being separated by a new line
+func (b DocumentBlock) Text() string {
+	var ss []string
+	for _, l := range b.Lines {
+		ss = append(ss, l.Text())
+	}
+	return strings.Join(ss, "\n")
+}
+
+// Text returns the line text, words being separated by a space
+func (l DocumentLine) Text() string {
+	var ss []string
+	for _, w := range l.Words {
+		ss = append(ss, w.Text())
+	}
+	return strings.Join(ss, " ")
+}
+
+// CorrectedText returns the corrected document text, pages being separated by a form feed
+func (d Document) CorrectedText() string {
+	var ss []string
+	for _, p := range d.Pages {
+		ss = append(ss, p.CorrectedText())
+	}
+	return strings.Join(ss, "\f")
+}
+
+// CorrectedText returns the corrected page text, blocks being separated by an empty line
+func (p DocumentPage) CorrectedText() string {
+	var ss []string
+	for _, b := range p.Blocks {
+		ss = append(ss, b.CorrectedText())
+	}
+	return strings.Join(ss, "\n\n")
+}
+
+// CorrectedText returns the corrected block text, lines being separated by a new line
+func (b DocumentBlock) CorrectedText() string {
+	var ss []string
+	for _, l := range b.Lines {
+		ss = append(ss, l.CorrectedText())
+	}
+	return strings.Join(ss, "\n")
+}
+
+// CorrectedText returns the corrected line text, words being separated by a space
+func (l DocumentLine) CorrectedText() string {
+	var ss []string
+	for _, w := range l.Words {
+		ss = append(ss, w.CorrectedText())
+	}
+	return strings.Join(ss, " ")
+}
+
+// CorrectedText returns the corrected word text, or the raw text if the word hasn't been corrected
+func (w DocumentWord) CorrectedText() string {
+	if len(w.Corrected) > 0 {
+		return w.Corrected
+	}
+	return w.Text()
+}
+
+// Text returns the word text
+func (w DocumentWord) Text() string {
+	var s string
+	for _, c := range w.Chars {
+		s += c.Label
+	}
+	return s
+}
+
+// OCROption represents an OCR option
+type OCROption func(o *ocrOptions)
+
+type ocrOptions struct {
+	c                     ConfigurationDetector
+	correction            *Dictionary
+	correctionMaxDistance int
+	d                     *Detector
+	dictionary            *Dictionary
+	minProbability        float64
+	patterns              []*regexp.Regexp
+	patternsDrop          bool
+	smoothingStrength     float64
+	verticalLines         bool
+}
+
+// WithCorrection makes OCR correct words not found in the dictionary with the closest dictionary word
+// within maxDistance edits. Default max distance is 2.
+func WithCorrection(d *Dictionary, maxDistance int) OCROption {
+	return func(o *ocrOptions) {
+		o.correction = d
+		if maxDistance > 0 {
+			o.correctionMaxDistance = maxDistance
+		}
+	}
+}
+
+// WithDetector makes OCR use the provided detector instead of creating its own
+func WithDetector(d *Detector) OCROption {
+	return func(o *ocrOptions) { o.d = d }
+}
+
+// WithDictionary makes OCR smooth character confidences based on their agreement with the dictionary.
+// Strength, between 0 and 1, indicates how much confidences are adjusted. Default is 0.5.
+func WithDictionary(d *Dictionary, strength float64) OCROption {
+	return func(o *ocrOptions) {
+		o.dictionary = d
+		o.smoothingStrength = strength
+	}
+}
+
+// WithDetectorConfiguration makes OCR create its detector with the provided configuration
+func WithDetectorConfiguration(c ConfigurationDetector) OCROption {
+	return func(o *ocrOptions) { o.c = c }
+}
+
+// WithPatterns makes OCR check the text of each line against the patterns (e.g. `^\d{2}:\d{2}$` for
+// clocks). Lines matching none of them are dropped if drop is true, or flagged otherwise.
+func WithPatterns(drop bool, patterns ...*regexp.Regexp) OCROption {
+	return func(o *ocrOptions) {
+		o.patterns = patterns
+		o.patternsDrop = drop
+	}
+}
+
+// WithOCRMinProbability makes OCR ignore detections below the provided probability. Default is 0.3.
+func WithOCRMinProbability(p float64) OCROption {
+	return func(o *ocrOptions) { o.minProbability = p }
+}
+
+// WithVerticalLines makes OCR assemble columns of characters into vertical lines, which are otherwise
+// assembled into horizontal lines
+func WithVerticalLines() OCROption {
+	return func(o *ocrOptions) { o.verticalLines = true }
+}
+
+// OCR detects characters in the image located at src and assembles them into a document
+func OCR(ctx context.Context, src string, opts ...OCROption) (d Document, err error) {
+	// Options
+	o := &ocrOptions{
+		correctionMaxDistance: defaultCorrectionMaxDistance,
+		minProbability:        defaultDocumentMinProbability,
+		smoothingStrength:     defaultSmoothingStrength,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Create detector
+	if o.d == nil {
+		if o.d, err = NewDetectorWithContext(ctx, o.c); err != nil {
+			err = errors.Wrap(err, "astiocr: creating detector failed")
+			return
+		}
+		defer o.d.Close()
+	}
+
+	// Decode image
+	var img image.Image
+	if img, err = decodeImageFile(src); err != nil {
+		err = errors.Wrapf(err, "astiocr: decoding %s failed", src)
+		return
+	}
+
+	// Detect
+	var rs []DetectionResult
+	if rs, err = o.d.DetectImage(ctx, img); err != nil {
+		err = errors.Wrapf(err, "astiocr: detecting in %s failed", src)
+		return
+	}
+
+	// Filter results
+	var frs []DetectionResult
+	for _, r := range rs {
+		if r.Probability >= o.minProbability {
+			frs = append(frs, r)
+		}
+	}
+
+	// Assemble
+	p := AssemblePage(frs, img.Bounds().Dx(), img.Bounds().Dy(), o.verticalLines)
+
+	// Smooth confidences
+	if o.dictionary != nil {
+		p.SmoothConfidences(o.dictionary, o.smoothingStrength)
+	}
+
+	// Correct
+	if o.correction != nil {
+		p.Correct(o.correction, o.correctionMaxDistance)
+	}
+
+	// Match patterns
+	if len(o.patterns) > 0 {
+		p.MatchPatterns(o.patterns, o.patternsDrop)
+	}
+	d = Document{Pages: []DocumentPage{p}}
+	return
+}
+
+// AssemblePage groups detection results of an image of the provided dimensions into blocks, lines and
+// words in reading order. Columns of characters are assembled into vertical lines only if verticalLines
+// is true.
+func AssemblePage(rs []DetectionResult, width, height int, verticalLines bool) (p DocumentPage) {
+	// Init
+	p = DocumentPage{
+		Height: height,
+		Width:  width,
+	}
+
+	// Convert results to characters
+	var cs []DocumentChar
+	for _, r := range rs {
+		cs = append(cs, DocumentChar{
+			Box:           r.Box,
+			Confidence:    r.Probability,
+			Label:         r.Label,
+			RawConfidence: r.Probability,
+		})
+	}
+
+	// Split characters between horizontal and vertical lines
+	hcs, vlcs := cs, [][]DocumentChar(nil)
+	if verticalLines {
+		hcs, vlcs = splitVerticalLines(cs, width, height)
+	}
+
+	// Assemble blocks
+	var hls, vls []DocumentLine
+	for _, lcs := range groupLines(hcs, width, height) {
+		hls = append(hls, assembleLine(lcs, width, height, false))
+	}
+	for _, lcs := range vlcs {
+		vls = append(vls, assembleLine(lcs, width, height, true))
+	}
+	p.Blocks = assembleBlocks(hls, width, height, false)
+
+	// Vertical blocks are inserted in reading order based on their top
+	if vbs := assembleBlocks(vls, width, height, true); len(vbs) > 0 {
+		p.Blocks = append(p.Blocks, vbs...)
+		sort.SliceStable(p.Blocks, func(i, j int) bool { return p.Blocks[i].Box.Y1 < p.Blocks[j].Box.Y1 })
+	}
+
+	// Compute block confidences
+	for idx := range p.Blocks {
+		p.Blocks[idx].Confidence = averageLineConfidence(p.Blocks[idx].Lines)
+	}
+	return
+}
+
+// assembleBlocks groups consecutive lines close to each other into blocks. Horizontal lines are expected
+// to be sorted from top to bottom and vertical lines from right to left.
+func assembleBlocks(ls []DocumentLine, width, height int, vertical bool) (bs []DocumentBlock) {
+	// Loop through lines
+	var b *DocumentBlock
+	for _, l := range ls {
+		// Lines far apart belong to different blocks
+		if b != nil {
+			last := b.Lines[len(b.Lines)-1]
+			var gap, size float64
+			var aligned bool
+			if vertical {
+				gap = (last.Box.X1 - l.Box.X2) * float64(width)
+				size = (last.Box.X2 - last.Box.X1) * float64(width)
+				aligned = overlapsVertically(l.Box, b.Box)
+			} else {
+				gap = (l.Box.Y1 - last.Box.Y2) * float64(height)
+				size = (last.Box.Y2 - last.Box.Y1) * float64(height)
+				aligned = overlapsHorizontally(l.Box, b.Box)
+			}
+			if gap > size || !aligned {
+				bs = append(bs, *b)
+				b = nil
+			}
+		}
+
+		// Add line to block
+		if b == nil {
+			b = &DocumentBlock{Box: l.Box}
+		}
+		b.Lines = append(b.Lines, l)
+		b.Box = b.Box.Union(l.Box)
+	}
+	if b != nil {
+		bs = append(bs, *b)
+	}
+	return
+}
+
+// splitVerticalLines returns characters belonging to vertical lines, grouped into lines sorted from right
+// to left whose characters are sorted from top to bottom, and the remaining characters. A character
+// belongs to a vertical line when its closest vertical neighbor is closer than its closest horizontal
+// neighbor, and when at least one other character of its column does too.
+func splitVerticalLines(cs []DocumentChar, width, height int) (hcs []DocumentChar, vls [][]DocumentChar) {
+	// Neighbors overlap along one of the axes, therefore only characters sharing a row or a column
+	// need to be compared
+	rows := newCharBuckets(cs, func(b DetectionBox) (float64, float64) { return b.Y1, b.Y2 })
+	columns := newCharBuckets(cs, func(b DetectionBox) (float64, float64) { return b.X1, b.X2 })
+
+	// Loop through characters
+	var vcs []DocumentChar
+	for i, c := range cs {
+		// Get closest neighbors, in pixels
+		hGap, vGap := math.Inf(1), math.Inf(1)
+		a := c.Box
+
+		// Horizontal neighbors
+		rows.neighbors(a, func(j int) {
+			if b := cs[j].Box; i != j && math.Min(a.Y2, b.Y2)-math.Max(a.Y1, b.Y1) > 0.5*math.Min(a.Y2-a.Y1, b.Y2-b.Y1) {
+				hGap = math.Min(hGap, math.Max(0, math.Max(b.X1-a.X2, a.X1-b.X2))*float64(width))
+			}
+		})
+
+		// Vertical neighbors
+		columns.neighbors(a, func(j int) {
+			if b := cs[j].Box; i != j && math.Min(a.X2, b.X2)-math.Max(a.X1, b.X1) > 0.5*math.Min(a.X2-a.X1, b.X2-b.X1) {
+				vGap = math.Min(vGap, math.Max(0, math.Max(b.Y1-a.Y2, a.Y1-b.Y2))*float64(height))
+			}
+		})
+
+		// Vertical neighbor is closer
+		if vGap < hGap {
+			vcs = append(vcs, c)
+		} else {
+			hcs = append(hcs, c)
+		}
+	}
+
+	// Group columns by transposing boxes
+	var bs []DetectionBox
+	for _, c := range vcs {
+		bs = append(bs, DetectionBox{X1: c.Box.Y1, X2: c.Box.Y2, Y1: c.Box.X1, Y2: c.Box.X2})
+	}
+	ls := groupLineIndexes(bs, height, width)
+
+	// Loop through columns from right to left
+	for idx := len(ls) - 1; idx >= 0; idx-- {
+		// Isolated characters are part of horizontal lines
+		if len(ls[idx]) < 2 {
+			hcs = append(hcs, vcs[ls[idx][0]])
+			continue
+		}
+
+		// Add line
+		var l []DocumentChar
+		for _, i := range ls[idx] {
+			l = append(l, vcs[i])
+		}
+		vls = append(vls, l)
+	}
+	return
+}
+
+// charBuckets groups characters by the buckets their extent along an axis overlaps, buckets being as big
+// as the average extent
+type charBuckets struct {
+	buckets map[int][]int
+	extent  func(b DetectionBox) (start, end float64)
+	size    float64
+}
+
+func newCharBuckets(cs []DocumentChar, extent func(b DetectionBox) (start, end float64)) (b charBuckets) {
+	// Get bucket size
+	b = charBuckets{
+		buckets: make(map[int][]int),
+		extent:  extent,
+	}
+	for _, c := range cs {
+		s, e := extent(c.Box)
+		b.size += e - s
+	}
+	if b.size <= 0 {
+		return
+	}
+	b.size /= float64(len(cs))
+
+	// Add characters
+	for idx, c := range cs {
+		s, e := b.span(c.Box)
+		for k := s; k <= e; k++ {
+			b.buckets[k] = append(b.buckets[k], idx)
+		}
+	}
+	return
+}
+
+func (b charBuckets) span(bx DetectionBox) (start, end int) {
+	s, e := b.extent(bx)
+	return int(math.Floor(s / b.size)), int(math.Floor(e / b.size))
+}
+
+// neighbors calls fn with the index of every character sharing a bucket with the box. The same index may
+// be provided several times.
+func (b charBuckets) neighbors(bx DetectionBox, fn func(idx int)) {
+	if b.size <= 0 {
+		return
+	}
+	s, e := b.span(bx)
+	for k := s; k <= e; k++ {
+		for _, idx := range b.buckets[k] {
+			fn(idx)
+		}
+	}
+}
+
+// groupLines groups characters of an image of the provided dimensions into lines, see groupLineIndexes, and
+// returns lines sorted from top to bottom
+func groupLines(cs []DocumentChar, width, height int) (ls [][]DocumentChar) {
+	// Get boxes
+	var bs []DetectionBox
+	for _, c := range cs {
+		bs = append(bs, c.Box)
+	}
+
+	// Loop through lines
+	for _, idxs := range groupLineIndexes(bs, width, height) {
+		var l []DocumentChar
+		for _, idx := range idxs {
+			l = append(l, cs[idx])
+		}
+		ls = append(ls, l)
+	}
+	return
+}
+
+// groupLineIndexes groups boxes of an image of the provided dimensions whose extents, perpendicular to the
+// dominant baseline direction, overlap by more than half of the smallest height, and returns the indexes
+// of the boxes of each line, lines being sorted from top to bottom. Taking the baseline direction into
+// account prevents lines tilted by a few degrees from absorbing the boxes of the lines above and below.
+// The direction is first estimated from neighbor boxes, then refined by fitting the lines it produces.
+func groupLineIndexes(bs []DetectionBox, width, height int) (ls [][]int) {
+	ls = groupStraightenedLineIndexes(bs, width, height, baselineAngle(bs, width, height))
+	if a, ok := linesAngle(bs, width, height, ls); ok {
+		ls = groupStraightenedLineIndexes(bs, width, height, a)
+	}
+	return
+}
+
+// groupStraightenedLineIndexes groups boxes into lines once straightened along the baseline angle
+func groupStraightenedLineIndexes(bs []DetectionBox, width, height int, a float64) (ls [][]int) {
+	// Straighten boxes, in pixels, by projecting their center on the axis perpendicular to the baselines
+	sin, cos := math.Sin(a), math.Cos(a)
+	sbs := make([]DetectionBox, len(bs))
+	for idx, b := range bs {
+		x, y := b.Center()
+		v := y*float64(height)*cos - x*float64(width)*sin
+		h := (b.Y2 - b.Y1) * float64(height) / 2
+		sbs[idx] = DetectionBox{Y1: v - h, Y2: v + h}
+	}
+
+	// Sort by vertical center
+	is := make([]int, len(sbs))
+	for idx := range is {
+		is[idx] = idx
+	}
+	sort.SliceStable(is, func(i, j int) bool { return sbs[is[i]].Y1+sbs[is[i]].Y2 < sbs[is[j]].Y1+sbs[is[j]].Y2 })
+
+	// Loop through boxes
+	var lbs []DetectionBox
+	for _, i := range is {
+		// Look for a matching line
+		b := sbs[i]
+		idx := -1
+		for li, lb := range lbs {
+			o := math.Min(lb.Y2, b.Y2) - math.Max(lb.Y1, b.Y1)
+			if o > 0.5*math.Min(lb.Y2-lb.Y1, b.Y2-b.Y1) {
+				idx = li
+				break
+			}
+		}
+
+		// Add box
+		if idx < 0 {
+			ls = append(ls, []int{i})
+			lbs = append(lbs, b)
+		} else {
+			ls[idx] = append(ls[idx], i)
+			lbs[idx] = lbs[idx].Union(b)
+		}
+	}
+	return
+}
+
+// linesAngle returns the median angle, in radians, of the lines fitted through the centers of the boxes of
+// each line spanning at least 3 times their height. Centers are weighted by the squared height of their box
+// since the centers of small boxes, such as punctuation, are further from the center line.
+func linesAngle(bs []DetectionBox, width, height int, ls [][]int) (a float64, ok bool) {
+	// Loop through lines
+	var as []float64
+	for _, l := range ls {
+		// Compute weighted sums, in pixels
+		var sh, sw, sx, sxx, sxy, sy float64
+		minX, maxX := math.Inf(1), math.Inf(-1)
+		for _, idx := range l {
+			x, y := bs[idx].Center()
+			x, y = x*float64(width), y*float64(height)
+			h := (bs[idx].Y2 - bs[idx].Y1) * float64(height)
+			w := h * h
+			sh += h
+			sw += w
+			sx += w * x
+			sxx += w * x * x
+			sxy += w * x * y
+			sy += w * y
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		}
+
+		// Line is too short
+		if sw == 0 || maxX-minX < 3*sh/float64(len(l)) {
+			continue
+		}
+
+		// Fit line
+		if vx := sxx - sx*sx/sw; vx > 0 {
+			as = append(as, math.Atan((sxy-sx*sy/sw)/vx))
+		}
+	}
+
+	// No line
+	if len(as) == 0 {
+		return
+	}
+	return median(as), true
+}
+
+// baselineAngle returns the dominant angle, in radians, of the baselines of the boxes of an image of the
+// provided dimensions. It is the interquartile mean of the angles between the centers of boxes and of their
+// closest neighbor on the right, neighbors being less than 45 degrees away and closer than twice the box height.
+func baselineAngle(bs []DetectionBox, width, height int) float64 {
+	// Get centers and heights, in pixels, sorted horizontally
+	type center struct{ h, x, y float64 }
+	var cs []center
+	for _, b := range bs {
+		x, y := b.Center()
+		cs = append(cs, center{h: (b.Y2 - b.Y1) * float64(height), x: x * float64(width), y: y * float64(height)})
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].x < cs[j].x })
+
+	// Loop through centers
+	var as []float64
+	for i, c := range cs {
+		// Look for the closest neighbor on the right
+		min, a := math.Inf(1), 0.0
+		for _, o := range cs[i+1:] {
+			dx, dy := o.x-c.x, o.y-c.y
+			if dx > 2*c.h {
+				break
+			}
+			if d := dx*dx + dy*dy; dx > 0 && math.Abs(dy) < dx && d < min {
+				min, a = d, math.Atan2(dy, dx)
+			}
+		}
+		if !math.IsInf(min, 1) {
+			as = append(as, a)
+		}
+	}
+
+	// No neighbors
+	if len(as) == 0 {
+		return 0
+	}
+
+	// Average the middle half of the angles, which is less sensitive than the median to the offsets between
+	// the centers of characters of different heights
+	sort.Float64s(as)
+	as = as[len(as)/4 : len(as)-len(as)/4]
+	var sum float64
+	for _, a := range as {
+		sum += a
+	}
+	return sum / float64(len(as))
+}
+
+// assembleLine splits characters of a line into words based on the gap between them along the reading
+// axis, and computes the line orientation
+func assembleLine(cs []DocumentChar, width, height int, vertical bool) (l DocumentLine) {
+	// Get reading axis
+	start := func(b DetectionBox) float64 { return b.X1 }
+	end := func(b DetectionBox) float64 { return b.X2 }
+	size := float64(width)
+	if vertical {
+		start = func(b DetectionBox) float64 { return b.Y1 }
+		end = func(b DetectionBox) float64 { return b.Y2 }
+		size = float64(height)
+	}
+
+	// Sort in reading order
+	sort.SliceStable(cs, func(i, j int) bool { return start(cs[i].Box) < start(cs[j].Box) })
+
+	// Get median character size which is used as the reference to detect spaces
+	var ss []float64
+	for _, c := range cs {
+		ss = append(ss, (end(c.Box)-start(c.Box))*size)
+	}
+	ms := median(ss)
+
+	// Loop through characters
+	var w *DocumentWord
+	for _, c := range cs {
+		// Characters far apart belong to different words
+		if w != nil && (start(c.Box)-end(w.Box))*size > 0.5*ms {
+			l.Words = append(l.Words, *w)
+			w = nil
+		}
+
+		// Add character to word
+		if w == nil {
+			w = &DocumentWord{Box: c.Box}
+		}
+		w.Chars = append(w.Chars, c)
+		w.Box = w.Box.Union(c.Box)
+	}
+	if w != nil {
+		l.Words = append(l.Words, *w)
+	}
+
+	// Compute confidences
+	var sum float64
+	if len(l.Words) > 0 {
+		l.Box = l.Words[0].Box
+	}
+	for idx := range l.Words {
+		var wsum float64
+		for _, c := range l.Words[idx].Chars {
+			wsum += c.Confidence
+		}
+		l.Words[idx].Confidence = wsum / float64(len(l.Words[idx].Chars))
+		sum += l.Words[idx].Confidence
+		l.Box = l.Box.Union(l.Words[idx].Box)
+	}
+	if len(l.Words) > 0 {
+		l.Confidence = sum / float64(len(l.Words))
+	}
+
+	// Compute orientation
+	l.Angle = lineAngle(cs, width, height, vertical)
+	switch {
+	case vertical:
+		l.Orientation = LineOrientationVertical
+	case math.Abs(l.Angle) >= rotatedLineMinAngle:
+		l.Orientation = LineOrientationRotated
+	default:
+		l.Orientation = LineOrientationHorizontal
+	}
+	return
+}
+
+// lineAngle fits a line through the centers of the characters using least squares and returns its angle in
+// degrees. Positive angles are counterclockwise.
+func lineAngle(cs []DocumentChar, width, height int, vertical bool) float64 {
+	// Get centers, in pixels. The reading axis is u whereas v is the other axis.
+	var us, vs []float64
+	for _, c := range cs {
+		x, y := c.Box.Center()
+		x, y = x*float64(width), y*float64(height)
+		if vertical {
+			us, vs = append(us, y), append(vs, x)
+		} else {
+			us, vs = append(us, x), append(vs, y)
+		}
+	}
+
+	// Compute means
+	var mu, mv float64
+	for idx := range us {
+		mu += us[idx]
+		mv += vs[idx]
+	}
+	mu /= float64(len(us))
+	mv /= float64(len(vs))
+
+	// Compute slope
+	var cov, vu float64
+	for idx := range us {
+		cov += (us[idx] - mu) * (vs[idx] - mv)
+		vu += (us[idx] - mu) * (us[idx] - mu)
+	}
+	if vu == 0 {
+		return 0
+	}
+	a := math.Atan(cov/vu) * 180 / math.Pi
+
+	// Y axis points downward
+	if !vertical && a != 0 {
+		a = -a
+	}
+	return a
+}
+
+func overlapsHorizontally(a, b DetectionBox) bool {
+	return a.X1 < b.X2 && b.X1 < a.X2
+}
+
+func overlapsVertically(a, b DetectionBox) bool {
+	return a.Y1 < b.Y2 && b.Y1 < a.Y2
+}
@@ -0,0 +1,144 @@
+package astiocr
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func isURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// downloadModel downloads the model located at rawURL into the cache directory and returns its local
+// path. Models already in the cache are not downloaded again, and interrupted downloads are resumed.
+func downloadModel(ctx context.Context, l Logger, rawURL, dirPath string, i ConfigurationIntegrity) (p string, err error) {
+	// Parse url
+	var u *url.URL
+	if u, err = url.Parse(rawURL); err != nil {
+		err = errors.Wrapf(err, "astiocr: parsing url %s failed", rawURL)
+		return
+	}
+
+	// Get path. The url hash prevents models with the same name but different urls from colliding.
+	p = filepath.Join(dirPath, fmt.Sprintf("%x", sha256.Sum256([]byte(rawURL)))[:16]+"_"+path.Base(u.Path))
+
+	// Model is already in the cache
+	if _, err = os.Stat(p); err == nil {
+		if err = verifyFile(p, i); err == nil {
+			l.Debugf("astiocr: %s already exists, skipping download of %s", p, rawURL)
+			return
+		}
+		l.Error(errors.Wrapf(err, "astiocr: verifying cached %s failed, downloading it again", p))
+		if err = os.Remove(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: removing %s failed", p)
+			return
+		}
+	} else if !os.IsNotExist(err) {
+		err = errors.Wrapf(err, "astiocr: stating %s failed", p)
+		return
+	}
+
+	// Create cache directory
+	if err = os.MkdirAll(dirPath, 0755); err != nil {
+		err = errors.Wrapf(err, "astiocr: mkdirall %s failed", dirPath)
+		return
+	}
+
+	// Download
+	pp := p + ".part"
+	l.Debugf("astiocr: downloading %s to %s", rawURL, pp)
+	if err = downloadResume(ctx, l, rawURL, pp); err != nil {
+		err = errors.Wrapf(err, "astiocr: downloading %s to %s failed", rawURL, pp)
+		return
+	}
+
+	// Verify
+	if err = verifyFile(pp, i); err != nil {
+		os.Remove(pp)
+		err = errors.Wrapf(err, "astiocr: verifying %s failed", rawURL)
+		return
+	}
+
+	// Rename
+	if err = os.Rename(pp, p); err != nil {
+		err = errors.Wrapf(err, "astiocr: renaming %s to %s failed", pp, p)
+		return
+	}
+	return
+}
+
+// downloadResume downloads the url into p. If p already exists, only the missing bytes are requested.
+func downloadResume(ctx context.Context, l Logger, rawURL, p string) (err error) {
+	// Open file
+	var f *os.File
+	if f, err = os.OpenFile(p, os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Get offset
+	var offset int64
+	if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+		err = errors.Wrapf(err, "astiocr: seeking end of %s failed", p)
+		return
+	}
+
+	// Create request
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, rawURL, nil); err != nil {
+		err = errors.Wrap(err, "astiocr: creating request failed")
+		return
+	}
+	req = req.WithContext(ctx)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Send request
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		err = errors.Wrapf(err, "astiocr: sending request to %s failed", rawURL)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Process status code
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		l.Debugf("astiocr: resuming download of %s at byte %d", rawURL, offset)
+	case http.StatusOK:
+		// Server doesn't support ranges, start from scratch
+		if err = f.Truncate(0); err != nil {
+			err = errors.Wrapf(err, "astiocr: truncating %s failed", p)
+			return
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			err = errors.Wrapf(err, "astiocr: seeking start of %s failed", p)
+			return
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// File is already complete
+		return
+	default:
+		err = fmt.Errorf("astiocr: invalid status code %d", resp.StatusCode)
+		return
+	}
+
+	// Copy
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		err = errors.Wrapf(err, "astiocr: copying body to %s failed", p)
+		return
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"golang.org/x/image/draw"
+	ft "golang.org/x/image/font"
+)
+
+// GatherSummaryOutline represents the outline drawn around the characters of a summary image
+type GatherSummaryOutline struct {
+	// Color, formatted as "#rrggbbaa"
+	Color string `json:"color"`
+	Width int    `json:"width"`
+	c     color.RGBA
+}
+
+// GatherSummaryShadow represents the drop shadow drawn behind the characters of a summary image
+type GatherSummaryShadow struct {
+	// Blur radius, in pixels
+	Blur    int     `json:"blur"`
+	Opacity float64 `json:"opacity"`
+	// Offset, in pixels
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// randomOutline returns an outline of the configured proportion of images, or nil
+func (t *Trainer) randomOutline() *GatherSummaryOutline {
+	// Check proportion
+	if t.outline.Proportion <= 0 || rand.Float64()*100 >= t.outline.Proportion {
+		return nil
+	}
+
+	// Pick color and width
+	c := t.outline.Colors[rand.Intn(len(t.outline.Colors))].RGBA
+	return &GatherSummaryOutline{
+		Color: fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A),
+		Width: t.outline.WidthMin + rand.Intn(t.outline.WidthMax-t.outline.WidthMin+1),
+		c:     c,
+	}
+}
+
+// randomShadow returns a drop shadow of the configured proportion of images, or nil
+func (t *Trainer) randomShadow() *GatherSummaryShadow {
+	// Check proportion
+	if t.shadow.Proportion <= 0 || rand.Float64()*100 >= t.shadow.Proportion {
+		return nil
+	}
+
+	// Pick blur, offset and opacity
+	return &GatherSummaryShadow{
+		Blur:    t.shadow.BlurMin + rand.Intn(t.shadow.BlurMax-t.shadow.BlurMin+1),
+		Opacity: t.shadow.OpacityMin + rand.Float64()*(t.shadow.OpacityMax-t.shadow.OpacityMin),
+		X:       t.shadow.OffsetMin + rand.Intn(t.shadow.OffsetMax-t.shadow.OffsetMin+1),
+		Y:       t.shadow.OffsetMin + rand.Intn(t.shadow.OffsetMax-t.shadow.OffsetMin+1),
+	}
+}
+
+// randomTextOpacity returns the opacity of the text of the configured proportion of images, or 0 when the
+// text is opaque
+func (t *Trainer) randomTextOpacity() float64 {
+	if t.textOpacity.Proportion <= 0 || rand.Float64()*100 >= t.textOpacity.Proportion {
+		return 0
+	}
+	return t.textOpacity.Min + rand.Float64()*(t.textOpacity.Max-t.textOpacity.Min)
+}
+
+// inkColor returns the color the characters of the image are drawn with, which is the font color made
+// translucent by the text opacity
+func inkColor(fontColor color.Color, si *GatherSummaryImage) color.Color {
+	if si == nil || si.TextOpacity <= 0 {
+		return fontColor
+	}
+	r, g, b, a := fontColor.RGBA()
+	scale := func(v uint32) uint16 { return uint16(math.Round(float64(v) * si.TextOpacity)) }
+	return color.RGBA64{R: scale(r), G: scale(g), B: scale(b), A: scale(a)}
+}
+
+// effectsPadding returns the room the effects need around the glyph ink
+func effectsPadding(si *GatherSummaryImage) (p int) {
+	if si == nil {
+		return
+	}
+	if si.Outline != nil {
+		p += si.Outline.Width
+	}
+	if si.Shadow != nil {
+		p += si.Shadow.Blur
+	}
+	return
+}
+
+// drawStringEffects draws the effects of the string the drawer is about to draw
+func drawStringEffects(d *ft.Drawer, s string, si *GatherSummaryImage) {
+	// No effects
+	if si == nil || (si.Outline == nil && si.Shadow == nil) {
+		return
+	}
+
+	// Draw string mask
+	b, _ := d.BoundString(s)
+	mask := image.NewAlpha(glyphRect(b).Inset(-effectsPadding(si)))
+	(&ft.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: d.Face,
+		Dot:  d.Dot,
+	}).DrawString(s)
+
+	// Draw effects
+	drawEffects(d.Dst, mask, si)
+}
+
+// drawEffects draws the drop shadow and the outline of the ink mask, in that order. The mask bounds must
+// leave room for the effects.
+func drawEffects(img draw.Image, mask *image.Alpha, si *GatherSummaryImage) {
+	// No effects
+	if si == nil || (si.Outline == nil && si.Shadow == nil) {
+		return
+	}
+
+	// The outline is part of the shape casting the shadow
+	if si.Outline != nil {
+		mask = dilateAlpha(mask, si.Outline.Width)
+	}
+
+	// Draw shadow
+	if s := si.Shadow; s != nil {
+		r := mask.Bounds()
+		draw.DrawMask(img, r.Add(image.Pt(s.X, s.Y)), image.NewUniform(color.NRGBA{A: uint8(math.Round(s.Opacity * 0xff))}), image.ZP, blurAlpha(mask, s.Blur), r.Min, draw.Over)
+	}
+
+	// Draw outline
+	if o := si.Outline; o != nil {
+		draw.DrawMask(img, mask.Bounds(), image.NewUniform(o.c), image.ZP, mask, mask.Bounds().Min, draw.Over)
+	}
+}
+
+// dilateAlpha returns the mask where each pixel is the max of the pixels located inside the disc of the
+// radius
+func dilateAlpha(mask *image.Alpha, radius int) *image.Alpha {
+	r := mask.Bounds()
+	o := image.NewAlpha(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var a uint8
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					if dx*dx+dy*dy > radius*radius || !image.Pt(x+dx, y+dy).In(r) {
+						continue
+					}
+					if v := mask.AlphaAt(x+dx, y+dy).A; v > a {
+						a = v
+					}
+				}
+			}
+			o.SetAlpha(x, y, color.Alpha{A: a})
+		}
+	}
+	return o
+}
+
+// blurAlpha returns the mask blurred by a box filter of the radius, applied horizontally then vertically
+func blurAlpha(mask *image.Alpha, radius int) *image.Alpha {
+	// No blur
+	if radius <= 0 {
+		return mask
+	}
+
+	// Loop through directions
+	r := mask.Bounds()
+	src := mask
+	for _, d := range []image.Point{{X: 1}, {Y: 1}} {
+		dst := image.NewAlpha(r)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				var sum int
+				for k := -radius; k <= radius; k++ {
+					if p := image.Pt(x+k*d.X, y+k*d.Y); p.In(r) {
+						sum += int(src.AlphaAt(p.X, p.Y).A)
+					}
+				}
+				dst.SetAlpha(x, y, color.Alpha{A: uint8(sum / (2*radius + 1))})
+			}
+		}
+		src = dst
+	}
+	return src
+}
@@ -0,0 +1,150 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// ConfigurationElastic represents an elastic distortion configuration
+// The proportion of images is distorted after rendering, like warped or handheld captures, by moving each
+// pixel along a random displacement field smoothed by a gaussian whose standard deviation in pixels is
+// picked between sigma min and sigma max (default is 4 and 8), the biggest displacement in pixels being
+// picked between alpha min and alpha max (default is 1 and 3). Boxes are remapped accordingly.
+type ConfigurationElastic struct {
+	AlphaMax   float64 `toml:"alpha_max"`
+	AlphaMin   float64 `toml:"alpha_min"`
+	Proportion float64 `toml:"proportion"`
+	SigmaMax   float64 `toml:"sigma_max"`
+	SigmaMin   float64 `toml:"sigma_min"`
+}
+
+// GatherSummaryElastic represents the elastic distortion applied to a summary image
+type GatherSummaryElastic struct {
+	Alpha float64 `json:"alpha"`
+	Sigma float64 `json:"sigma"`
+}
+
+// random returns the elastic distortion applied to images
+func (c ConfigurationElastic) random() *GatherSummaryElastic {
+	return &GatherSummaryElastic{
+		Alpha: c.AlphaMin + rand.Float64()*(c.AlphaMax-c.AlphaMin),
+		Sigma: c.SigmaMin + rand.Float64()*(c.SigmaMax-c.SigmaMin),
+	}
+}
+
+// elasticField returns random displacements of the pixels of an image of the size, row-major, smoothed by
+// a gaussian and scaled so that the biggest displacement is alpha
+func elasticField(width, height int, e *GatherSummaryElastic) (dx, dy []float64) {
+	// Draw random displacements
+	dx, dy = make([]float64, width*height), make([]float64, width*height)
+	for idx := range dx {
+		dx[idx], dy[idx] = rand.Float64()*2-1, rand.Float64()*2-1
+	}
+
+	// Smooth
+	radius := int(math.Ceil(3 * e.Sigma))
+	var ks []float64
+	var total float64
+	for d := -radius; d <= radius; d++ {
+		k := math.Exp(-float64(d*d) / (2 * e.Sigma * e.Sigma))
+		ks = append(ks, k)
+		total += k
+	}
+	clamp := func(v, max int) int { return int(math.Max(0, math.Min(float64(max-1), float64(v)))) }
+	smooth := func(f []float64) []float64 {
+		// Horizontally then vertically
+		for _, horizontal := range []bool{true, false} {
+			o := make([]float64, len(f))
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					var v float64
+					for idx, k := range ks {
+						d := idx - radius
+						if horizontal {
+							v += k * f[y*width+clamp(x+d, width)]
+						} else {
+							v += k * f[clamp(y+d, height)*width+x]
+						}
+					}
+					o[y*width+x] = v / total
+				}
+			}
+			f = o
+		}
+		return f
+	}
+	dx, dy = smooth(dx), smooth(dy)
+
+	// Scale
+	var max float64
+	for idx := range dx {
+		max = math.Max(max, math.Hypot(dx[idx], dy[idx]))
+	}
+	if max == 0 {
+		return
+	}
+	for idx := range dx {
+		dx[idx] *= e.Alpha / max
+		dy[idx] *= e.Alpha / max
+	}
+	return
+}
+
+// distortElastic distorts the image and remaps the boxes of the summary to the bounds of the pixels whose
+// color comes from inside them
+func distortElastic(img *image.RGBA, e *GatherSummaryElastic, si *GatherSummaryImage) {
+	// Distort
+	r := img.Bounds()
+	w, h := r.Dx(), r.Dy()
+	dx, dy := elasticField(w, h, e)
+	remapImage(img, func(x, y int) (float64, float64) {
+		idx := (y-r.Min.Y)*w + x - r.Min.X
+		return float64(x) + dx[idx], float64(y) + dy[idx]
+	})
+
+	// Remap boxes
+	pad := int(math.Ceil(e.Alpha)) + 1
+	remap := func(x0, x1, y0, y1 *int) {
+		o := image.Rect(*x0, *y0, *x1, *y1)
+		s := o.Inset(-pad).Intersect(image.Rect(0, 0, w, h))
+		var b image.Rectangle
+		for y := s.Min.Y; y < s.Max.Y; y++ {
+			for x := s.Min.X; x < s.Max.X; x++ {
+				idx := y*w + x
+				sx, sy := float64(x)+0.5+dx[idx], float64(y)+0.5+dy[idx]
+				if sx >= float64(o.Min.X) && sx < float64(o.Max.X) && sy >= float64(o.Min.Y) && sy < float64(o.Max.Y) {
+					b = b.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+		if b.Empty() {
+			return
+		}
+		*x0, *x1, *y0, *y1 = b.Min.X, b.Max.X, b.Min.Y, b.Max.Y
+	}
+	for idx := range si.Boxes {
+		b := &si.Boxes[idx]
+		remap(&b.X0, &b.X1, &b.Y0, &b.Y1)
+	}
+	for idx := range si.Words {
+		sw := &si.Words[idx]
+		remap(&sw.X0, &sw.X1, &sw.Y0, &sw.Y1)
+	}
+}
+
+// validate applies the default elastic options and checks them
+func (c *ConfigurationElastic) validate() (err error) {
+	if c.AlphaMin == 0 && c.AlphaMax == 0 {
+		c.AlphaMin, c.AlphaMax = 1, 3
+	}
+	if c.SigmaMin == 0 && c.SigmaMax == 0 {
+		c.SigmaMin, c.SigmaMax = 4, 8
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.AlphaMin < 0 || c.AlphaMax < c.AlphaMin || c.SigmaMin <= 0 || c.SigmaMax < c.SigmaMin {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid elastic proportion %v with alpha range [%v, %v] and sigma range [%v, %v]", c.Proportion, c.AlphaMin, c.AlphaMax, c.SigmaMin, c.SigmaMax))
+		return
+	}
+	return
+}
@@ -0,0 +1,182 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Ensemble modes
+const (
+	ensembleModeMax  = "max"
+	ensembleModeVote = "vote"
+)
+
+// ensembleBackend runs several backends on the same image and merges their results
+type ensembleBackend struct {
+	bs       []Backend
+	minVotes int
+	mode     string
+}
+
+func newEnsembleBackend(c ConfigurationDetector) (b *ensembleBackend, err error) {
+	// Create backend
+	b = &ensembleBackend{
+		minVotes: c.Ensemble.MinVotes,
+		mode:     c.Ensemble.Mode,
+	}
+
+	// Mode
+	switch b.mode {
+	case "":
+		b.mode = ensembleModeMax
+	case ensembleModeMax, ensembleModeVote:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid ensemble mode %s", b.mode))
+		return
+	}
+
+	// Min votes
+	if b.minVotes <= 0 {
+		b.minVotes = len(c.Ensemble.ModelPaths)/2 + 1
+	}
+
+	// Loop through models
+	for _, p := range c.Ensemble.ModelPaths {
+		// Create backend
+		mc := c
+		mc.Ensemble = ConfigurationEnsemble{}
+		mc.ModelPath = p
+		var mb Backend
+		if mb, err = newBackend(mc); err != nil {
+			b.Close()
+			err = errors.Wrapf(err, "astiocr: creating backend for model %s failed", p)
+			return
+		}
+		b.bs = append(b.bs, mb)
+	}
+	return
+}
+
+// Close implements the Backend interface
+func (b *ensembleBackend) Close() (err error) {
+	for _, mb := range b.bs {
+		if errC := mb.Close(); errC != nil && err == nil {
+			err = errC
+		}
+	}
+	return
+}
+
+// Detect implements the Backend interface
+func (b *ensembleBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	// Run backends in parallel
+	rss := make([][]DetectionResult, len(b.bs))
+	errs := make([]error, len(b.bs))
+	wg := &sync.WaitGroup{}
+	for idx, mb := range b.bs {
+		wg.Add(1)
+		go func(idx int, mb Backend) {
+			defer wg.Done()
+			rss[idx], errs[idx] = mb.Detect(ctx, img)
+		}(idx, mb)
+	}
+	wg.Wait()
+
+	// Check errors
+	for idx, errD := range errs {
+		if errD != nil {
+			err = errors.Wrapf(errD, "astiocr: detecting with model #%d failed", idx+1)
+			return
+		}
+	}
+
+	// Merge
+	switch b.mode {
+	case ensembleModeVote:
+		rs = voteResults(rss, b.minVotes, mergeIoUThreshold)
+	default:
+		for _, mrs := range rss {
+			rs = append(rs, mrs...)
+		}
+		rs = mergeResults(rs, mergeIoUThreshold)
+	}
+	return
+}
+
+type ensembleVote struct {
+	model int
+	r     DetectionResult
+}
+
+// voteResults groups results of different models overlapping above the threshold, and keeps groups
+// where at least minVotes models agree on the label. The kept box is the average of the agreeing
+// boxes and its probability is the average probability over all models.
+func voteResults(rss [][]DetectionResult, minVotes int, threshold float64) (o []DetectionResult) {
+	// Flatten results sorted by decreasing probability
+	var vs []ensembleVote
+	for idx, rs := range rss {
+		for _, r := range rs {
+			vs = append(vs, ensembleVote{model: idx, r: r})
+		}
+	}
+	sort.SliceStable(vs, func(i, j int) bool { return vs[i].r.Probability > vs[j].r.Probability })
+
+	// Group results around the most probable ones
+	var groups [][]ensembleVote
+	for _, v := range vs {
+		var found bool
+		for idx, g := range groups {
+			if g[0].r.Box.IoU(v.r.Box) > threshold {
+				groups[idx] = append(groups[idx], v)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []ensembleVote{v})
+		}
+	}
+
+	// Loop through groups
+	for _, g := range groups {
+		// Count votes per label, each model voting at most once
+		votes := make(map[string]map[int]ensembleVote)
+		for _, v := range g {
+			if _, ok := votes[v.r.Label]; !ok {
+				votes[v.r.Label] = make(map[int]ensembleVote)
+			}
+			if _, ok := votes[v.r.Label][v.model]; !ok {
+				votes[v.r.Label][v.model] = v
+			}
+		}
+
+		// Get winning label
+		var label string
+		var best int
+		for l, mvs := range votes {
+			if len(mvs) > best || (len(mvs) == best && l < label) {
+				label, best = l, len(mvs)
+			}
+		}
+		if best < minVotes {
+			continue
+		}
+
+		// Average
+		r := DetectionResult{Label: label}
+		for _, v := range votes[label] {
+			r.Box.X1 += v.r.Box.X1 / float64(best)
+			r.Box.X2 += v.r.Box.X2 / float64(best)
+			r.Box.Y1 += v.r.Box.Y1 / float64(best)
+			r.Box.Y2 += v.r.Box.Y2 / float64(best)
+			r.Probability += v.r.Probability / float64(len(rss))
+		}
+		o = append(o, r)
+	}
+	return
+}
@@ -0,0 +1,143 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Equalization constants
+const (
+	// Default CLAHE clip limit, relative to the average histogram bin count
+	equalizeDefaultClipLimit = 2
+	// Default CLAHE number of tiles per side
+	equalizeDefaultGridSize = 8
+)
+
+func newEqualizeStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	switch c.Method {
+	case "", "global":
+		s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+			ls, w, _ := luminances(img)
+			m := equalizationMapping(ls, 0)
+			return equalize(img, ls, w, func(x, y int, l uint8) float64 { return m[l] }), identityBox
+		}
+	case "clahe":
+		// Default values
+		clipLimit, gridSize := c.ClipLimit, c.GridSize
+		if clipLimit == 0 {
+			clipLimit = equalizeDefaultClipLimit
+		}
+		if gridSize == 0 {
+			gridSize = equalizeDefaultGridSize
+		}
+		if clipLimit < 1 || gridSize < 0 {
+			err = fmt.Errorf("astiocr: invalid clip limit %v and grid size %d", clipLimit, gridSize)
+			return
+		}
+		s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+			ls, w, h := luminances(img)
+			if w == 0 || h == 0 {
+				return img, identityBox
+			}
+			return equalize(img, ls, w, claheMapping(ls, w, h, gridSize, clipLimit)), identityBox
+		}
+	default:
+		err = fmt.Errorf("astiocr: invalid equalization method %s", c.Method)
+	}
+	return
+}
+
+// equalize shifts the pixels of the image so that their luminance becomes the one returned by f. Colors
+// are kept.
+func equalize(img image.Image, ls []float64, w int, f func(x, y int, l uint8) float64) image.Image {
+	r := img.Bounds()
+	dst := image.NewRGBA(r)
+	shift := func(v uint8, d float64) uint8 { return uint8(math.Max(0, math.Min(255, float64(v)+d))) }
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			l := ls[(y-r.Min.Y)*w+x-r.Min.X]
+			d := f(x-r.Min.X, y-r.Min.Y, uint8(math.Min(255, l))) - l
+			dst.SetRGBA(x, y, color.RGBA{R: shift(c.R, d), G: shift(c.G, d), B: shift(c.B, d), A: c.A})
+		}
+	}
+	return dst
+}
+
+// equalizationMapping returns the luminance mapping spreading the histogram of the luminances over the
+// whole range. If clip is > 0, histogram bins are clipped to that count and the excess is redistributed
+// over all bins, which limits noise amplification.
+func equalizationMapping(ls []float64, clip float64) (m [256]float64) {
+	// Build histogram
+	if len(ls) == 0 {
+		return
+	}
+	var hs [256]float64
+	for _, l := range ls {
+		hs[int(math.Min(255, l))]++
+	}
+
+	// Clip histogram
+	if clip > 0 {
+		var excess float64
+		for idx := range hs {
+			if hs[idx] > clip {
+				excess += hs[idx] - clip
+				hs[idx] = clip
+			}
+		}
+		for idx := range hs {
+			hs[idx] += excess / 256
+		}
+	}
+
+	// Build mapping from the cumulative histogram
+	var sum float64
+	for idx := range hs {
+		sum += hs[idx]
+		m[idx] = sum * 255 / float64(len(ls))
+	}
+	return
+}
+
+// claheMapping returns a contrast limited adaptive histogram equalization: the image is split in a grid
+// of tiles equalized independently, and the mappings of the 4 closest tiles are interpolated for each
+// pixel so that no tile border is visible
+func claheMapping(ls []float64, w, h, gridSize int, clipLimit float64) func(x, y int, l uint8) float64 {
+	// Get tile dimensions
+	gx, gy := int(math.Min(float64(gridSize), float64(w))), int(math.Min(float64(gridSize), float64(h)))
+	tw, th := float64(w)/float64(gx), float64(h)/float64(gy)
+
+	// Loop through tiles
+	ms := make([][256]float64, gx*gy)
+	for ty := 0; ty < gy; ty++ {
+		for tx := 0; tx < gx; tx++ {
+			// Get tile luminances
+			var tls []float64
+			for y := int(float64(ty) * th); y < int(float64(ty+1)*th); y++ {
+				for x := int(float64(tx) * tw); x < int(float64(tx+1)*tw); x++ {
+					tls = append(tls, ls[y*w+x])
+				}
+			}
+
+			// Build mapping
+			ms[ty*gx+tx] = equalizationMapping(tls, clipLimit*float64(len(tls))/256)
+		}
+	}
+
+	return func(x, y int, l uint8) float64 {
+		// Get the closest tile centers
+		fx := math.Max(0, math.Min(float64(gx-1), (float64(x)+0.5)/tw-0.5))
+		fy := math.Max(0, math.Min(float64(gy-1), (float64(y)+0.5)/th-0.5))
+		x1, y1 := int(fx), int(fy)
+		x2, y2 := int(math.Min(float64(gx-1), float64(x1+1))), int(math.Min(float64(gy-1), float64(y1+1)))
+		ax, ay := fx-float64(x1), fy-float64(y1)
+
+		// Interpolate
+		top := (1-ax)*ms[y1*gx+x1][l] + ax*ms[y1*gx+x2][l]
+		bottom := (1-ax)*ms[y2*gx+x1][l] + ax*ms[y2*gx+x2][l]
+		return (1-ay)*top + ay*bottom
+	}
+}
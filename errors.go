@@ -0,0 +1,58 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+
+	"github.com/pkg/errors"
+)
+
+// Errors callers can branch on. Since they may be wrapped, use errors.Cause to retrieve them.
+var (
+	ErrConfigInvalid          = errors.New("astiocr: invalid configuration")
+	ErrInferenceFailed        = errors.New("astiocr: inference failed")
+	ErrModelNotFound          = errors.New("astiocr: model not found")
+	ErrUnsupportedImageFormat = errors.New("astiocr: unsupported image format")
+)
+
+// kindError attaches one of the exported errors to an error while keeping its message. errors.Cause
+// returns the exported error whereas Unwrap returns the original error.
+type kindError struct {
+	err  error
+	kind error
+}
+
+func withKind(kind, err error) error {
+	return &kindError{
+		err:  err,
+		kind: kind,
+	}
+}
+
+// Error implements the error interface
+func (e *kindError) Error() string { return e.err.Error() }
+
+// Cause implements the causer interface of github.com/pkg/errors
+func (e *kindError) Cause() error { return e.kind }
+
+// Is makes errors.Is match the exported error
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// Unwrap returns the original error
+func (e *kindError) Unwrap() error { return e.err }
+
+// inferenceError attaches ErrInferenceFailed to backend errors, unless they're caused by the context
+func inferenceError(err error) error {
+	if c := errors.Cause(err); c == context.Canceled || c == context.DeadlineExceeded {
+		return err
+	}
+	return withKind(ErrInferenceFailed, err)
+}
+
+// decodeError attaches ErrUnsupportedImageFormat to decoding errors caused by unknown formats
+func decodeError(err error) error {
+	if err == image.ErrFormat {
+		return withKind(ErrUnsupportedImageFormat, err)
+	}
+	return err
+}
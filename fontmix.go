@@ -0,0 +1,101 @@
+package astiocr
+
+import (
+	"math/rand"
+
+	ft "golang.org/x/image/font"
+)
+
+// Font mixing modes
+const (
+	fontMixingCharacter = "character"
+	fontMixingNone      = "none"
+	fontMixingWord      = "word"
+)
+
+// faceCache creates the face of each font once per image
+type faceCache struct {
+	faces    map[*font]ft.Face
+	fontSize int
+}
+
+func newFaceCache(fontSize int) *faceCache {
+	return &faceCache{
+		faces:    make(map[*font]ft.Face),
+		fontSize: fontSize,
+	}
+}
+
+func (c *faceCache) face(f *font) ft.Face {
+	fc, ok := c.faces[f]
+	if !ok {
+		fc = newFace(f, c.fontSize)
+		c.faces[f] = fc
+	}
+	return fc
+}
+
+// randomFont returns a random font
+func (t *Trainer) randomFont() *font {
+	if len(t.fonts) == 1 {
+		return t.fonts[0]
+	}
+	return t.fonts[rand.Intn(len(t.fonts))]
+}
+
+// summaryFont returns the font with the name and style recorded in a summary, or the first font if it
+// can't be found
+func (t *Trainer) summaryFont(name, style string) *font {
+	for _, f := range t.fonts {
+		if f.name == name && f.style == style {
+			return f
+		}
+	}
+	return t.fonts[0]
+}
+
+// characterFont returns the font of an isolated character, f being the font of the image
+func (t *Trainer) characterFont(f *font) *font {
+	if t.fontMixing == fontMixingNone {
+		return f
+	}
+	return t.randomFont()
+}
+
+// wordFonts returns the font of each character of the word, f being the font of the image
+func (t *Trainer) wordFonts(f *font, w string) (fs []*font) {
+	// Get word font
+	wf := f
+	if t.fontMixing == fontMixingWord {
+		wf = t.randomFont()
+	}
+
+	// Loop through characters
+	for range []rune(w) {
+		if t.fontMixing == fontMixingCharacter {
+			fs = append(fs, t.randomFont())
+		} else {
+			fs = append(fs, wf)
+		}
+	}
+	return
+}
+
+// lineMetrics returns the ascent and descent of lines, which are the biggest of all fonts when fonts are
+// mixed so that lines don't overlap
+func (t *Trainer) lineMetrics(c *faceCache, f *font) (ascent, descent int) {
+	fs := []*font{f}
+	if t.fontMixing != fontMixingNone {
+		fs = t.fonts
+	}
+	for _, f := range fs {
+		m := c.face(f).Metrics()
+		if a := m.Ascent.Ceil(); a > ascent {
+			ascent = a
+		}
+		if d := m.Descent.Ceil(); d > descent {
+			descent = d
+		}
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	ft "golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Hintings
+const (
+	hintingFull     = "full"
+	hintingNone     = "none"
+	hintingVertical = "vertical"
+)
+
+// parseHinting parses the hinting of a font configuration
+func parseHinting(s string) (h ft.Hinting, err error) {
+	switch s {
+	case "", hintingNone:
+		h = ft.HintingNone
+	case hintingFull:
+		h = ft.HintingFull
+	case hintingVertical:
+		h = ft.HintingVertical
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid hinting %s", s))
+	}
+	return
+}
+
+// aliasedFace draws the glyphs of a face without anti-aliasing
+type aliasedFace struct {
+	ft.Face
+}
+
+// Glyph implements the ft.Face interface
+func (f aliasedFace) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	// Get glyph
+	var m image.Image
+	var mp image.Point
+	if dr, m, mp, advance, ok = f.Face.Glyph(dot, r); !ok {
+		return
+	}
+
+	// Threshold mask
+	dst := image.NewAlpha(dr)
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			if _, _, _, a := m.At(mp.X+x-dr.Min.X, mp.Y+y-dr.Min.Y).RGBA(); a >= 0x8000 {
+				dst.SetAlpha(x, y, color.Alpha{A: 0xff})
+			}
+		}
+	}
+	return dr, dst, dr.Min, advance, ok
+}
+
+// pixelatedFace draws the glyphs of a face, whose size has been divided by the factor, upscaled by the
+// factor without interpolation, which looks like small text upscaled in videos
+type pixelatedFace struct {
+	ft.Face
+	factor int
+}
+
+// Glyph implements the ft.Face interface. Glyphs are drawn at the rounded dot so that their bounds don't
+// depend on the sub-pixel position of the dot.
+func (f pixelatedFace) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	// Get small glyph
+	var sdr image.Rectangle
+	var sm image.Image
+	var smp image.Point
+	if sdr, sm, smp, advance, ok = f.Face.Glyph(fixed.Point26_6{}, r); !ok {
+		return
+	}
+	advance *= fixed.Int26_6(f.factor)
+
+	// Upscale mask
+	o := image.Pt(dot.X.Round(), dot.Y.Round())
+	dr = image.Rect(sdr.Min.X*f.factor, sdr.Min.Y*f.factor, sdr.Max.X*f.factor, sdr.Max.Y*f.factor).Add(o)
+	dst := image.NewAlpha(dr)
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			_, _, _, a := sm.At(smp.X+floorDiv(x-o.X, f.factor)-sdr.Min.X, smp.Y+floorDiv(y-o.Y, f.factor)-sdr.Min.Y).RGBA()
+			dst.SetAlpha(x, y, color.Alpha{A: uint8(a >> 8)})
+		}
+	}
+	return dr, dst, dr.Min, advance, ok
+}
+
+// GlyphBounds implements the ft.Face interface
+func (f pixelatedFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	var sdr image.Rectangle
+	if sdr, _, _, advance, ok = f.Face.Glyph(fixed.Point26_6{}, r); !ok {
+		return
+	}
+	bounds = fixed.R(sdr.Min.X*f.factor, sdr.Min.Y*f.factor, sdr.Max.X*f.factor, sdr.Max.Y*f.factor)
+	advance *= fixed.Int26_6(f.factor)
+	return
+}
+
+// GlyphAdvance implements the ft.Face interface
+func (f pixelatedFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	advance, ok = f.Face.GlyphAdvance(r)
+	advance *= fixed.Int26_6(f.factor)
+	return
+}
+
+// Kern implements the ft.Face interface
+func (f pixelatedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	return f.Face.Kern(r0, r1) * fixed.Int26_6(f.factor)
+}
+
+// Metrics implements the ft.Face interface
+func (f pixelatedFace) Metrics() ft.Metrics {
+	m := f.Face.Metrics()
+	k := fixed.Int26_6(f.factor)
+	m.Ascent *= k
+	m.CapHeight *= k
+	m.Descent *= k
+	m.Height *= k
+	m.XHeight *= k
+	return m
+}
+
+// floorDiv returns a / b rounded down
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
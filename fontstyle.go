@@ -0,0 +1,198 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	ft "golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Style of fonts read from their main file
+const fontStyleRegular = "regular"
+
+// Synthetic styles
+const (
+	syntheticStyleBold        = "bold"
+	syntheticStyleBoldOblique = "bold_oblique"
+	syntheticStyleOblique     = "oblique"
+)
+
+// Synthetic style constants
+const (
+	// Stroke width added by the synthetic bold style, as a fraction of the font height
+	syntheticBoldStrokeRatio = 0.05
+	// Horizontal shift per pixel above the baseline of the synthetic oblique style, which is ~12 degrees
+	syntheticObliqueSlant = 0.2
+)
+
+// styledFace applies synthetic bold and oblique transforms to the glyphs of a face
+type styledFace struct {
+	ft.Face
+	oblique bool
+	stroke  int
+}
+
+func newStyledFace(f ft.Face, bold, oblique bool) ft.Face {
+	sf := &styledFace{
+		Face:    f,
+		oblique: oblique,
+	}
+	if bold {
+		sf.stroke = int(math.Max(1, math.Round(float64(f.Metrics().Height.Ceil())*syntheticBoldStrokeRatio)))
+	}
+	return sf
+}
+
+// slant returns the horizontal shift of pixels located dy pixels below the baseline
+func (f *styledFace) slant(dy int) int {
+	if !f.oblique {
+		return 0
+	}
+	return int(math.Round(-float64(dy) * syntheticObliqueSlant))
+}
+
+// Glyph implements the ft.Face interface
+func (f *styledFace) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	// Get glyph
+	if dr, mask, maskp, advance, ok = f.Face.Glyph(dot, r); !ok || dr.Empty() {
+		return
+	}
+	advance += fixed.I(f.stroke)
+
+	// Get destination rectangle
+	baseline := dot.Y.Round()
+	o := image.Rect(
+		dr.Min.X+f.slant(dr.Max.Y-1-baseline),
+		dr.Min.Y,
+		dr.Max.X+f.slant(dr.Min.Y-baseline)+f.stroke,
+		dr.Max.Y,
+	)
+
+	// Loop through pixels
+	dst := image.NewAlpha(o)
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		shift := f.slant(y - baseline)
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			// Get alpha
+			_, _, _, a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+			if a == 0 {
+				continue
+			}
+
+			// Bold strokes are thickened by smearing pixels to the right
+			for s := 0; s <= f.stroke; s++ {
+				if p := dst.AlphaAt(x+shift+s, y); uint32(p.A) < a>>8 {
+					p.A = uint8(a >> 8)
+					dst.SetAlpha(x+shift+s, y, p)
+				}
+			}
+		}
+	}
+	return o, dst, o.Min, advance, ok
+}
+
+// GlyphBounds implements the ft.Face interface
+func (f *styledFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	if bounds, advance, ok = f.Face.GlyphBounds(r); !ok {
+		return
+	}
+	bounds.Min.X += fixed.I(f.slant(bounds.Max.Y.Ceil() - 1))
+	bounds.Max.X += fixed.I(f.slant(bounds.Min.Y.Floor()) + f.stroke)
+	advance += fixed.I(f.stroke)
+	return
+}
+
+// GlyphAdvance implements the ft.Face interface
+func (f *styledFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	if advance, ok = f.Face.GlyphAdvance(r); ok {
+		advance += fixed.I(f.stroke)
+	}
+	return
+}
+
+// parseFont parses TrueType and OpenType (including CFF) fonts. The first font of collections is used.
+func parseFont(b []byte) (f *opentype.Font, err error) {
+	// Parse font
+	var errParse error
+	if f, errParse = opentype.Parse(b); errParse == nil {
+		return
+	}
+
+	// Parse collection
+	var c *opentype.Collection
+	if c, err = opentype.ParseCollection(b); err != nil {
+		err = errors.Wrapf(errParse, "astiocr: parsing font failed")
+		return
+	}
+	if f, err = c.Font(0); err != nil {
+		err = errors.Wrap(err, "astiocr: getting first font of collection failed")
+		return
+	}
+	return
+}
+
+// missingGlyphs returns the charset characters the font has no glyph for
+func (t *Trainer) missingGlyphs(f *font) (missing string) {
+	var buf sfnt.Buffer
+	for _, c := range t.charset {
+		if i, err := f.font.GlyphIndex(&buf, c); err != nil || i == 0 {
+			missing += string(c)
+		}
+	}
+	return
+}
+
+// fontStyles returns the style variants of the font, read from files or synthesized from the regular font,
+// sorted by style so that picking them is reproducible
+func fontStyles(c ConfigurationFont, regular font) (fs []*font, err error) {
+	// Sort styles
+	var ss []string
+	for s := range c.Styles {
+		ss = append(ss, s)
+	}
+	sort.Strings(ss)
+
+	// Loop through files
+	for _, s := range ss {
+		// Read file
+		var b []byte
+		if b, err = ioutil.ReadFile(c.Styles[s]); err != nil {
+			err = errors.Wrapf(err, "astiocr: reading file %s failed", c.Styles[s])
+			return
+		}
+
+		// Add font
+		f := regular
+		f.body = b
+		f.name = c.Styles[s]
+		f.style = s
+		fs = append(fs, &f)
+	}
+
+	// Loop through synthetic styles
+	for _, s := range c.SyntheticStyles {
+		// Add font
+		f := regular
+		f.style = "synthetic_" + s
+		switch s {
+		case syntheticStyleBold:
+			f.bold = true
+		case syntheticStyleBoldOblique:
+			f.bold, f.oblique = true, true
+		case syntheticStyleOblique:
+			f.oblique = true
+		default:
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid synthetic style %s", s))
+			return
+		}
+		fs = append(fs, &f)
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+)
+
+// Free placement layout constants
+const (
+	// Maximum number of placement attempts per image
+	freeMaxAttempts = 100
+	// Maximum number of words per image
+	freeMaxWords = 30
+	// Minimum gap between words, as a proportion of the font size
+	freeWordGap = 0.25
+)
+
+func (t *Trainer) createImageStrategyFree(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	backgroundColor, fontColor, font := p.BackgroundColor, p.FontColor, p.font
+
+	// Create image
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
+
+	// Scatter words
+	t.scatterWords(img, font, fontColor, &si)
+	return
+}
+
+// scatterWords draws words of random sizes at random positions where they don't collide with previous words
+func (t *Trainer) scatterWords(img draw.Image, font *font, fontColor color.Color, si *GatherSummaryImage) {
+	// Loop through attempts
+	var occupied []image.Rectangle
+	for attempts, count := 0, 1+rand.Intn(freeMaxWords); attempts < freeMaxAttempts && len(si.Words) < count; attempts++ {
+		// Get word at a random scale
+		fc := newFaceCache(t.randomFontSize())
+		rw := t.randomWord()
+		w := t.spaceWord(fc, rw, t.wordFonts(font, rw))
+		ascent, descent := t.lineMetrics(fc, font)
+
+		// Word doesn't fit
+		if w.width >= si.Width || ascent+descent >= si.Height {
+			continue
+		}
+
+		// Get random position
+		x := rand.Intn(si.Width - w.width)
+		y := ascent + rand.Intn(si.Height-ascent-descent)
+
+		// Word collides with previous words
+		gap := int(float64(fc.fontSize)*freeWordGap) + effectsPadding(si)
+		r := image.Rect(x, y-ascent, x+w.width, y+descent).Inset(-gap)
+		if overlapsRectangles([]image.Rectangle{r}, occupied) {
+			continue
+		}
+		occupied = append(occupied, r)
+
+		// Draw word
+		si.Words = append(si.Words, t.drawWord(img, fc, fontColor, x, y, w, si))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
+	"math"
 	"math/rand"
 	"os"
 	"os/exec"
@@ -15,10 +16,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/asticode/go-astilog"
-	"github.com/golang/freetype/truetype"
 	"github.com/pkg/errors"
 	ft "golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -29,20 +29,72 @@ type GatherSummary struct {
 
 // GatherSummaryImage represents a gather summary image
 type GatherSummaryImage struct {
+	// Affine transformation, as the first 2 rows of its matrix, the image was transformed with, if any
+	Affine []float64 `json:"affine,omitempty"`
+	// Rotation of the text around the image center, in degrees counterclockwise
+	Angle float64 `json:"angle,omitempty"`
+	// Names of the registered augmenters applied to the image, if any
+	Augmenters []string `json:"augmenters,omitempty"`
+	// Background image the background was cropped from, or image the text was composited onto, if any
+	Background string `json:"background,omitempty"`
+	// Blur applied to the image, if any
+	Blur   *GatherSummaryBlur `json:"blur,omitempty"`
 	Height int                `json:"height"`
-	Boxes  []GatherSummaryBox `json:"boxes"`
-	Path   string             `json:"path"`
-	Width  int                `json:"width"`
+	// Whether the edges of the boxes were randomly moved
+	BoxJitter bool               `json:"box_jitter,omitempty"`
+	Boxes     []GatherSummaryBox `json:"boxes"`
+	// Color jitter applied to the image, if any
+	ColorJitter *GatherSummaryColorJitter `json:"color_jitter,omitempty"`
+	// Elastic distortion applied to the image, if any
+	Elastic *GatherSummaryElastic `json:"elastic,omitempty"`
+	// Font file used to draw the image, unless fonts are mixed
+	Font string `json:"font,omitempty"`
+	// Kind of the gradient drawn as background, if any
+	Gradient string `json:"gradient,omitempty"`
+	// Quality of the JPEG compression applied to the image, if any
+	JPEGQuality int `json:"jpeg_quality,omitempty"`
+	// Lighting applied to the image, if any
+	Lighting *GatherSummaryLighting `json:"lighting,omitempty"`
+	// Standard deviation of the gaussian noise added to the image, if any
+	NoiseSigma float64 `json:"noise_sigma,omitempty"`
+	// Outline drawn around the characters, if any
+	Outline *GatherSummaryOutline `json:"outline,omitempty"`
+	Path    string                `json:"path"`
+	// Homography, row-major, the image was warped with, if any
+	Perspective []float64 `json:"perspective,omitempty"`
+	// Proportion of pixels turned black or white, if any
+	SaltAndPepperDensity float64 `json:"salt_and_pepper_density,omitempty"`
+	// Shadow drawn behind the characters, if any
+	Shadow *GatherSummaryShadow `json:"shadow,omitempty"`
+	// Strategy the image was generated with
+	Strategy string `json:"strategy,omitempty"`
+	// Style of the font used to draw the image, unless fonts are mixed
+	Style string `json:"style,omitempty"`
+	// Opacity the text was drawn with over the background, if not opaque
+	TextOpacity float64 `json:"text_opacity,omitempty"`
+	// Kind of the procedural texture drawn as background, if any
+	Texture string              `json:"texture,omitempty"`
+	Width   int                 `json:"width"`
+	Words   []GatherSummaryWord `json:"words,omitempty"`
 }
 
 // GatherSummaryBox represents a gather summary box
 type GatherSummaryBox struct {
+	// Rotation of the character, in degrees counterclockwise, when it's not drawn horizontally. The box is
+	// then the tight box of the rotated character.
+	Angle float64 `json:"angle,omitempty"`
+	// Font file used to draw the character when fonts are mixed
+	Font string `json:"font,omitempty"`
+	// Size of the font used to draw the character
+	FontSize   int    `json:"font_size,omitempty"`
 	Label      string `json:"label"`
 	LabelIndex int    `json:"label_index"`
-	X0         int    `json:"x0"`
-	X1         int    `json:"x1"`
-	Y0         int    `json:"y0"`
-	Y1         int    `json:"y1"`
+	// Style of the font used to draw the character when fonts are mixed
+	Style string `json:"style,omitempty"`
+	X0    int    `json:"x0"`
+	X1    int    `json:"x1"`
+	Y0    int    `json:"y0"`
+	Y1    int    `json:"y1"`
 }
 
 // Gather gathers training data
@@ -50,6 +102,26 @@ func (t *Trainer) Gather(ctx context.Context) (err error) {
 	// Init
 	rand.Seed(time.Now().UnixNano())
 
+	// Add google fonts
+	if err = t.addGoogleFonts(ctx); err != nil {
+		err = errors.Wrap(err, "astiocr: adding google fonts failed")
+		return
+	}
+
+	// Profile target images
+	if len(t.profileDirectoryPath) > 0 {
+		if err = t.applyProfile(ctx); err != nil {
+			err = errors.Wrap(err, "astiocr: applying profile failed")
+			return
+		}
+	}
+
+	// Check augmentations
+	if err = t.checkAugmentations(); err != nil {
+		err = errors.Wrap(err, "astiocr: checking augmentations failed")
+		return
+	}
+
 	// Create data folders
 	if err = t.createDataFolders(); err != nil {
 		err = errors.Wrap(err, "astiocr: creating data folders failed")
@@ -62,37 +134,63 @@ func (t *Trainer) Gather(ctx context.Context) (err error) {
 		return
 	}
 
+	// Create store
+	var s *imageStore
+	if s, err = newImageStore(t.storeDirectoryPath); err != nil {
+		err = errors.Wrap(err, "astiocr: creating image store failed")
+		return
+	}
+
+	// Get fingerprint
+	var fingerprint string
+	if fingerprint, err = t.generationFingerprint(); err != nil {
+		err = errors.Wrap(err, "astiocr: getting generation fingerprint failed")
+		return
+	}
+
 	// Loop through count
+	var m GatherManifest
+	var reused int
 	var summaryTraining, summaryTest GatherSummary
-	astilog.Debugf("astiocr: generating %d images (%d for training - %d for test)", t.count, t.trainingDataCount, t.testDataCount)
+	t.l.Debugf("astiocr: generating %d images (%d for training - %d for test)", t.count, t.trainingDataCount, t.testDataCount)
 	for idx := 0; idx < t.count; idx++ {
 		// Check context
-		if ctx.Err() != nil {
+		if err = ctx.Err(); err != nil {
 			err = errors.Wrap(err, "astiocr: context error")
 			return
 		}
 
-		// Create image
-		var img *image.RGBA
+		// Seed
+		var key string
+		if t.seed != 0 {
+			// Training and test images may be generated with different strategies
+			split := "training"
+			if idx >= t.trainingDataCount {
+				split = "test"
+			}
+			rand.Seed(t.seed + int64(idx))
+			key = fmt.Sprintf("%s-%s-%d", fingerprint, split, t.seed+int64(idx))
+		}
+
+		// Reuse image
+		var hash string
 		var si GatherSummaryImage
-		if idx < t.trainingDataCount {
-			img, si = t.createImageStrategy2()
-		} else {
-			img, si = t.createImageStrategy2()
+		var ok bool
+		if si, hash, ok = s.get(key); ok {
+			reused++
+		} else if si, hash, err = t.generateImage(idx, key, s); err != nil {
+			err = errors.Wrap(err, "astiocr: generating image failed")
+			return
 		}
 
 		// No boxes
 		if len(si.Boxes) == 0 {
 			continue
 		}
-
-		// Store image
-		var p string
-		if p, err = t.storeImage(idx, img); err != nil {
-			err = errors.Wrap(err, "astiocr: storing image failed")
-			return
-		}
-		si.Path = p
+		m.Images = append(m.Images, GatherManifestImage{
+			Hash:  hash,
+			Index: idx + 1,
+		})
 
 		// Append image to summary
 		if idx < t.trainingDataCount {
@@ -103,9 +201,22 @@ func (t *Trainer) Gather(ctx context.Context) (err error) {
 
 		// Log
 		if (idx+1)%50 == 0 && idx > 0 {
-			astilog.Debugf("astiocr: %d/%d images created", idx+1, t.count)
+			t.l.Debugf("astiocr: %d/%d images created", idx+1, t.count)
 		}
 	}
+	t.l.Debugf("astiocr: %d images reused from the store", reused)
+
+	// Close store
+	if err = s.close(); err != nil {
+		err = errors.Wrap(err, "astiocr: closing image store failed")
+		return
+	}
+
+	// Write manifest
+	if err = t.writeManifest(m); err != nil {
+		err = errors.Wrap(err, "astiocr: writing manifest failed")
+		return
+	}
 
 	// Write summaries
 	if err = t.writeSummaries(summaryTraining, summaryTest); err != nil {
@@ -114,16 +225,96 @@ func (t *Trainer) Gather(ctx context.Context) (err error) {
 	}
 
 	// Prepare data
-	if err = t.prepareData(ctx); err != nil {
+	if err = t.prepareData(ctx, t.outputDataDirectoryPath); err != nil {
 		err = errors.Wrap(err, "astiocr: preparing data failed")
 		return
 	}
 	return
 }
 
+// Generation strategies
+const (
+	strategyCharacter  = "character"
+	strategyComposite  = "composite"
+	strategyCurved     = "curved"
+	strategyFree       = "free"
+	strategyGrid       = "grid"
+	strategyParagraphs = "paragraphs"
+	strategyReceipt    = "receipt"
+	strategyWords      = "words"
+)
+
+func (t *Trainer) generateImage(idx int, key string, s *imageStore) (si GatherSummaryImage, hash string, err error) {
+	// Create image
+	n := t.randomStrategy(idx)
+	p := t.newStrategyParams()
+	if !isBuiltinStrategy(n) {
+		p.Face = newFace(p.font, p.FontSize)
+	}
+	var img *image.RGBA
+	img, si = t.strategy(n).Generate(p)
+
+	// No boxes
+	if img == nil || len(si.Boxes) == 0 {
+		return
+	}
+	si.Strategy = n
+	for idx := range si.Boxes {
+		if b := &si.Boxes[idx]; b.LabelIndex == 0 {
+			if rs := []rune(b.Label); len(rs) == 1 {
+				b.LabelIndex = t.labelIndex(rs[0])
+			}
+		}
+	}
+
+	// Augment
+	if err = t.augmentImage(img, &si); err != nil {
+		err = errors.Wrap(err, "astiocr: augmenting image failed")
+		return
+	}
+
+	// Jitter boxes
+	if t.boxJitter.Proportion > 0 && t.boxJitter.Max > 0 {
+		t.jitterBoxes(&si)
+	}
+
+	// Store image
+	if si.Path, hash, err = s.put(key, img, si); err != nil {
+		err = errors.Wrap(err, "astiocr: storing image failed")
+		return
+	}
+	return
+}
+
+func (t *Trainer) applyProfile(ctx context.Context) (err error) {
+	// Profile
+	var p ImageProfile
+	if p, err = profileImages(ctx, t.l, t.profileDirectoryPath); err != nil {
+		err = errors.Wrapf(err, "astiocr: profiling images in %s failed", t.profileDirectoryPath)
+		return
+	}
+	t.l.Debugf("astiocr: profiled %d images: %d color(s), font sizes between %d and %d, noise sigma %.2f", p.Count, len(p.Colors), p.FontSizeMin, p.FontSizeMax, p.NoiseSigma)
+
+	// Apply
+	if len(p.Colors) > 0 {
+		t.colors = p.Colors
+	}
+	if !t.fontSizeConfigured && p.FontSizeMin > 0 && p.FontSizeMax >= p.FontSizeMin {
+		t.fontSize.Min, t.fontSize.Max = p.FontSizeMin, p.FontSizeMax
+	}
+	if t.gaussianNoise == (ConfigurationGaussianNoise{}) && p.NoiseSigma > 0 {
+		t.gaussianNoise = ConfigurationGaussianNoise{
+			Proportion: 100,
+			SigmaMax:   p.NoiseSigma * 1.5,
+			SigmaMin:   p.NoiseSigma / 2,
+		}
+	}
+	return
+}
+
 func (t *Trainer) createDataFolders() (err error) {
 	// Remove folder
-	astilog.Debugf("astiocr: removing %s", t.outputDataDirectoryPath)
+	t.l.Debugf("astiocr: removing %s", t.outputDataDirectoryPath)
 	if err = os.RemoveAll(t.outputDataDirectoryPath); err != nil {
 		err = errors.Wrapf(err, "astiocr: removeAll %s failed", t.outputDataDirectoryPath)
 		return
@@ -131,11 +322,10 @@ func (t *Trainer) createDataFolders() (err error) {
 
 	// Loop through folders to create
 	for _, p := range []string{
-		filepath.Join(t.outputDataDirectoryPath, "images"),
 		filepath.Join(t.outputDataDirectoryPath, "test"),
 		filepath.Join(t.outputDataDirectoryPath, "training"),
 	} {
-		astilog.Debugf("astiocr: creating %s", p)
+		t.l.Debugf("astiocr: creating %s", p)
 		if err = os.MkdirAll(p, 0700); err != nil {
 			err = errors.Wrapf(err, "astiocr: mkdirall %s failed", p)
 		}
@@ -143,7 +333,8 @@ func (t *Trainer) createDataFolders() (err error) {
 	return
 }
 
-const characters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+// labelMapEscaper escapes characters that can't appear as is in label map names
+var labelMapEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
 
 func (t *Trainer) createLabelMap() (err error) {
 	// Create file
@@ -156,50 +347,61 @@ func (t *Trainer) createLabelMap() (err error) {
 	defer f.Close()
 
 	// Loop through characters
-	astilog.Debugf("astiocr: creating label map to %s", p)
-	for idx, c := range characters {
-		if c == 'E' || c == 'e' {
-			if _, err = f.WriteString(fmt.Sprintf("item {\n  id: %d\n  name: '%s'\n}\n", idx+1, string(c))); err != nil {
-				err = errors.Wrapf(err, "astiocr: writing to %s failed", p)
-				return
-			}
+	t.l.Debugf("astiocr: creating label map to %s", p)
+	for idx, c := range t.charset {
+		if _, err = f.WriteString(fmt.Sprintf("item {\n  id: %d\n  name: '%s'\n}\n", idx+1, labelMapEscaper.Replace(string(c)))); err != nil {
+			err = errors.Wrapf(err, "astiocr: writing to %s failed", p)
+			return
 		}
 	}
 	return
 }
 
-func (t *Trainer) createImageStrategy1() (img *image.RGBA, si GatherSummaryImage) {
-	// Initialize parameters
-	fontSize, backgroundColor, fontColor, font := t.initParams()
+// labelIndex returns the label index of the character, or 0 if it's not in the charset
+func (t *Trainer) labelIndex(r rune) int {
+	for idx, c := range t.charset {
+		if c == r {
+			return idx + 1
+		}
+	}
+	return 0
+}
 
-	// Get coordinates
-	x0, x1, y0, y1 := 0, int(float64(fontSize)*1.5), 0, int(float64(fontSize)*1.5)
+func (t *Trainer) createImageStrategyCharacter(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
 
 	// Create image
-	img, si = t.createImage(backgroundColor, y1, x1)
+	size := int(float64(fontSize) * 1.5)
+	img, si = t.createImage(backgroundColor, font, size, size)
 
 	// Draw character
-	char, charIdx := t.drawCharacter(img, fontColor, font, fontSize, int(float64(fontSize)*0.3), int(float64(fontSize)*1.3))
+	charIdx := rand.Intn(len(t.charset))
+	char, b := t.drawCharacter(img, fontColor, font, fontSize, int(float64(fontSize)*0.3), int(float64(fontSize)*1.3), charIdx, &si)
+	if b.Empty() {
+		return
+	}
 
 	// Add box to summary
 	si.Boxes = append(si.Boxes, GatherSummaryBox{
+		FontSize:   fontSize,
 		Label:      string(char),
 		LabelIndex: charIdx + 1,
-		X0:         x0,
-		X1:         x1,
-		Y0:         y0,
-		Y1:         y1,
+		X0:         b.Min.X,
+		X1:         b.Max.X,
+		Y0:         b.Min.Y,
+		Y1:         b.Max.Y,
 	})
 	return
 }
 
-func (t *Trainer) createImageStrategy2() (img *image.RGBA, si GatherSummaryImage) {
+func (t *Trainer) createImageStrategyGrid(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
 	// Initialize parameters
-	fontSize, backgroundColor, fontColor, font := t.initParams()
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
 	coverage := rand.Intn(50)
 
 	// Create image
-	img, si = t.createImage(backgroundColor, t.image.Height, t.image.Width)
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
 
 	// Draw characters
 	t.drawCharacters(fontSize, coverage, img, fontColor, &si, font)
@@ -207,7 +409,7 @@ func (t *Trainer) createImageStrategy2() (img *image.RGBA, si GatherSummaryImage
 }
 
 func (t *Trainer) initParams() (fontSize int, backgroundColor, fontColor color.RGBA, font *font) {
-	fontSize = rand.Intn(6) + 12
+	fontSize = t.randomFontSize()
 	cc := t.colors[0]
 	if len(t.colors) > 1 {
 		cc = t.colors[rand.Intn(len(t.colors)-1)]
@@ -219,21 +421,58 @@ func (t *Trainer) initParams() (fontSize int, backgroundColor, fontColor color.R
 	}
 	font = t.fonts[0]
 	if len(t.fonts) > 1 {
-		font = t.fonts[rand.Intn(len(t.fonts)-1)]
+		font = t.fonts[rand.Intn(len(t.fonts))]
 	}
 	return
 }
 
-func (t *Trainer) createImage(backgroundColor color.Color, height, width int) (img *image.RGBA, si GatherSummaryImage) {
+// Font size distributions
+const (
+	fontSizeDistributionLogUniform = "log_uniform"
+	fontSizeDistributionNormal     = "normal"
+	fontSizeDistributionUniform    = "uniform"
+)
+
+// randomFontSize returns a random font size inside the configured range following the configured
+// distribution
+func (t *Trainer) randomFontSize() int {
+	min, max := float64(t.fontSize.Min), float64(t.fontSize.Max)
+	switch t.fontSize.Distribution {
+	case fontSizeDistributionLogUniform:
+		return int(math.Min(max, math.Floor(min*math.Exp(rand.Float64()*math.Log((max+1)/min)))))
+	case fontSizeDistributionNormal:
+		// Almost all sizes are within 3 standard deviations of the middle of the range
+		return int(math.Max(min, math.Min(max, math.Round((min+max)/2+rand.NormFloat64()*(max-min)/6))))
+	default:
+		return rand.Intn(t.fontSize.Max-t.fontSize.Min+1) + t.fontSize.Min
+	}
+}
+
+func (t *Trainer) createImage(backgroundColor color.RGBA, font *font, height, width int) (img *image.RGBA, si GatherSummaryImage) {
 	// Create image
+	img, si = t.newImage(font, height, width)
+
+	// Draw background
+	t.drawBackground(img, backgroundColor, &si)
+	return
+}
+
+// newImage creates an empty image and its summary, picking the effects applied to its text
+func (t *Trainer) newImage(font *font, height, width int) (img *image.RGBA, si GatherSummaryImage) {
 	img = image.NewRGBA(image.Rect(0, 0, width, height))
 	si = GatherSummaryImage{
 		Height: height,
 		Width:  width,
 	}
-
-	// Draw background
-	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.ZP, draw.Src)
+	if t.fontMixing == fontMixingNone {
+		si.Font, si.Style = font.name, font.style
+	}
+	if t.rotates() {
+		si.Angle = t.randomImageAngle()
+	}
+	si.Outline = t.randomOutline()
+	si.Shadow = t.randomShadow()
+	si.TextOpacity = t.randomTextOpacity()
 	return
 }
 
@@ -255,31 +494,113 @@ func (t *Trainer) drawCharacters(fontSize, coverage int, img *image.RGBA, fontCo
 				continue
 			}
 
-			// Draw character
-			char, charIdx := t.drawCharacter(img, fontColor, font, fontSize, col, row)
+			// Draw mirrored character
+			cf := t.characterFont(font)
+			if t.mirroredProportion > 0 && rand.Float64()*100 < t.mirroredProportion {
+				t.drawMirroredCharacter(img, fontColor, cf, fontSize, col, row, image.Rect(x0, y0, x1, y1), si)
+				continue
+			}
+
+			// Draw hard negative
+			charIdx, charCol := -1, col
+			if t.hardNegatives.Proportion > 0 && rand.Float64()*100 < t.hardNegatives.Proportion {
+				// Hard negative replaces the cell
+				if t.hardNegatives.Placement == hardNegativePlacementCell {
+					t.drawHardNegative(img, fontColor, cf, fontSize, col, row, si)
+					continue
+				}
 
-			// Only parse "e" letters for now
-			if char == "E" || char == "e" {
-				// Show box
-				if t.showBox && !t.showGrid {
-					t.drawBox(x0, x1, y0, y1, img, fontColor)
+				// Hard negative and the character it looks like are drawn in this cell and the next one, in
+				// a random order. A regular character is drawn if there is no next cell.
+				if col+2*step < t.image.Width {
+					negativeCol := col + step
+					if rand.Intn(2) == 0 {
+						charCol, negativeCol = negativeCol, col
+					}
+					if c := t.drawHardNegative(img, fontColor, cf, fontSize, negativeCol, row, si); c != 0 {
+						charIdx = t.hardNegativeTarget(c)
+						if t.showGrid {
+							t.drawBox(x1, x1+step, y0, y1, img, fontColor)
+						}
+						col += step
+					} else {
+						charCol = col
+					}
 				}
+			}
+
+			// Draw character
+			if charIdx < 0 {
+				charIdx = rand.Intn(len(t.charset))
+			}
+			var angle float64
+			var b image.Rectangle
+			var char string
+			if t.rotates() {
+				char, b, angle = t.drawRotatedCharacter(img, fontColor, cf, fontSize, charCol, row, charIdx, si)
+			} else {
+				char, b = t.drawCharacter(img, fontColor, cf, fontSize, charCol, row, charIdx, si)
+			}
+			if b.Empty() {
+				continue
+			}
 
-				// Add box to summary
-				si.Boxes = append(si.Boxes, GatherSummaryBox{
-					Label:      string(char),
-					LabelIndex: charIdx + 1,
-					X0:         x0,
-					X1:         x1,
-					Y0:         y0,
-					Y1:         y1,
-				})
+			// Show box
+			if t.showBox && !t.showGrid {
+				t.drawBox(b.Min.X, b.Max.X, b.Min.Y, b.Max.Y, img, fontColor)
 			}
+
+			// Add box to summary
+			sb := t.summaryBox(char, charIdx+1, fontSize, b, cf)
+			sb.Angle = angle
+			si.Boxes = append(si.Boxes, sb)
 		}
 	}
 	return
 }
 
+// summaryBox creates a summary box, recording the font of the character when fonts are mixed
+func (t *Trainer) summaryBox(label string, labelIndex, fontSize int, b image.Rectangle, f *font) (sb GatherSummaryBox) {
+	sb = GatherSummaryBox{
+		FontSize:   fontSize,
+		Label:      label,
+		LabelIndex: labelIndex,
+		X0:         b.Min.X,
+		X1:         b.Max.X,
+		Y0:         b.Min.Y,
+		Y1:         b.Max.Y,
+	}
+	if t.fontMixing != fontMixingNone {
+		sb.Font, sb.Style = f.name, f.style
+	}
+	return
+}
+
+// jitterBoxes randomly moves the edges of all the boxes of a proportion of the images while keeping them
+// inside the image
+func (t *Trainer) jitterBoxes(si *GatherSummaryImage) {
+	// Check proportion
+	if rand.Float64()*100 >= t.boxJitter.Proportion {
+		return
+	}
+	si.BoxJitter = true
+
+	// Loop through boxes
+	jitter := func(v, size, min, max int) int {
+		d := int(math.Round((rand.Float64()*2 - 1) * t.boxJitter.Max * float64(size)))
+		return int(math.Max(float64(min), math.Min(float64(max), float64(v+d))))
+	}
+	for idx := range si.Boxes {
+		// Jitter edges while making sure the box doesn't collapse
+		b := &si.Boxes[idx]
+		w, h := b.X1-b.X0, b.Y1-b.Y0
+		b.X0 = jitter(b.X0, w, 0, b.X1-1)
+		b.X1 = jitter(b.X1, w, b.X0+1, si.Width)
+		b.Y0 = jitter(b.Y0, h, 0, b.Y1-1)
+		b.Y1 = jitter(b.Y1, h, b.Y0+1, si.Height)
+	}
+}
+
 func (t *Trainer) drawBox(x0, x1, y0, y1 int, img draw.Image, c color.Color) {
 	borderTop := image.Rect(x0, y0, x1, y0+1)
 	borderRight := image.Rect(x1, y0, x1+1, y1)
@@ -291,29 +612,137 @@ func (t *Trainer) drawBox(x0, x1, y0, y1 int, img draw.Image, c color.Color) {
 	draw.Draw(img, borderLeft, &image.Uniform{c}, image.ZP, draw.Src)
 }
 
-func (t *Trainer) drawCharacter(img draw.Image, fontColor color.Color, font *font, fontSize, col, row int) (char string, charIdx int) {
+func (t *Trainer) drawCharacter(img draw.Image, fontColor color.Color, font *font, fontSize, col, row, charIdx int, si *GatherSummaryImage) (char string, b image.Rectangle) {
 	// Get character
-	charIdx = rand.Intn(len(characters) - 1)
-	char = string(characters[charIdx])
+	char = string(t.charset[charIdx])
 
 	// Draw character
+	b = t.drawString(img, fontColor, font, fontSize, col, row, char, si)
+	return
+}
+
+// drawString draws the string, with the effects of the summary image if any, and returns the tight box of
+// its glyphs, computed from the font metrics and clipped to the image
+func (t *Trainer) drawString(img draw.Image, fontColor color.Color, font *font, fontSize, col, row int, s string, si *GatherSummaryImage) image.Rectangle {
 	d := &ft.Drawer{
-		Dst: img,
-		Src: image.NewUniform(fontColor),
-		Face: truetype.NewFace(font.font, &truetype.Options{
-			DPI:  72,
-			Size: float64(fontSize),
-		}),
-		Dot: fixed.P(col+int(float64(fontSize)/2.0/font.positionRatio), row-int(float64(fontSize)/2.0/font.positionRatio)),
-	}
-	d.DrawString(char)
+		Dst:  img,
+		Src:  image.NewUniform(inkColor(fontColor, si)),
+		Face: newFace(font, fontSize),
+		Dot:  characterDot(font, fontSize, col, row),
+	}
+	b, _ := d.BoundString(s)
+	drawStringEffects(d, s, si)
+	d.DrawString(s)
+	return glyphRect(b).Intersect(img.Bounds())
+}
+
+// characterDot returns the dot of a character drawn in the grid cell whose bottom left corner is (col, row)
+func characterDot(font *font, fontSize, col, row int) fixed.Point26_6 {
+	return fixed.P(col+int(float64(fontSize)/2.0/font.positionRatio), row-int(float64(fontSize)/2.0/font.positionRatio))
+}
+
+// newFace creates a face of the font at the size, applying its synthetic style if any
+func newFace(f *font, fontSize int) ft.Face {
+	// Error is always nil since options are provided
+	size := float64(fontSize)
+	if f.pixelation > 1 {
+		size /= float64(f.pixelation)
+	}
+	var fc ft.Face
+	fc, _ = opentype.NewFace(f.font, &opentype.FaceOptions{
+		DPI:     72,
+		Hinting: f.hinting,
+		Size:    size,
+	})
+	if f.bold || f.oblique {
+		fc = newStyledFace(fc, f.bold, f.oblique)
+	}
+	if f.aliased {
+		fc = aliasedFace{Face: fc}
+	}
+	if f.pixelation > 1 {
+		fc = pixelatedFace{Face: fc, factor: f.pixelation}
+	}
+	return fc
+}
+
+// glyphRect converts glyph bounds to the smallest rectangle containing them
+func glyphRect(b fixed.Rectangle26_6) image.Rectangle {
+	return image.Rect(b.Min.X.Floor(), b.Min.Y.Floor(), b.Max.X.Ceil(), b.Max.Y.Ceil())
+}
+
+// Mirror flips
+const (
+	mirrorFlipBoth = iota
+	mirrorFlipHorizontal
+	mirrorFlipVertical
+)
+
+// mirrorLookalikes indexes, per flip, the characters whose flipped glyph looks like a character, since
+// most fonts draw them symmetrically or as mirrors of each other
+var mirrorLookalikes = map[int]map[rune]rune{
+	mirrorFlipBoth:       newMirrorLookalikes("%+-:=HINOSXZlosxz08", "69", "bq", "dp", "nu", "MW", "mw", "()", "[]", "{}", "<>", "',"),
+	mirrorFlipHorizontal: newMirrorLookalikes("!\"'*+-.:=AHIMOTUVWXYilmnouvwx08^_|", "bd", "pq", "()", "[]", "{}", "<>", `/\`),
+	mirrorFlipVertical:   newMirrorLookalikes("+-:=BCDEHIKOXclox038", "bp", "dq", "MW", "mw", "nu", "',"),
+}
+
+// newMirrorLookalikes maps symmetric characters to themselves and pairs of characters to each other
+func newMirrorLookalikes(symmetric string, pairs ...string) (m map[rune]rune) {
+	m = make(map[rune]rune)
+	for _, r := range symmetric {
+		m[r] = r
+	}
+	for _, p := range pairs {
+		rs := []rune(p)
+		m[rs[0]], m[rs[1]] = rs[1], rs[0]
+	}
 	return
 }
 
-func (t *Trainer) storeImage(idx int, img *image.RGBA) (p string, err error) {
+// drawMirroredCharacter draws a character flipped horizontally, vertically or both inside the cell.
+// Characters whose flipped glyph looks like a charset character are not drawn since they're not negatives.
+func (t *Trainer) drawMirroredCharacter(img draw.Image, fontColor color.Color, font *font, fontSize, col, row int, cell image.Rectangle, si *GatherSummaryImage) {
+	// Get flip
+	flip := rand.Intn(3)
+	flipX, flipY := flip != mirrorFlipVertical, flip != mirrorFlipHorizontal
+
+	// Get characters whose flipped glyph doesn't look like a charset character
+	var cs []rune
+	for _, c := range t.charset {
+		if l, ok := mirrorLookalikes[flip][c]; !ok || t.labelIndex(l) == 0 {
+			cs = append(cs, c)
+		}
+	}
+	if len(cs) == 0 {
+		return
+	}
+
+	// Draw character on a transparent image
+	src := image.NewRGBA(cell)
+	t.drawString(src, fontColor, font, fontSize, col, row, string(cs[rand.Intn(len(cs))]), si)
+
+	// Flip
+	dst := image.NewRGBA(cell)
+	for y := cell.Min.Y; y < cell.Max.Y; y++ {
+		for x := cell.Min.X; x < cell.Max.X; x++ {
+			sx, sy := x, y
+			if flipX {
+				sx = cell.Max.X - 1 - (x - cell.Min.X)
+			}
+			if flipY {
+				sy = cell.Max.Y - 1 - (y - cell.Min.Y)
+			}
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	// Draw
+	draw.Draw(img, cell, dst, cell.Min, draw.Over)
+}
+
+func storePNG(p string, img image.Image) (err error) {
 	// Create file
 	var f *os.File
-	p = filepath.Join(t.outputDataDirectoryPath, "images", fmt.Sprintf("%d.png", idx+1))
 	if f, err = os.Create(p); err != nil {
 		err = errors.Wrapf(err, "astiocr: creating %s failed", p)
 		return
@@ -341,6 +770,25 @@ func (t *Trainer) writeSummaries(summaryTraining, summaryTest GatherSummary) (er
 	return
 }
 
+func (t *Trainer) writeManifest(m GatherManifest) (err error) {
+	// Create file
+	var f *os.File
+	p := filepath.Join(t.outputDataDirectoryPath, "manifest.json")
+	if f, err = os.Create(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: creating %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Write manifest
+	t.l.Debugf("astiocr: writing manifest to %s", p)
+	if err = json.NewEncoder(f).Encode(m); err != nil {
+		err = errors.Wrap(err, "astiocr: writing manifest failed")
+		return
+	}
+	return
+}
+
 func (t *Trainer) writeSummary(s GatherSummary, p string) (err error) {
 	// Create file
 	var f *os.File
@@ -351,7 +799,7 @@ func (t *Trainer) writeSummary(s GatherSummary, p string) (err error) {
 	defer f.Close()
 
 	// Write summary
-	astilog.Debugf("astiocr: writing summary to %s", p)
+	t.l.Debugf("astiocr: writing summary to %s", p)
 	if err = json.NewEncoder(f).Encode(s); err != nil {
 		err = errors.Wrap(err, "astiocr: writing summary failed")
 		return
@@ -359,10 +807,10 @@ func (t *Trainer) writeSummary(s GatherSummary, p string) (err error) {
 	return
 }
 
-func (t *Trainer) prepareData(ctx context.Context) (err error) {
-	cmd := exec.CommandContext(ctx, t.pythonBinaryPath, filepath.Join(t.scriptsDirectoryPath, "prepare_data.py"), "--data_directory_path", t.outputDataDirectoryPath)
+func (t *Trainer) prepareData(ctx context.Context, dataDirectoryPath string) (err error) {
+	cmd := exec.CommandContext(ctx, t.pythonBinaryPath, filepath.Join(t.scriptsDirectoryPath, "prepare_data.py"), "--data_directory_path", dataDirectoryPath)
 	var b []byte
-	astilog.Debugf("astiocr: executing <%s>", strings.Join(cmd.Args, " "))
+	t.l.Debugf("astiocr: executing <%s>", strings.Join(cmd.Args, " "))
 	if b, err = cmd.CombinedOutput(); err != nil {
 		err = errors.Wrapf(err, "astiocr: running %s failed with body %s", strings.Join(cmd.Args, " "), b)
 		return
@@ -0,0 +1,263 @@
+package astiocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Google fonts developer API endpoint listing font families
+const googleFontsAPIURL = "https://www.googleapis.com/webfonts/v1/webfonts"
+
+// Default maximum number of families downloaded per category
+const googleFontsDefaultMaxFamiliesPerCategory = 10
+
+// ConfigurationGoogleFonts represents a google fonts configuration
+// Downloaded fonts are stored in the cache directory and used in addition to the configured fonts.
+type ConfigurationGoogleFonts struct {
+	// Key of the google fonts developer API. It's only needed when fonts are not cached yet.
+	APIKey string `toml:"api_key"`
+
+	// Categories whose most popular families are downloaded: "display", "handwriting", "monospace",
+	// "sans-serif" or "serif"
+	Categories []string `toml:"categories"`
+
+	// Families to download (e.g. "Roboto")
+	Families []string `toml:"families"`
+
+	// Maximum number of families downloaded per category. Default is 10.
+	MaxFamiliesPerCategory int `toml:"max_families_per_category"`
+
+	// Variants to download (e.g. "regular", "italic", "700"). Default is "regular".
+	Variants []string `toml:"variants"`
+}
+
+type googleFontsList struct {
+	Items []googleFontsFamily `json:"items"`
+}
+
+type googleFontsFamily struct {
+	Category string            `json:"category"`
+	Family   string            `json:"family"`
+	Files    map[string]string `json:"files"`
+}
+
+func (c ConfigurationGoogleFonts) variants() []string {
+	if len(c.Variants) == 0 {
+		return []string{"regular"}
+	}
+	return c.Variants
+}
+
+func (t *Trainer) googleFontsDirectoryPath() string {
+	return filepath.Join(t.cacheDirectoryPath, "google_fonts")
+}
+
+func (t *Trainer) googleFontPath(family, variant string) string {
+	return filepath.Join(t.googleFontsDirectoryPath(), strings.Replace(family, " ", "", -1)+"-"+variant+".ttf")
+}
+
+// googleFontVariant returns the variant of a google font based on its path
+func googleFontVariant(p string) string {
+	b := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	return b[strings.LastIndex(b, "-")+1:]
+}
+
+// DownloadGoogleFonts downloads the configured google fonts to the cache directory, unless they're already
+// cached, and returns their paths
+func (t *Trainer) DownloadGoogleFonts(ctx context.Context) (ps []string, err error) {
+	// Nothing to download
+	if len(t.googleFonts.Families) == 0 && len(t.googleFonts.Categories) == 0 {
+		return
+	}
+
+	// Families are all cached, in which case the API is not needed
+	if len(t.googleFonts.Categories) == 0 {
+		cached := true
+		for _, f := range t.googleFonts.Families {
+			for _, v := range t.googleFonts.variants() {
+				p := t.googleFontPath(f, v)
+				if _, errStat := os.Stat(p); errStat != nil {
+					cached = false
+				} else {
+					ps = append(ps, p)
+				}
+			}
+		}
+		if cached {
+			return
+		}
+		ps = nil
+	}
+
+	// Create dir
+	if err = os.MkdirAll(t.googleFontsDirectoryPath(), 0755); err != nil {
+		err = errors.Wrapf(err, "astiocr: mkdirall %s failed", t.googleFontsDirectoryPath())
+		return
+	}
+
+	// List families
+	var l googleFontsList
+	if l, err = t.listGoogleFonts(ctx); err != nil {
+		err = errors.Wrap(err, "astiocr: listing google fonts failed")
+		return
+	}
+
+	// Loop through families
+	for _, f := range t.selectGoogleFonts(l) {
+		for _, v := range t.googleFonts.variants() {
+			// Variant doesn't exist
+			u, ok := f.Files[v]
+			if !ok {
+				t.l.Warnf("astiocr: google font %s has no variant %s", f.Family, v)
+				continue
+			}
+
+			// Download
+			p := t.googleFontPath(f.Family, v)
+			if _, errStat := os.Stat(p); errStat != nil {
+				t.l.Debugf("astiocr: downloading google font %s %s to %s", f.Family, v, p)
+				if err = downloadResume(ctx, t.l, strings.Replace(u, "http://", "https://", 1), p+".part"); err != nil {
+					err = errors.Wrapf(err, "astiocr: downloading %s failed", u)
+					return
+				}
+				if err = os.Rename(p+".part", p); err != nil {
+					err = errors.Wrapf(err, "astiocr: renaming %s.part to %s failed", p, p)
+					return
+				}
+			}
+			ps = append(ps, p)
+		}
+	}
+	return
+}
+
+func (t *Trainer) listGoogleFonts(ctx context.Context) (l googleFontsList, err error) {
+	// No API key
+	if len(t.googleFonts.APIKey) == 0 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: google fonts api key is missing"))
+		return
+	}
+
+	// Create request
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, googleFontsAPIURL+"?"+url.Values{
+		"key":  []string{t.googleFonts.APIKey},
+		"sort": []string{"popularity"},
+	}.Encode(), nil); err != nil {
+		err = errors.Wrap(err, "astiocr: creating request failed")
+		return
+	}
+	req = req.WithContext(ctx)
+
+	// Send request
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		err = errors.Wrap(err, "astiocr: sending request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	// Process status code
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		err = fmt.Errorf("astiocr: invalid status code %d: %s", resp.StatusCode, b)
+		return
+	}
+
+	// Unmarshal
+	if err = json.NewDecoder(resp.Body).Decode(&l); err != nil {
+		err = errors.Wrap(err, "astiocr: unmarshaling failed")
+		return
+	}
+	return
+}
+
+// selectGoogleFonts returns the configured families and the most popular families of the configured
+// categories. Families are listed by popularity.
+func (t *Trainer) selectGoogleFonts(l googleFontsList) (fs []googleFontsFamily) {
+	// Get max families per category
+	max := t.googleFonts.MaxFamiliesPerCategory
+	if max == 0 {
+		max = googleFontsDefaultMaxFamiliesPerCategory
+	}
+
+	// Index configuration
+	families := make(map[string]bool)
+	for _, f := range t.googleFonts.Families {
+		families[strings.ToLower(f)] = true
+	}
+	categories := make(map[string]int)
+	for _, c := range t.googleFonts.Categories {
+		categories[strings.ToLower(c)] = 0
+	}
+
+	// Loop through families
+	for _, f := range l.Items {
+		// Family is configured
+		if families[strings.ToLower(f.Family)] {
+			delete(families, strings.ToLower(f.Family))
+			fs = append(fs, f)
+			continue
+		}
+
+		// Category is configured
+		if n, ok := categories[f.Category]; ok && n < max {
+			categories[f.Category]++
+			fs = append(fs, f)
+		}
+	}
+
+	// Some families don't exist
+	for f := range families {
+		t.l.Warnf("astiocr: google font %s doesn't exist", f)
+	}
+	return
+}
+
+// addGoogleFonts downloads the google fonts and adds those containing the glyphs of all the charset
+// characters to the fonts
+func (t *Trainer) addGoogleFonts(ctx context.Context) (err error) {
+	// Download
+	var ps []string
+	if ps, err = t.DownloadGoogleFonts(ctx); err != nil {
+		err = errors.Wrap(err, "astiocr: downloading google fonts failed")
+		return
+	}
+
+	// Loop through paths
+	for _, p := range ps {
+		// Read file
+		f := &font{
+			name:          p,
+			positionRatio: 2.5,
+			style:         googleFontVariant(p),
+		}
+		if f.body, err = ioutil.ReadFile(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: reading %s failed", p)
+			return
+		}
+
+		// Parse
+		if f.font, err = parseFont(f.body); err != nil {
+			err = errors.Wrapf(err, "astiocr: parsing font %s failed", p)
+			return
+		}
+
+		// Font doesn't contain the glyphs of all the charset characters
+		if missing := t.missingGlyphs(f); len(missing) > 0 {
+			t.l.Warnf("astiocr: skipping google font %s since it has no glyph for %q", p, missing)
+			continue
+		}
+		t.fonts = append(t.fonts, f)
+	}
+	return
+}
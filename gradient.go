@@ -0,0 +1,134 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/asticode/go-astitools/image"
+)
+
+// Gradient kinds
+const (
+	gradientKindLinear = "linear"
+	gradientKindRadial = "radial"
+)
+
+// ConfigurationGradient represents a gradient backgrounds configuration
+// The background of the proportion of images is a linear gradient, whose direction is random, or a radial
+// gradient, whose center is random, like broadcast graphics and UI panels. Its color stops are those of a
+// random palette. Default palette goes from the image background color to a contrasting version of it.
+type ConfigurationGradient struct {
+	// "linear" or "radial". Default is both.
+	Kinds      []string                       `toml:"kinds"`
+	Palettes   []ConfigurationGradientPalette `toml:"palettes"`
+	Proportion float64                        `toml:"proportion"`
+}
+
+// ConfigurationGradientPalette represents the color stops of a gradient
+type ConfigurationGradientPalette struct {
+	Colors []astiimage.RGBA `toml:"colors"`
+	// Position of each color along the gradient, increasing from 0 to 1. Default is evenly spaced.
+	Offsets []float64 `toml:"offsets"`
+}
+
+// gradientStop represents a color stop of a gradient
+type gradientStop struct {
+	c      color.RGBA
+	offset float64
+}
+
+// parseGradientPalette returns the color stops of the palette
+func parseGradientPalette(p ConfigurationGradientPalette) (ss []gradientStop, err error) {
+	// Check colors
+	if len(p.Colors) < 2 || (len(p.Offsets) > 0 && len(p.Offsets) != len(p.Colors)) {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: gradient palette has %d colors and %d offsets", len(p.Colors), len(p.Offsets)))
+		return
+	}
+
+	// Loop through colors
+	for idx, c := range p.Colors {
+		s := gradientStop{c: c.RGBA}
+		if len(p.Offsets) > 0 {
+			s.offset = p.Offsets[idx]
+		} else {
+			s.offset = float64(idx) / float64(len(p.Colors)-1)
+		}
+		if s.offset < 0 || s.offset > 1 || (idx > 0 && s.offset < ss[idx-1].offset) {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid gradient offsets %v", p.Offsets))
+			return
+		}
+		ss = append(ss, s)
+	}
+	return
+}
+
+// defaultGradientStops returns stops going from the color to a version of it that is mixed with black if
+// it's light, or with white if it's dark
+func defaultGradientStops(c color.RGBA) []gradientStop {
+	o := color.RGBA{A: 0xff}
+	if luminance(c) < 0.5 {
+		o = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	return []gradientStop{{c: c}, {c: mixColors(c, o, 0.5), offset: 1}}
+}
+
+// mixColors returns the color located at the position, between 0 and 1, between a and b
+func mixColors(a, b color.RGBA, pos float64) color.RGBA {
+	mix := func(a, b uint8) uint8 { return uint8(math.Round(float64(a) + (float64(b)-float64(a))*pos)) }
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+// gradientColor returns the color of the gradient at the position, between 0 and 1
+func gradientColor(ss []gradientStop, pos float64) color.RGBA {
+	if pos <= ss[0].offset {
+		return ss[0].c
+	}
+	for idx := 1; idx < len(ss); idx++ {
+		if pos <= ss[idx].offset {
+			a, b := ss[idx-1], ss[idx]
+			if b.offset == a.offset {
+				return b.c
+			}
+			return mixColors(a.c, b.c, (pos-a.offset)/(b.offset-a.offset))
+		}
+	}
+	return ss[len(ss)-1].c
+}
+
+// drawGradient covers the image with a random gradient and returns its kind
+func (t *Trainer) drawGradient(img *image.RGBA, backgroundColor color.RGBA) (kind string) {
+	// Pick stops
+	ss := defaultGradientStops(backgroundColor)
+	if len(t.gradientStops) > 0 {
+		ss = t.gradientStops[rand.Intn(len(t.gradientStops))]
+	}
+
+	// Get position of each pixel along the gradient
+	var pos func(x, y float64) float64
+	r := img.Bounds()
+	w, h := float64(r.Dx()), float64(r.Dy())
+	switch kind = t.gradient.Kinds[rand.Intn(len(t.gradient.Kinds))]; kind {
+	case gradientKindRadial:
+		// The gradient ends at the farthest corner
+		cx, cy := rand.Float64()*w, rand.Float64()*h
+		radius := math.Hypot(math.Max(cx, w-cx), math.Max(cy, h-cy))
+		pos = func(x, y float64) float64 { return math.Hypot(x-cx, y-cy) / radius }
+	default:
+		// The gradient goes from the corner where the projection is the lowest to the opposite corner
+		sin, cos := math.Sincos(rand.Float64() * 2 * math.Pi)
+		min := math.Min(0, cos*w) + math.Min(0, sin*h)
+		length := math.Abs(cos*w) + math.Abs(sin*h)
+		pos = func(x, y float64) float64 { return (x*cos + y*sin - min) / length }
+	}
+
+	// Loop through pixels
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, gradientColor(ss, pos(float64(x-r.Min.X)+0.5, float64(y-r.Min.Y)+0.5)))
+		}
+	}
+	return
+}
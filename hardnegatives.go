@@ -0,0 +1,137 @@
+package astiocr
+
+import (
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"strings"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Hard negatives placements
+const (
+	hardNegativePlacementCell   = "cell"
+	hardNegativePlacementTarget = "target"
+)
+
+// ConfigurationHardNegatives represents a hard negatives configuration
+// Hard negatives are characters that look like charset characters without being in the charset. The
+// proportion of characters drawn by the "grid" strategy are hard negatives, which are not labeled so that
+// the model learns to tell them apart from the characters they look like.
+type ConfigurationHardNegatives struct {
+	// Default is the characters looking like the charset characters that are not in the charset
+	Characters string `toml:"characters"`
+	// Either "cell", where hard negatives replace grid cells, or "target", where hard negatives are drawn
+	// in the cell next to a labeled character they look like. Default is "cell".
+	Placement  string  `toml:"placement"`
+	Proportion float64 `toml:"proportion"`
+}
+
+// confusables are characters looking like characters of the default charset, indexed by the character they
+// look like. Characters that are drawn identically or almost identically in most fonts, such as '-' and '–',
+// are left out since they can't be told apart.
+var confusables = map[rune]string{
+	'!': "¡|",
+	'$': "§S",
+	'%': "‰",
+	'(': "[{<",
+	')': "]}>",
+	',': "'",
+	'-': "—_~¬",
+	'.': "·•",
+	'/': "\\|",
+	'0': "OoQDθØø",
+	'1': "lI!ı¹",
+	'2': "Zz²",
+	'3': "³B8",
+	'5': "S§s",
+	'6': "bG",
+	'7': "T/",
+	'8': "B&",
+	'9': "gq",
+	':': ";÷",
+	'?': "¿",
+	'B': "ß8β",
+	'C': "(¢©",
+	'E': "€£",
+	'L': "£",
+	'O': "0QØ",
+	'P': "Þ",
+	'S': "§5$",
+	'Y': "¥",
+	'a': "@α",
+	'c': "(¢©",
+	'e': "€ε",
+	'i': "¡ı",
+	'l': "1",
+	'n': "η",
+	'o': "º0σ",
+	'p': "þ",
+	'u': "µ",
+	'w': "ω",
+	'x': "×χ",
+	'y': "¥γ",
+	'€': "ε",
+}
+
+// defaultHardNegatives returns the characters looking like the charset characters that are not in the
+// charset, in charset order
+func defaultHardNegatives(charset []rune) (s string) {
+	m := make(map[rune]bool)
+	for _, c := range charset {
+		m[c] = true
+	}
+	for _, c := range charset {
+		for _, n := range confusables[c] {
+			if !m[n] {
+				m[n] = true
+				s += string(n)
+			}
+		}
+	}
+	return
+}
+
+// hardNegativeTarget returns the charset index of a random character the hard negative looks like, or of
+// a random character if there is none
+func (t *Trainer) hardNegativeTarget(c rune) int {
+	var idxs []int
+	for idx, r := range t.charset {
+		if strings.ContainsRune(confusables[r], c) {
+			idxs = append(idxs, idx)
+		}
+	}
+	if len(idxs) == 0 {
+		return rand.Intn(len(t.charset))
+	}
+	return idxs[rand.Intn(len(idxs))]
+}
+
+// drawHardNegative draws a random hard negative the font has a glyph for at the same position as
+// drawCharacter and returns it, or 0 if there is none. Nothing is labeled.
+func (t *Trainer) drawHardNegative(img draw.Image, fontColor color.Color, font *font, fontSize, col, row int, si *GatherSummaryImage) (c rune) {
+	// Get characters the font has a glyph for
+	var buf sfnt.Buffer
+	var cs []rune
+	for _, c := range t.hardNegatives.Characters {
+		if i, err := font.font.GlyphIndex(&buf, c); err == nil && i != 0 {
+			cs = append(cs, c)
+		}
+	}
+	if len(cs) == 0 {
+		return
+	}
+	c = cs[rand.Intn(len(cs))]
+
+	// Draw rotated character
+	if t.rotates() {
+		dot := characterDot(font, fontSize, col, row)
+		drawGlyph(img, t.rotatedGlyph(newFace(font, fontSize), font, c, float64(dot.X)/64, float64(dot.Y)/64, si), fontColor, si)
+		return
+	}
+
+	// Draw character
+	t.drawString(img, fontColor, font, fontSize, col, row, string(c), si)
+	return
+}
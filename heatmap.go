@@ -0,0 +1,94 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Heatmap renders, on top of a grayscale version of the image, the highest score of all the boxes returned
+// by the model for each pixel, including boxes below any threshold. Cold colors mean low scores whereas
+// warm colors mean high scores, which helps diagnosing why the model misses characters in some regions.
+// It relies on DetectRaw.
+func (d *Detector) Heatmap(ctx context.Context, model string, img image.Image) (o image.Image, err error) {
+	// Detect
+	var raw RawOutput
+	if _, raw, err = d.DetectRaw(ctx, model, img); err != nil {
+		err = errors.Wrap(err, "astiocr: detecting raw outputs failed")
+		return
+	}
+
+	// Loop through boxes
+	r := img.Bounds()
+	scores := make([]float64, r.Dx()*r.Dy())
+	for idx, b := range raw.Boxes {
+		// Get rectangle
+		s := float64(raw.Scores[idx])
+		br := DetectionBox{
+			X1: float64(b[1]),
+			X2: float64(b[3]),
+			Y1: float64(b[0]),
+			Y2: float64(b[2]),
+		}.Rect(r).Intersect(r)
+
+		// Keep the highest score
+		for y := br.Min.Y; y < br.Max.Y; y++ {
+			for x := br.Min.X; x < br.Max.X; x++ {
+				if i := (y-r.Min.Y)*r.Dx() + x - r.Min.X; s > scores[i] {
+					scores[i] = s
+				}
+			}
+		}
+	}
+
+	// Render
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Blend the heat color with the luminance
+			l := luminance(img.At(x, y)) * 255
+			h := heatColor(scores[(y-r.Min.Y)*r.Dx()+x-r.Min.X])
+			blend := func(v uint8) uint8 { return uint8(math.Round(0.4*l + 0.6*float64(v))) }
+			dst.SetRGBA(x-r.Min.X, y-r.Min.Y, color.RGBA{R: blend(h.R), G: blend(h.G), B: blend(h.B), A: 255})
+		}
+	}
+	o = dst
+	return
+}
+
+// heatColor maps a score between 0 and 1 to a color going from blue to red through green
+func heatColor(s float64) color.RGBA {
+	s = math.Max(0, math.Min(1, s))
+	if s < 0.5 {
+		return color.RGBA{G: uint8(s * 2 * 255), B: uint8((1 - s*2) * 255), A: 255}
+	}
+	return color.RGBA{R: uint8((s - 0.5) * 2 * 255), G: uint8((1 - (s-0.5)*2) * 255), A: 255}
+}
+
+// HeatmapFile renders the heatmap of the image located at src with the provided model and writes it as a
+// png to dst
+func (d *Detector) HeatmapFile(ctx context.Context, model, src, dst string) (err error) {
+	// Decode image
+	var img image.Image
+	if img, err = decodeImageFile(src); err != nil {
+		err = errors.Wrapf(err, "astiocr: decoding %s failed", src)
+		return
+	}
+
+	// Render
+	var h image.Image
+	if h, err = d.Heatmap(ctx, model, img); err != nil {
+		err = errors.Wrap(err, "astiocr: rendering heatmap failed")
+		return
+	}
+
+	// Store
+	if err = storePNG(dst, h); err != nil {
+		err = errors.Wrapf(err, "astiocr: storing %s failed", dst)
+		return
+	}
+	return
+}
@@ -0,0 +1,116 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// idleBackend unloads its underlying backend once it has been idle for too long, and transparently
+// reloads it on the next detection
+type idleBackend struct {
+	b        Backend
+	c        ConfigurationDetector
+	delay    time.Duration
+	inFlight int
+	l        Logger
+	lastUsed time.Time
+	m        *sync.Mutex
+	t        *time.Timer
+}
+
+func newIdleBackend(c ConfigurationDetector, delay time.Duration) (b *idleBackend, err error) {
+	// Create backend
+	b = &idleBackend{
+		c:        c,
+		delay:    delay,
+		l:        newLogger(c.Logger),
+		lastUsed: time.Now(),
+		m:        &sync.Mutex{},
+	}
+
+	// Load backend right away so that configuration errors are not delayed until the first detection
+	if b.b, err = newBackend(c); err != nil {
+		return
+	}
+	b.t = time.AfterFunc(delay, b.unload)
+	return
+}
+
+// Close implements the Backend interface
+func (b *idleBackend) Close() (err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.t.Stop()
+	if b.b != nil {
+		err = b.b.Close()
+		b.b = nil
+	}
+	return
+}
+
+// Detect implements the Backend interface
+func (b *idleBackend) Detect(ctx context.Context, img image.Image) (rs []DetectionResult, err error) {
+	// Acquire backend
+	var bk Backend
+	if bk, err = b.acquire(); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
+		return
+	}
+	defer b.release()
+
+	// Detect
+	return bk.Detect(ctx, img)
+}
+
+func (b *idleBackend) acquire() (bk Backend, err error) {
+	// Lock
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// Reload backend
+	if b.b == nil {
+		b.l.Debug("astiocr: reloading idle backend")
+		if b.b, err = newBackend(b.c); err != nil {
+			err = errors.Wrap(err, "astiocr: reloading backend failed")
+			return
+		}
+	}
+
+	// Update state
+	b.inFlight++
+	b.t.Stop()
+	bk = b.b
+	return
+}
+
+func (b *idleBackend) release() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.inFlight--
+	b.lastUsed = time.Now()
+	if b.inFlight == 0 {
+		b.t.Reset(b.delay)
+	}
+}
+
+func (b *idleBackend) unload() {
+	// Lock
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// Backend is in use, has already been unloaded or has been used since the timer fired
+	if b.inFlight > 0 || b.b == nil || time.Since(b.lastUsed) < b.delay {
+		return
+	}
+
+	// Unload
+	b.l.Debugf("astiocr: unloading backend idle for %s", time.Since(b.lastUsed))
+	if err := b.b.Close(); err != nil {
+		b.l.Error(errors.Wrap(err, "astiocr: closing idle backend failed"))
+	}
+	b.b = nil
+}
@@ -0,0 +1,92 @@
+package astiocr
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigurationIntegrity represents a file integrity configuration
+// Empty values disable the corresponding check.
+type ConfigurationIntegrity struct {
+	// Base64 encoded ed25519 public key used to check the signature
+	PublicKey string `toml:"public_key"`
+
+	// Hex encoded sha256 checksum of the file
+	SHA256 string `toml:"sha256"`
+
+	// Base64 encoded ed25519 signature of the file sha256 checksum. Signing the checksum rather than the
+	// file means big files don't need to be loaded in memory.
+	Signature string `toml:"signature"`
+}
+
+func (c ConfigurationIntegrity) enabled() bool {
+	return len(c.SHA256) > 0 || len(c.Signature) > 0
+}
+
+// verifyFile makes sure the file located at p has not been corrupted or tampered with
+func verifyFile(p string, c ConfigurationIntegrity) (err error) {
+	// Nothing to check
+	if !c.enabled() {
+		return
+	}
+
+	// Open file
+	var f *os.File
+	if f, err = os.Open(p); err != nil {
+		if os.IsNotExist(err) {
+			err = withKind(ErrModelNotFound, err)
+		}
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Compute checksum
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		err = errors.Wrapf(err, "astiocr: hashing %s failed", p)
+		return
+	}
+	sum := h.Sum(nil)
+
+	// Check checksum
+	if len(c.SHA256) > 0 && !strings.EqualFold(hex.EncodeToString(sum), c.SHA256) {
+		err = fmt.Errorf("astiocr: sha256 of %s is %x, expected %s", p, sum, c.SHA256)
+		return
+	}
+
+	// Check signature
+	if len(c.Signature) > 0 {
+		// Decode public key
+		var k []byte
+		if k, err = base64.StdEncoding.DecodeString(c.PublicKey); err != nil {
+			err = errors.Wrap(err, "astiocr: decoding public key failed")
+			return
+		} else if len(k) != ed25519.PublicKeySize {
+			err = fmt.Errorf("astiocr: public key size is %d, expected %d", len(k), ed25519.PublicKeySize)
+			return
+		}
+
+		// Decode signature
+		var s []byte
+		if s, err = base64.StdEncoding.DecodeString(c.Signature); err != nil {
+			err = errors.Wrap(err, "astiocr: decoding signature failed")
+			return
+		}
+
+		// Verify
+		if !ed25519.Verify(ed25519.PublicKey(k), sum, s) {
+			err = fmt.Errorf("astiocr: invalid signature of %s", p)
+			return
+		}
+	}
+	return
+}
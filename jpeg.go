@@ -0,0 +1,76 @@
+package astiocr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigurationJPEG represents a JPEG artifacts configuration
+// The proportion of images is encoded as JPEG, at a quality picked between quality min and quality max
+// (default is 10 and 40), and decoded back before being stored, which reproduces the macro-blocking
+// artifacts of streamed video.
+type ConfigurationJPEG struct {
+	Proportion float64 `toml:"proportion"`
+	QualityMax int     `toml:"quality_max"`
+	QualityMin int     `toml:"quality_min"`
+}
+
+// random returns the JPEG quality images are compressed with
+func (c ConfigurationJPEG) random() int {
+	return c.QualityMin + rand.Intn(c.QualityMax-c.QualityMin+1)
+}
+
+// compressJPEG replaces the image with its JPEG version at the quality. Alpha, which JPEG doesn't support, is
+// kept.
+func compressJPEG(img *image.RGBA, quality int) (err error) {
+	// Encode
+	buf := &bytes.Buffer{}
+	if err = jpeg.Encode(buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		err = errors.Wrap(err, "astiocr: encoding jpeg failed")
+		return
+	}
+
+	// Decode
+	var dec image.Image
+	if dec, err = jpeg.Decode(buf); err != nil {
+		err = errors.Wrap(err, "astiocr: decoding jpeg failed")
+		return
+	}
+
+	// Replace colors while keeping alpha. Channels can't exceed alpha since colors are alpha-premultiplied.
+	r := img.Bounds()
+	c := image.NewRGBA(r)
+	draw.Draw(c, r, dec, dec.Bounds().Min, draw.Src)
+	min := func(a, b uint8) uint8 {
+		if a < b {
+			return a
+		}
+		return b
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			o, n := img.RGBAAt(x, y), c.RGBAAt(x, y)
+			o.R, o.G, o.B = min(n.R, o.A), min(n.G, o.A), min(n.B, o.A)
+			img.SetRGBA(x, y, o)
+		}
+	}
+	return
+}
+
+// validate applies the default JPEG options and checks them
+func (c *ConfigurationJPEG) validate() (err error) {
+	if c.QualityMin == 0 && c.QualityMax == 0 {
+		c.QualityMin, c.QualityMax = 10, 40
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.QualityMin < 1 || c.QualityMax < c.QualityMin || c.QualityMax > 100 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid jpeg proportion %v with quality range [%d, %d]", c.Proportion, c.QualityMin, c.QualityMax))
+		return
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package astiocr
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type jsonDetectionBox DetectionBox
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Boxes whose coordinates are not ordered are
+// rejected.
+func (b *DetectionBox) UnmarshalJSON(data []byte) (err error) {
+	var j jsonDetectionBox
+	if err = json.Unmarshal(data, &j); err != nil {
+		return
+	}
+	if j.X1 > j.X2 || j.Y1 > j.Y2 {
+		err = fmt.Errorf("astiocr: invalid box coordinates %v, %v, %v, %v", j.X1, j.X2, j.Y1, j.Y2)
+		return
+	}
+	*b = DetectionBox(j)
+	return
+}
+
+type jsonDetectionResult DetectionResult
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Results whose probability is not between 0 and
+// 1 are rejected.
+func (r *DetectionResult) UnmarshalJSON(data []byte) (err error) {
+	var j jsonDetectionResult
+	if err = json.Unmarshal(data, &j); err != nil {
+		return
+	}
+	if j.Probability < 0 || j.Probability > 1 {
+		err = fmt.Errorf("astiocr: invalid probability %v", j.Probability)
+		return
+	}
+	*r = DetectionResult(j)
+	return
+}
@@ -0,0 +1,114 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// Lighting kinds
+const (
+	lightingKindGradient = "gradient"
+	lightingKindShadow   = "shadow"
+	lightingKindVignette = "vignette"
+)
+
+// lightingShadowSoftness is the width of the transition between cast shadows and lit areas, as a proportion
+// of the image diagonal
+const lightingShadowSoftness = 0.1
+
+// ConfigurationLighting represents a lighting configuration
+// The proportion of images is unevenly lit after rendering, like documents and screens, by a brightness
+// gradient in a random direction, a vignette or a soft shadow cast by a random straight edge, whose
+// strength is picked between strength min and strength max (default is 0.2 and 0.5).
+type ConfigurationLighting struct {
+	// "gradient", "shadow" or "vignette". Default is all of them.
+	Kinds       []string `toml:"kinds"`
+	Proportion  float64  `toml:"proportion"`
+	StrengthMax float64  `toml:"strength_max"`
+	StrengthMin float64  `toml:"strength_min"`
+}
+
+// GatherSummaryLighting represents the lighting applied to a summary image
+type GatherSummaryLighting struct {
+	Kind string `json:"kind"`
+	// Maximum proportion by which brightness is increased or decreased
+	Strength float64 `json:"strength"`
+}
+
+// random returns the lighting applied to images
+func (c ConfigurationLighting) random() *GatherSummaryLighting {
+	return &GatherSummaryLighting{
+		Kind:     c.Kinds[rand.Intn(len(c.Kinds))],
+		Strength: c.StrengthMin + rand.Float64()*(c.StrengthMax-c.StrengthMin),
+	}
+}
+
+// lightImage multiplies the brightness of each pixel of the image according to the lighting
+func lightImage(img *image.RGBA, l *GatherSummaryLighting) {
+	// Get brightness factor of each pixel
+	r := img.Bounds()
+	w, h := float64(r.Dx()), float64(r.Dy())
+	var factor func(x, y float64) float64
+	switch l.Kind {
+	case lightingKindShadow:
+		// The edge goes through a random point of the image in a random direction
+		px, py := rand.Float64()*w, rand.Float64()*h
+		sin, cos := math.Sincos(rand.Float64() * 2 * math.Pi)
+		softness := lightingShadowSoftness * math.Hypot(w, h)
+		factor = func(x, y float64) float64 {
+			d := math.Max(0, math.Min(1, ((x-px)*cos+(y-py)*sin)/softness+0.5))
+			return 1 - l.Strength*d*d*(3-2*d)
+		}
+	case lightingKindVignette:
+		cx, cy := w/2, h/2
+		max := math.Hypot(cx, cy)
+		factor = func(x, y float64) float64 {
+			d := math.Hypot(x-cx, y-cy) / max
+			return 1 - l.Strength*d*d
+		}
+	default:
+		// Brightness goes from 1 - strength to 1 + strength along a random direction
+		sin, cos := math.Sincos(rand.Float64() * 2 * math.Pi)
+		min := math.Min(0, cos*w) + math.Min(0, sin*h)
+		length := math.Abs(cos*w) + math.Abs(sin*h)
+		factor = func(x, y float64) float64 {
+			return 1 + l.Strength*(2*(x*cos+y*sin-min)/length-1)
+		}
+	}
+
+	// Loop through pixels. Channels can't exceed alpha since colors are alpha-premultiplied.
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			f := factor(float64(x-r.Min.X)+0.5, float64(y-r.Min.Y)+0.5)
+			light := func(v uint8) uint8 { return uint8(math.Max(0, math.Min(float64(c.A), math.Round(float64(v)*f)))) }
+			c.R, c.G, c.B = light(c.R), light(c.G), light(c.B)
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// validate applies the default lighting options and checks them
+func (c *ConfigurationLighting) validate() (err error) {
+	if len(c.Kinds) == 0 {
+		c.Kinds = []string{lightingKindGradient, lightingKindShadow, lightingKindVignette}
+	}
+	for _, k := range c.Kinds {
+		switch k {
+		case lightingKindGradient, lightingKindShadow, lightingKindVignette:
+		default:
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid lighting kind %s", k))
+			return
+		}
+	}
+	if c.StrengthMin == 0 && c.StrengthMax == 0 {
+		c.StrengthMin, c.StrengthMax = 0.2, 0.5
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.StrengthMin < 0 || c.StrengthMax < c.StrengthMin || c.StrengthMax > 1 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid lighting proportion %v with strength range [%v, %v]", c.Proportion, c.StrengthMin, c.StrengthMax))
+		return
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package astiocr
+
+import "github.com/asticode/go-astilog"
+
+// Logger represents a logger
+// Detectors and trainers don't log anything unless a logger is provided in their configuration.
+type Logger interface {
+	Debug(v ...interface{})
+	Debugf(format string, v ...interface{})
+	Error(v ...interface{})
+	Errorf(format string, v ...interface{})
+	Warn(v ...interface{})
+	Warnf(format string, v ...interface{})
+}
+
+func newLogger(l Logger) Logger {
+	if l == nil {
+		return nopLogger{}
+	}
+	return l
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(v ...interface{})                 {}
+func (nopLogger) Debugf(format string, v ...interface{}) {}
+func (nopLogger) Error(v ...interface{})                 {}
+func (nopLogger) Errorf(format string, v ...interface{}) {}
+func (nopLogger) Warn(v ...interface{})                  {}
+func (nopLogger) Warnf(format string, v ...interface{})  {}
+
+// NewAstilogLogger creates a logger writing to the global astilog logger
+func NewAstilogLogger() Logger {
+	return astilogLogger{}
+}
+
+type astilogLogger struct{}
+
+func (astilogLogger) Debug(v ...interface{})                 { astilog.Debug(v...) }
+func (astilogLogger) Debugf(format string, v ...interface{}) { astilog.Debugf(format, v...) }
+func (astilogLogger) Error(v ...interface{})                 { astilog.Error(v...) }
+func (astilogLogger) Errorf(format string, v ...interface{}) { astilog.Errorf(format, v...) }
+func (astilogLogger) Warn(v ...interface{})                  { astilog.Warn(v...) }
+func (astilogLogger) Warnf(format string, v ...interface{})  { astilog.Warnf(format, v...) }
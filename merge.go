@@ -0,0 +1,31 @@
+package astiocr
+
+import "sort"
+
+// Above this IoU, two boxes with the same label are considered to be the same detection
+const mergeIoUThreshold = 0.5
+
+// mergeResults removes duplicate detections by keeping, for each group of boxes with the same
+// label overlapping above the threshold, the one with the highest probability
+func mergeResults(rs []DetectionResult, threshold float64) (o []DetectionResult) {
+	// Sort by decreasing probability
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].Probability > rs[j].Probability })
+
+	// Loop through results
+	for _, r := range rs {
+		// Check whether result overlaps with a kept result
+		var duplicate bool
+		for _, k := range o {
+			if k.Label == r.Label && k.Box.IoU(r.Box) > threshold {
+				duplicate = true
+				break
+			}
+		}
+
+		// Keep result
+		if !duplicate {
+			o = append(o, r)
+		}
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package astiocr
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics are registered in the default prometheus registry
+var (
+	metricDetections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "astiocr_detections_total",
+		Help: "Number of detections per label",
+	}, []string{"label"})
+	metricDetectionsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "astiocr_detections_in_flight",
+		Help: "Number of images being processed",
+	})
+	metricErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "astiocr_detection_errors_total",
+		Help: "Number of failed detections",
+	})
+	metricLowQualityInputs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "astiocr_low_quality_inputs_total",
+		Help: "Number of images failing the quality gate",
+	})
+	metricInferenceDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "astiocr_inference_duration_seconds",
+		Help:    "Duration of the model inference",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	metricModelLoadDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "astiocr_model_load_duration_seconds",
+		Help: "Duration of the last model load",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricDetections,
+		metricDetectionsInFlight,
+		metricErrors,
+		metricInferenceDuration,
+		metricLowQualityInputs,
+		metricModelLoadDuration,
+	)
+}
@@ -0,0 +1,134 @@
+package astiocr
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ModelInfo represents model metadata clients can use to validate their compatibility with a model
+type ModelInfo struct {
+	Backend string
+	Classes int
+	Input   ModelInfoTensor
+	Labels  []string
+	// Metadata embedded in the model, if any
+	Metadata map[string]string
+	Outputs  []string
+}
+
+// ModelInfoTensor represents a tensor description. Unknown dimensions are -1.
+type ModelInfoTensor struct {
+	DataType string
+	Name     string
+	Shape    []int64
+}
+
+// ModelInfoBackend represents a backend capable of describing its model
+// Backends that don't implement it are described by their name only.
+type ModelInfoBackend interface {
+	ModelInfo() (ModelInfo, error)
+}
+
+// ModelInfo returns metadata of the provided model ("" being the default model)
+func (d *Detector) ModelInfo(model string) (i ModelInfo, err error) {
+	// Acquire backend
+	var b *backendRef
+	if b, err = d.acquireBackend(model); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
+		return
+	}
+	defer b.release()
+
+	// Get info
+	return backendModelInfo(b.b, d.backendName())
+}
+
+func (d *Detector) backendName() string {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if len(d.c.Backend) > 0 {
+		return d.c.Backend
+	}
+	return defaultBackend
+}
+
+func backendModelInfo(b Backend, name string) (i ModelInfo, err error) {
+	// Backend doesn't describe its model
+	mb, ok := b.(ModelInfoBackend)
+	if !ok {
+		i.Backend = name
+		return
+	}
+
+	// Get info
+	if i, err = mb.ModelInfo(); err != nil {
+		err = errors.Wrap(err, "astiocr: getting model info failed")
+		return
+	}
+	if len(i.Backend) == 0 {
+		i.Backend = name
+	}
+	return
+}
+
+// ModelInfo implements the ModelInfoBackend interface
+func (b *idleBackend) ModelInfo() (i ModelInfo, err error) {
+	// Acquire backend
+	var bk Backend
+	if bk, err = b.acquire(); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
+		return
+	}
+	defer b.release()
+
+	// Get info
+	return backendModelInfo(bk, "")
+}
+
+// ModelInfo implements the ModelInfoBackend interface. Models of an ensemble must be compatible,
+// therefore only the first one is described.
+func (b *ensembleBackend) ModelInfo() (i ModelInfo, err error) {
+	if len(b.bs) == 0 {
+		err = fmt.Errorf("astiocr: no models in ensemble")
+		return
+	}
+	return backendModelInfo(b.bs[0], "")
+}
+
+// ModelInfo implements the ModelInfoBackend interface
+func (b *tensorFlowBackend) ModelInfo() (i ModelInfo, err error) {
+	// Input
+	i = ModelInfo{
+		Backend: defaultBackend,
+		Classes: len(b.labels),
+		Input: ModelInfoTensor{
+			DataType: dataTypeName(b.o.input.Output(0).DataType()),
+			Name:     b.o.input.Name(),
+		},
+		Outputs: []string{
+			b.o.boxes.Name(),
+			b.o.classes.Name(),
+			b.o.numDetections.Name(),
+			b.o.scores.Name(),
+		},
+	}
+	s := b.o.input.Output(0).Shape()
+	if s.NumDimensions() >= 0 {
+		for idx := 0; idx < s.NumDimensions(); idx++ {
+			i.Input.Shape = append(i.Input.Shape, s.Size(idx))
+		}
+	}
+
+	// Labels
+	i.Labels = append([]string(nil), b.labels...)
+
+	// Metadata
+	if o := b.g.Operation(tensorFlowMetadataOperation); o != nil {
+		if i.Metadata, err = b.metadata(o); err != nil {
+			err = errors.Wrap(err, "astiocr: getting metadata failed")
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// ConfigurationGaussianNoise represents a gaussian noise configuration
+// Gaussian noise, whose standard deviation in pixel intensity between 0 and 255 is picked between sigma
+// min and sigma max, is added to the proportion of images after rendering to simulate sensor noise.
+type ConfigurationGaussianNoise struct {
+	Proportion float64 `toml:"proportion"`
+	SigmaMax   float64 `toml:"sigma_max"`
+	SigmaMin   float64 `toml:"sigma_min"`
+}
+
+// random returns the standard deviation of the gaussian noise added to the images
+func (c ConfigurationGaussianNoise) random() float64 {
+	return c.SigmaMin + rand.Float64()*(c.SigmaMax-c.SigmaMin)
+}
+
+// addGaussianNoise adds gaussian noise of the standard deviation to each color channel of each pixel
+func addGaussianNoise(img *image.RGBA, sigma float64) {
+	// Channels can't exceed alpha since colors are alpha-premultiplied
+	noise := func(v, a uint8) uint8 {
+		return uint8(math.Max(0, math.Min(float64(a), math.Round(float64(v)+rand.NormFloat64()*sigma))))
+	}
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			c.R, c.G, c.B = noise(c.R, c.A), noise(c.G, c.A), noise(c.B, c.A)
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// ConfigurationSaltAndPepperNoise represents a salt and pepper noise configuration
+// The proportion of pixels, picked between density min and density max, of the proportion of images is
+// turned black or white after rendering to simulate compression and transmission artifacts.
+type ConfigurationSaltAndPepperNoise struct {
+	DensityMax float64 `toml:"density_max"`
+	DensityMin float64 `toml:"density_min"`
+	Proportion float64 `toml:"proportion"`
+}
+
+// random returns the density of the salt and pepper noise added to images
+func (c ConfigurationSaltAndPepperNoise) random() float64 {
+	return c.DensityMin + rand.Float64()*(c.DensityMax-c.DensityMin)
+}
+
+// addSaltAndPepperNoise turns the proportion of pixels of the image black or white
+func addSaltAndPepperNoise(img *image.RGBA, density float64) {
+	r := img.Bounds()
+	for count := int(math.Round(density * float64(r.Dx()*r.Dy()))); count > 0; count-- {
+		x, y := r.Min.X+rand.Intn(r.Dx()), r.Min.Y+rand.Intn(r.Dy())
+		c := img.RGBAAt(x, y)
+		if rand.Intn(2) == 0 {
+			c.R, c.G, c.B = 0, 0, 0
+		} else {
+			c.R, c.G, c.B = c.A, c.A, c.A
+		}
+		img.SetRGBA(x, y, c)
+	}
+}
+
+// validate checks the gaussian noise options
+func (c *ConfigurationGaussianNoise) validate() (err error) {
+	if c.Proportion < 0 || c.Proportion > 100 || c.SigmaMin < 0 || c.SigmaMax < c.SigmaMin {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid gaussian noise proportion %v with sigma range [%v, %v]", c.Proportion, c.SigmaMin, c.SigmaMax))
+		return
+	}
+	return
+}
+
+// validate checks the salt and pepper noise options
+func (c *ConfigurationSaltAndPepperNoise) validate() (err error) {
+	if c.Proportion < 0 || c.Proportion > 100 || c.DensityMin < 0 || c.DensityMax < c.DensityMin || c.DensityMax > 1 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid salt and pepper noise proportion %v with density range [%v, %v]", c.Proportion, c.DensityMin, c.DensityMax))
+		return
+	}
+	return
+}
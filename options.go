@@ -0,0 +1,52 @@
+package astiocr
+
+import tf "github.com/tensorflow/tensorflow/tensorflow/go"
+
+// DetectorOption represents a detector option
+type DetectorOption func(c *ConfigurationDetector)
+
+// NewDetectorWithOptions creates a new detector for the model located at modelPath, which is nicer than
+// building a configuration when using the detector programmatically
+func NewDetectorWithOptions(modelPath string, opts ...DetectorOption) (*Detector, error) {
+	c := ConfigurationDetector{ModelPath: modelPath}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return NewDetector(c)
+}
+
+// WithCharset makes the detector label class n+1 with the nth character of the charset
+func WithCharset(charset string) DetectorOption {
+	return func(c *ConfigurationDetector) { c.Charset = charset }
+}
+
+// WithConfiguration makes the detector use the provided configuration. Its model path is only used if
+// none has been provided. Options provided afterwards still apply.
+func WithConfiguration(cfg ConfigurationDetector) DetectorOption {
+	return func(c *ConfigurationDetector) {
+		if len(c.ModelPath) > 0 {
+			cfg.ModelPath = c.ModelPath
+		}
+		*c = cfg
+	}
+}
+
+// WithLabelMap makes the detector label class n+1 with the nth label
+func WithLabelMap(labels ...string) DetectorOption {
+	return func(c *ConfigurationDetector) { c.Labels = labels }
+}
+
+// WithLogger makes the detector log through the provided logger
+func WithLogger(l Logger) DetectorOption {
+	return func(c *ConfigurationDetector) { c.Logger = l }
+}
+
+// WithMinProbability makes the detector drop detections below the provided probability
+func WithMinProbability(p float64) DetectorOption {
+	return func(c *ConfigurationDetector) { c.MinProbability = p }
+}
+
+// WithSessionOptions makes the tensorflow backend create its session with the provided options
+func WithSessionOptions(o *tf.SessionOptions) DetectorOption {
+	return func(c *ConfigurationDetector) { c.TensorFlow.SessionOptions = o }
+}
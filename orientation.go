@@ -0,0 +1,57 @@
+package astiocr
+
+import (
+	"image"
+	"math"
+)
+
+// Below this total gradient energy, a box is considered as flat and its angle is not estimated
+const orientationMinEnergy = 1e-6
+
+// estimateAngles estimates the angle of each result's box
+func estimateAngles(img image.Image, rs []DetectionResult) {
+	for idx := range rs {
+		rs[idx].Angle = estimateAngle(img, rs[idx].Box)
+	}
+}
+
+// estimateAngle estimates the rotation, in degrees between -45 and 45, of the text inside the box using
+// gradient analysis. Text strokes are mostly horizontal and vertical, which means gradient orientations
+// cluster around the text rotation modulo 90 degrees. The dominant orientation is computed as the
+// average of the quadrupled gradient angles weighted by the gradient energy. Positive angles are
+// counterclockwise.
+func estimateAngle(img image.Image, b DetectionBox) float64 {
+	// Get rectangle
+	ib := img.Bounds()
+	r := b.Rect(ib).Intersect(ib)
+	if r.Dx() < 3 || r.Dy() < 3 {
+		return 0
+	}
+
+	// Loop through pixels
+	l := func(x, y int) float64 { return luminance(img.At(x, y)) }
+	var sumCos, sumSin, sumEnergy float64
+	for y := r.Min.Y + 1; y < r.Max.Y-1; y++ {
+		for x := r.Min.X + 1; x < r.Max.X-1; x++ {
+			// Sobel gradients. Y is flipped since image coordinates go downwards.
+			gx := l(x+1, y-1) + 2*l(x+1, y) + l(x+1, y+1) - l(x-1, y-1) - 2*l(x-1, y) - l(x-1, y+1)
+			gy := l(x-1, y-1) + 2*l(x, y-1) + l(x+1, y-1) - l(x-1, y+1) - 2*l(x, y+1) - l(x+1, y+1)
+			e := gx*gx + gy*gy
+			if e == 0 {
+				continue
+			}
+
+			// Quadruple angle
+			a := 4 * math.Atan2(gy, gx)
+			sumCos += e * math.Cos(a)
+			sumSin += e * math.Sin(a)
+			sumEnergy += e
+		}
+	}
+
+	// Box is flat
+	if sumEnergy < orientationMinEnergy {
+		return 0
+	}
+	return math.Atan2(sumSin, sumCos) / 4 * 180 / math.Pi
+}
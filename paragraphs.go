@@ -0,0 +1,171 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"unicode"
+
+	ft "golang.org/x/image/font"
+)
+
+// Text alignments
+const (
+	alignmentCenter  = "center"
+	alignmentJustify = "justify"
+	alignmentLeft    = "left"
+	alignmentRight   = "right"
+)
+
+var alignments = []string{alignmentCenter, alignmentJustify, alignmentLeft, alignmentRight}
+
+// Paragraph layout constants
+const (
+	// Maximum number of sentences in a paragraph
+	paragraphMaxSentences = 4
+	// Maximum line spacing, as an extra proportion of the line height
+	paragraphMaxLineSpacing = 0.6
+	// Maximum number of words in a sentence
+	sentenceMaxWords = 12
+	// Minimum number of words in a sentence
+	sentenceMinWords = 3
+)
+
+func (t *Trainer) createImageStrategyParagraphs(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
+	fc := newFaceCache(fontSize)
+	ascent, descent := t.lineMetrics(fc, font)
+	space := ft.MeasureString(fc.face(font), " ").Ceil()
+
+	// Create image
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
+
+	// Get random layout
+	margin := fontSize * (1 + rand.Intn(3))
+	lineHeight := int(float64(ascent+descent) * (1 + rand.Float64()*paragraphMaxLineSpacing))
+	alignment := alignments[rand.Intn(len(alignments))]
+	width := t.image.Width - 2*margin
+	if width <= 0 {
+		return
+	}
+
+	// Loop through paragraphs
+	for y := margin + ascent; y+descent <= t.image.Height-margin; y += lineHeight / 2 {
+		// Loop through lines
+		var ws []spacedWord
+		for _, w := range t.randomParagraph() {
+			ws = append(ws, t.spaceWord(fc, w, t.wordFonts(font, w)))
+		}
+		ls := wrapWords(ws, width, space)
+		for idx, l := range ls {
+			// Line doesn't fit
+			if y+descent > t.image.Height-margin {
+				return
+			}
+
+			// Draw line
+			t.drawLine(img, fc, fontColor, margin, y, width, space, l, alignment, idx == len(ls)-1, &si)
+			y += lineHeight
+		}
+	}
+	return
+}
+
+// randomParagraph returns the words of random sentences
+func (t *Trainer) randomParagraph() (ws []string) {
+	for idx, count := 0, 1+rand.Intn(paragraphMaxSentences); idx < count; idx++ {
+		ws = append(ws, t.randomSentence()...)
+	}
+	return
+}
+
+// randomSentence returns a random corpus sentence, or random words capitalizing the first one and ending
+// with a period when those characters are in the charset if there's no corpus
+func (t *Trainer) randomSentence() (ws []string) {
+	// Corpus
+	if len(t.sentences) > 0 {
+		return t.randomCorpusSentence()
+	}
+
+	// Get words
+	for idx, count := 0, sentenceMinWords+rand.Intn(sentenceMaxWords-sentenceMinWords+1); idx < count; idx++ {
+		ws = append(ws, t.randomWord())
+	}
+
+	// Capitalize
+	if rs := []rune(ws[0]); t.labelIndex(unicode.ToUpper(rs[0])) > 0 {
+		rs[0] = unicode.ToUpper(rs[0])
+		ws[0] = string(rs)
+	}
+
+	// Punctuate
+	if t.labelIndex(',') > 0 && len(ws) > sentenceMinWords && rand.Intn(2) == 0 {
+		idx := rand.Intn(len(ws) - 1)
+		ws[idx] += ","
+	}
+	if t.labelIndex('.') > 0 {
+		ws[len(ws)-1] += "."
+	}
+	return
+}
+
+// wrapWords greedily splits words into lines fitting in the width. Words wider than the width are dropped.
+func wrapWords(ws []spacedWord, width, space int) (ls [][]spacedWord) {
+	var l []spacedWord
+	var lw int
+	for _, w := range ws {
+		// Word is too wide
+		if w.width > width {
+			continue
+		}
+
+		// Start a new line
+		if len(l) > 0 && lw+space+w.width > width {
+			ls = append(ls, l)
+			l, lw = nil, 0
+		}
+
+		// Add word
+		if len(l) > 0 {
+			lw += space
+		}
+		l = append(l, w)
+		lw += w.width
+	}
+	if len(l) > 0 {
+		ls = append(ls, l)
+	}
+	return
+}
+
+// drawLine draws the words with their baseline at y, aligned inside [x, x+width]. The last line of a
+// justified paragraph is left aligned.
+func (t *Trainer) drawLine(img draw.Image, c *faceCache, fontColor color.Color, x, y, width, space int, ws []spacedWord, alignment string, last bool, si *GatherSummaryImage) {
+	// Get free space
+	free := width - space*(len(ws)-1)
+	for _, w := range ws {
+		free -= w.width
+	}
+
+	// Align
+	pos, gap := float64(x), float64(space)
+	switch alignment {
+	case alignmentCenter:
+		pos += float64(free) / 2
+	case alignmentJustify:
+		if !last && len(ws) > 1 {
+			gap += float64(free) / float64(len(ws)-1)
+		}
+	case alignmentRight:
+		pos += float64(free)
+	}
+
+	// Loop through words
+	for _, w := range ws {
+		si.Words = append(si.Words, t.drawWord(img, c, fontColor, int(math.Round(pos)), y, w, si))
+		pos += float64(w.width) + gap
+	}
+}
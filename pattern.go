@@ -0,0 +1,48 @@
+package astiocr
+
+import "regexp"
+
+// MatchPatterns checks the corrected text of each line against the patterns. Lines matching none of them
+// are removed if drop is true, or flagged as mismatching otherwise. Blocks left without lines are removed.
+func (p *DocumentPage) MatchPatterns(patterns []*regexp.Regexp, drop bool) {
+	// Loop through blocks
+	var bs []DocumentBlock
+	for _, b := range p.Blocks {
+		// Loop through lines
+		var ls []DocumentLine
+		for _, l := range b.Lines {
+			// Check patterns
+			if !matchesAny(l.CorrectedText(), patterns) {
+				if drop {
+					continue
+				}
+				l.PatternMismatch = true
+			}
+			ls = append(ls, l)
+		}
+
+		// No lines left
+		if len(ls) == 0 {
+			continue
+		}
+
+		// Update block
+		b.Lines = ls
+		b.Box = ls[0].Box
+		for _, l := range ls[1:] {
+			b.Box = b.Box.Union(l.Box)
+		}
+		b.Confidence = averageLineConfidence(ls)
+		bs = append(bs, b)
+	}
+	p.Blocks = bs
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
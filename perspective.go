@@ -0,0 +1,205 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+)
+
+// ConfigurationPerspective represents a perspective configuration
+// The proportion of images is warped after rendering, as if photographed at an angle, by moving each of its
+// corners inward by a random distance of up to strength times the image dimensions (default is 0.1).
+// Boxes are remapped accordingly.
+type ConfigurationPerspective struct {
+	Proportion float64 `toml:"proportion"`
+	Strength   float64 `toml:"strength"`
+}
+
+// homography represents a 3x3 projective transformation, row-major
+type homography [9]float64
+
+// apply returns the transformed point
+func (h homography) apply(x, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
+
+// newHomography returns the homography mapping the 4 source points to the 4 destination points, or false
+// if there's none
+func newHomography(src, dst [4][2]float64) (h homography, ok bool) {
+	// Build linear system
+	var m [8][9]float64
+	for idx := range src {
+		x, y, u, v := src[idx][0], src[idx][1], dst[idx][0], dst[idx][1]
+		m[2*idx] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		m[2*idx+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Solve with gaussian elimination and partial pivoting
+	for col := 0; col < 8; col++ {
+		// Get pivot
+		p := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[p][col]) {
+				p = row
+			}
+		}
+		if math.Abs(m[p][col]) < 1e-12 {
+			return
+		}
+		m[col], m[p] = m[p], m[col]
+
+		// Eliminate
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			f := m[row][col] / m[col][col]
+			for k := col; k < 9; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+	for idx := 0; idx < 8; idx++ {
+		h[idx] = m[idx][8] / m[idx][idx]
+	}
+	h[8] = 1
+	ok = true
+	return
+}
+
+// random returns the homography warping images of the size, or nil if there's none
+func (c ConfigurationPerspective) random(width, height int) []float64 {
+	// Move corners inward
+	w, h := float64(width), float64(height)
+	src := [4][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	var dst [4][2]float64
+	for idx, p := range src {
+		dx, dy := rand.Float64()*c.Strength*w, rand.Float64()*c.Strength*h
+		if p[0] > 0 {
+			dx = -dx
+		}
+		if p[1] > 0 {
+			dy = -dy
+		}
+		dst[idx] = [2]float64{p[0] + dx, p[1] + dy}
+	}
+
+	// Get homography
+	m, ok := newHomography(src, dst)
+	if !ok {
+		return nil
+	}
+	return m[:]
+}
+
+// warpHomography warps the image with the homography, pixels mapped from outside the image being replaced
+// with the closest pixel inside the image, and remaps the boxes of the summary to the bounds of their
+// transformed corners. Boxes that end up outside the image are removed.
+func warpHomography(img *image.RGBA, fwd homography, si *GatherSummaryImage) {
+	// Get inverse homography
+	r := img.Bounds()
+	w, h := float64(r.Dx()), float64(r.Dy())
+	src := [4][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	var dst [4][2]float64
+	for idx, c := range src {
+		dst[idx][0], dst[idx][1] = fwd.apply(c[0], c[1])
+	}
+	inv, ok := newHomography(dst, src)
+	if !ok {
+		return
+	}
+
+	// Warp
+	remapImage(img, func(x, y int) (float64, float64) {
+		sx, sy := inv.apply(float64(x-r.Min.X)+0.5, float64(y-r.Min.Y)+0.5)
+		return sx - 0.5 + float64(r.Min.X), sy - 0.5 + float64(r.Min.Y)
+	})
+
+	// Remap boxes
+	remap := func(x0, x1, y0, y1 *int) bool {
+		b := image.Rectangle{}
+		for idx, c := range [4][2]int{{*x0, *y0}, {*x1, *y0}, {*x1, *y1}, {*x0, *y1}} {
+			px, py := fwd.apply(float64(c[0]), float64(c[1]))
+			p := image.Rect(int(math.Floor(px)), int(math.Floor(py)), int(math.Ceil(px)), int(math.Ceil(py)))
+			if idx == 0 {
+				b = p
+			} else {
+				b = b.Union(p)
+			}
+		}
+		b = b.Intersect(image.Rect(0, 0, si.Width, si.Height))
+		*x0, *x1, *y0, *y1 = b.Min.X, b.Max.X, b.Min.Y, b.Max.Y
+		return !b.Empty()
+	}
+	var bs []GatherSummaryBox
+	for _, b := range si.Boxes {
+		if remap(&b.X0, &b.X1, &b.Y0, &b.Y1) {
+			bs = append(bs, b)
+		}
+	}
+	si.Boxes = bs
+	var ws []GatherSummaryWord
+	for _, w := range si.Words {
+		if remap(&w.X0, &w.X1, &w.Y0, &w.Y1) {
+			ws = append(ws, w)
+		}
+	}
+	si.Words = ws
+}
+
+// remapImage replaces each pixel of the image with the color of the image at the point the function maps it
+// to
+func remapImage(img *image.RGBA, fn func(x, y int) (float64, float64)) {
+	r := img.Bounds()
+	o := image.NewRGBA(r)
+	draw.Draw(o, r, img, r.Min, draw.Src)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx, sy := fn(x, y)
+			img.SetRGBA(x, y, bilinearRGBA(o, sx, sy))
+		}
+	}
+}
+
+// bilinearRGBA returns the color of the image at the point, interpolated between the 4 closest pixels,
+// points outside the image being moved to the closest pixel inside the image
+func bilinearRGBA(img *image.RGBA, x, y float64) color.RGBA {
+	r := img.Bounds()
+	clamp := func(v float64, min, max int) float64 { return math.Max(float64(min), math.Min(float64(max-1), v)) }
+	x, y = clamp(x, r.Min.X, r.Max.X), clamp(y, r.Min.Y, r.Max.Y)
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	x1, y1 := x0+1, y0+1
+	if x1 >= r.Max.X {
+		x1 = x0
+	}
+	if y1 >= r.Max.Y {
+		y1 = y0
+	}
+	fx, fy := x-float64(x0), y-float64(y0)
+	c00, c10, c01, c11 := img.RGBAAt(x0, y0), img.RGBAAt(x1, y0), img.RGBAAt(x0, y1), img.RGBAAt(x1, y1)
+	mix := func(a, b, c, d uint8) uint8 {
+		return uint8(math.Round((float64(a)*(1-fx)+float64(b)*fx)*(1-fy) + (float64(c)*(1-fx)+float64(d)*fx)*fy))
+	}
+	return color.RGBA{
+		R: mix(c00.R, c10.R, c01.R, c11.R),
+		G: mix(c00.G, c10.G, c01.G, c11.G),
+		B: mix(c00.B, c10.B, c01.B, c11.B),
+		A: mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// validate applies the default perspective options and checks them
+func (c *ConfigurationPerspective) validate() (err error) {
+	if c.Strength == 0 {
+		c.Strength = 0.1
+	}
+	if c.Proportion < 0 || c.Proportion > 100 || c.Strength < 0 || c.Strength >= 0.5 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid perspective proportion %v with strength %v", c.Proportion, c.Strength))
+		return
+	}
+	return
+}
@@ -0,0 +1,70 @@
+package astiocr
+
+import "regexp"
+
+// PostProcessor represents an object capable of post-processing detection results
+type PostProcessor interface {
+	PostProcess(rs []DetectionResult) []DetectionResult
+}
+
+// PostProcessorFunc allows using a func as a PostProcessor
+type PostProcessorFunc func(rs []DetectionResult) []DetectionResult
+
+// PostProcess implements the PostProcessor interface
+func (f PostProcessorFunc) PostProcess(rs []DetectionResult) []DetectionResult {
+	return f(rs)
+}
+
+// AddPostProcessors appends post-processors to the detector chain. Post-processors are run in the order
+// they were added, after the built-in post-processing.
+func (d *Detector) AddPostProcessors(ps ...PostProcessor) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.postProcessors = append(d.postProcessors, ps...)
+}
+
+func (d *Detector) postProcess(rs []DetectionResult) []DetectionResult {
+	// Copy chain
+	d.m.Lock()
+	ps := make([]PostProcessor, len(d.postProcessors))
+	copy(ps, d.postProcessors)
+	d.m.Unlock()
+
+	// Loop through post-processors
+	for _, p := range ps {
+		rs = p.PostProcess(rs)
+	}
+	return rs
+}
+
+// NMSPostProcessor removes duplicate detections by keeping, for each group of boxes with the same label
+// overlapping above the threshold, the one with the highest probability
+func NMSPostProcessor(threshold float64) PostProcessor {
+	return PostProcessorFunc(func(rs []DetectionResult) []DetectionResult {
+		return mergeResults(rs, threshold)
+	})
+}
+
+// MinProbabilityPostProcessor drops detections below the probability
+func MinProbabilityPostProcessor(p float64) PostProcessor {
+	return PostProcessorFunc(func(rs []DetectionResult) (o []DetectionResult) {
+		for _, r := range rs {
+			if r.Probability >= p {
+				o = append(o, r)
+			}
+		}
+		return
+	})
+}
+
+// LabelPatternPostProcessor drops detections whose label doesn't match the pattern
+func LabelPatternPostProcessor(pattern *regexp.Regexp) PostProcessor {
+	return PostProcessorFunc(func(rs []DetectionResult) (o []DetectionResult) {
+		for _, r := range rs {
+			if pattern.MatchString(r.Label) {
+				o = append(o, r)
+			}
+		}
+		return
+	})
+}
@@ -0,0 +1,274 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/draw"
+)
+
+// ConfigurationPreprocessingStep represents a preprocessing step configuration
+// Only the options of the step's kind are used.
+type ConfigurationPreprocessingStep struct {
+	// Kind of the step: "binarize", "contrast_stretch", "crop", "denoise", "deskew", "equalize", "grayscale", "invert" or "resize"
+	Kind string `toml:"kind"`
+
+	// Method of the binarize, denoise and equalize steps
+	Method string `toml:"method"`
+
+	// Binarize options: method is either "otsu" (default) which uses a global threshold, or "sauvola" which
+	// uses a threshold per pixel computed in a window of window size pixels (default is 15) with a
+	// sensitivity of k (default is 0.2)
+	K          float64 `toml:"k"`
+	WindowSize int     `toml:"window_size"`
+
+	// Contrast stretch options: proportions of the darkest and brightest pixels that are saturated.
+	// Default is 0.01 for both.
+	High float64 `toml:"high"`
+	Low  float64 `toml:"low"`
+
+	// Crop options: normalized coordinates of the region kept
+	X1 float64 `toml:"x1"`
+	X2 float64 `toml:"x2"`
+	Y1 float64 `toml:"y1"`
+	Y2 float64 `toml:"y2"`
+
+	// Denoise options: method is either "median" (default) or "bilateral" which weighs neighbors by their
+	// color difference to the center with a sigma of sigma color (default is 30). The kernel size must be
+	// odd. Default is 3.
+	KernelSize int     `toml:"kernel_size"`
+	SigmaColor float64 `toml:"sigma_color"`
+
+	// Deskew options: maximum skew, in degrees, that is corrected. Default is 5.
+	MaxAngle float64 `toml:"max_angle"`
+
+	// Equalize options: method is either "global" (default) which equalizes the histogram of the whole
+	// image, or "clahe" which equalizes a grid of grid size by grid size tiles (default is 8) whose
+	// histograms are clipped at clip limit times their average bin count (default is 2)
+	ClipLimit float64 `toml:"clip_limit"`
+	GridSize  int     `toml:"grid_size"`
+
+	// Resize options: dimensions in pixels. If only one is set, the aspect ratio is kept.
+	Height int `toml:"height"`
+	Width  int `toml:"width"`
+}
+
+// preprocessingStep transforms an image before detection and returns a func mapping normalized boxes
+// of the transformed image back to normalized boxes of the original image
+type preprocessingStep func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox)
+
+// Preprocessing steps indexed by kind
+var preprocessingSteps = map[string]func(c ConfigurationPreprocessingStep) (preprocessingStep, error){
+	"binarize":         newBinarizeStep,
+	"contrast_stretch": newContrastStretchStep,
+	"crop":             newCropStep,
+	"denoise":          newDenoiseStep,
+	"deskew":           newDeskewStep,
+	"equalize":         newEqualizeStep,
+	"grayscale":        newGrayscaleStep,
+	"invert":           newInvertStep,
+	"resize":           newResizeStep,
+}
+
+func newPreprocessingSteps(cs []ConfigurationPreprocessingStep) (ss []preprocessingStep, err error) {
+	for idx, c := range cs {
+		// Get factory
+		f, ok := preprocessingSteps[c.Kind]
+		if !ok {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: preprocessing step #%d has an invalid kind %s", idx+1, c.Kind))
+			return
+		}
+
+		// Create step
+		var s preprocessingStep
+		if s, err = f(c); err != nil {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: preprocessing step #%d is invalid: %s", idx+1, err))
+			return
+		}
+		ss = append(ss, s)
+	}
+	return
+}
+
+// Preprocess applies the configured preprocessing steps to the image, which is handy to check what the
+// backend is fed with
+func (d *Detector) Preprocess(img image.Image) image.Image {
+	img, _ = d.preprocess(img)
+	return img
+}
+
+// PreprocessFile applies the configured preprocessing steps to the image located at src and writes the
+// result as a png to dst
+func (d *Detector) PreprocessFile(src, dst string) (err error) {
+	// Decode image
+	var img image.Image
+	if img, err = decodeImageFile(src); err != nil {
+		err = errors.Wrapf(err, "astiocr: decoding %s failed", src)
+		return
+	}
+
+	// Store
+	if err = storePNG(dst, d.Preprocess(img)); err != nil {
+		err = errors.Wrapf(err, "astiocr: storing %s failed", dst)
+		return
+	}
+	return
+}
+
+func (d *Detector) preprocess(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+	var fs []func(b DetectionBox) DetectionBox
+	for _, s := range d.preprocessingSteps {
+		var f func(b DetectionBox) DetectionBox
+		img, f = s(img)
+		fs = append(fs, f)
+	}
+	return img, func(b DetectionBox) DetectionBox {
+		for idx := len(fs) - 1; idx >= 0; idx-- {
+			b = fs[idx](b)
+		}
+		return b
+	}
+}
+
+func identityBox(b DetectionBox) DetectionBox { return b }
+
+// mapPixels creates a new image whose pixels are the result of f applied to the source pixels
+func mapPixels(src image.Image, f func(c color.RGBA) color.RGBA) image.Image {
+	r := src.Bounds()
+	dst := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetRGBA(x, y, f(color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)))
+		}
+	}
+	return dst
+}
+
+func newContrastStretchStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	// Default values
+	low, high := c.Low, c.High
+	if low == 0 {
+		low = 0.01
+	}
+	if high == 0 {
+		high = 0.01
+	}
+	if low < 0 || high < 0 || low+high >= 1 {
+		err = fmt.Errorf("astiocr: invalid low %v and high %v", low, high)
+		return
+	}
+
+	s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		// Get luminance percentiles
+		r := img.Bounds()
+		var ls []float64
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				ls = append(ls, luminance(img.At(x, y))*255)
+			}
+		}
+		if len(ls) == 0 {
+			return img, identityBox
+		}
+		sort.Float64s(ls)
+		min, max := ls[int(low*float64(len(ls)-1))], ls[int((1-high)*float64(len(ls)-1))]
+		if max <= min {
+			return img, identityBox
+		}
+
+		// Stretch
+		stretch := func(v uint8) uint8 {
+			return uint8(math.Max(0, math.Min(255, (float64(v)-min)*255/(max-min))))
+		}
+		return mapPixels(img, func(c color.RGBA) color.RGBA {
+			return color.RGBA{R: stretch(c.R), G: stretch(c.G), B: stretch(c.B), A: c.A}
+		}), identityBox
+	}
+	return
+}
+
+func newCropStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	// Check coordinates
+	if c.X1 < 0 || c.Y1 < 0 || c.X2 > 1 || c.Y2 > 1 || c.X1 >= c.X2 || c.Y1 >= c.Y2 {
+		err = fmt.Errorf("astiocr: invalid crop coordinates %v, %v, %v, %v", c.X1, c.X2, c.Y1, c.Y2)
+		return
+	}
+
+	s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		// Crop
+		r := img.Bounds()
+		cr := image.Rect(
+			r.Min.X+int(c.X1*float64(r.Dx())),
+			r.Min.Y+int(c.Y1*float64(r.Dy())),
+			r.Min.X+int(c.X2*float64(r.Dx())),
+			r.Min.Y+int(c.Y2*float64(r.Dy())),
+		)
+		if cr.Empty() {
+			return img, identityBox
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, cr.Dx(), cr.Dy()))
+		draw.Draw(dst, dst.Bounds(), img, cr.Min, draw.Src)
+
+		// Boxes are relative to the cropped region
+		x1, y1 := float64(cr.Min.X-r.Min.X)/float64(r.Dx()), float64(cr.Min.Y-r.Min.Y)/float64(r.Dy())
+		w, h := float64(cr.Dx())/float64(r.Dx()), float64(cr.Dy())/float64(r.Dy())
+		return dst, func(b DetectionBox) DetectionBox {
+			return DetectionBox{
+				X1: x1 + b.X1*w,
+				X2: x1 + b.X2*w,
+				Y1: y1 + b.Y1*h,
+				Y2: y1 + b.Y2*h,
+			}
+		}
+	}
+	return
+}
+
+func newGrayscaleStep(c ConfigurationPreprocessingStep) (preprocessingStep, error) {
+	return func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		return mapPixels(img, func(c color.RGBA) color.RGBA {
+			l := uint8(math.Round(luminance(c) * 255))
+			return color.RGBA{R: l, G: l, B: l, A: c.A}
+		}), identityBox
+	}, nil
+}
+
+func newInvertStep(c ConfigurationPreprocessingStep) (preprocessingStep, error) {
+	return func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		return mapPixels(img, func(c color.RGBA) color.RGBA {
+			return color.RGBA{R: c.A - c.R, G: c.A - c.G, B: c.A - c.B, A: c.A}
+		}), identityBox
+	}, nil
+}
+
+func newResizeStep(c ConfigurationPreprocessingStep) (s preprocessingStep, err error) {
+	// Check dimensions
+	if c.Width < 0 || c.Height < 0 || (c.Width == 0 && c.Height == 0) {
+		err = fmt.Errorf("astiocr: invalid resize dimensions %dx%d", c.Width, c.Height)
+		return
+	}
+
+	s = func(img image.Image) (image.Image, func(b DetectionBox) DetectionBox) {
+		// Get dimensions
+		r := img.Bounds()
+		if r.Empty() {
+			return img, identityBox
+		}
+		w, h := c.Width, c.Height
+		if w == 0 {
+			w = int(math.Max(1, math.Round(float64(r.Dx())*float64(h)/float64(r.Dy()))))
+		} else if h == 0 {
+			h = int(math.Max(1, math.Round(float64(r.Dy())*float64(w)/float64(r.Dx()))))
+		}
+
+		// Resize. Boxes are normalized which means they don't need to be mapped.
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, r, draw.Src, nil)
+		return dst, identityBox
+	}
+	return
+}
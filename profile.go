@@ -0,0 +1,255 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/asticode/go-astitools/image"
+	"github.com/pkg/errors"
+)
+
+// ImageProfile represents statistics gathered on a sample of target images that can be used to make
+// generated images look like them
+type ImageProfile struct {
+	Colors      []ConfigurationColor
+	Count       int
+	FontSizeMax int
+	FontSizeMin int
+	NoiseSigma  float64
+}
+
+// Profiling constants
+const (
+	// Number of bits kept per channel when building color histograms
+	profileColorBits = 4
+	// Maximum number of font colors kept per background color
+	profileMaxFontColors = 3
+	// Minimum luminance difference between a font color and its background
+	profileMinContrast = 0.25
+	// Minimum proportion of foreground pixels in a row for it to be considered as part of a text line
+	profileMinRowCoverage = 0.005
+)
+
+// ProfileImages analyzes the images located in the directory and derives colors, font sizes and noise
+// level from them. Nothing is logged.
+func ProfileImages(ctx context.Context, dirPath string) (p ImageProfile, err error) {
+	return profileImages(ctx, nopLogger{}, dirPath)
+}
+
+func profileImages(ctx context.Context, l Logger, dirPath string) (p ImageProfile, err error) {
+	// Read dir
+	var fis []os.FileInfo
+	if fis, err = ioutil.ReadDir(dirPath); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading dir %s failed", dirPath)
+		return
+	}
+
+	// Loop through files
+	var fontSizes []int
+	var noiseSigmas []float64
+	colors := make(map[color.RGBA]map[color.RGBA]int)
+	for _, fi := range fis {
+		// Check context
+		if err = ctx.Err(); err != nil {
+			err = errors.Wrap(err, "astiocr: context error")
+			return
+		}
+
+		// Only process files
+		if fi.IsDir() {
+			continue
+		}
+
+		// Decode image
+		pth := filepath.Join(dirPath, fi.Name())
+		var img image.Image
+		if img, err = decodeImageFile(pth); err != nil {
+			l.Debugf("astiocr: skipping %s: %s", pth, err)
+			err = nil
+			continue
+		}
+		l.Debugf("astiocr: profiling %s", pth)
+		p.Count++
+
+		// Colors
+		background, fonts := profileColors(img)
+		if _, ok := colors[background]; !ok {
+			colors[background] = make(map[color.RGBA]int)
+		}
+		for _, f := range fonts {
+			colors[background][f]++
+		}
+
+		// Font sizes
+		fontSizes = append(fontSizes, profileFontSizes(img, background)...)
+
+		// Noise
+		noiseSigmas = append(noiseSigmas, profileNoiseSigma(img))
+	}
+
+	// No images
+	if p.Count == 0 {
+		err = errors.Errorf("astiocr: no image found in %s", dirPath)
+		return
+	}
+
+	// Colors
+	for background, fonts := range colors {
+		// No font color has been found for this background
+		if len(fonts) == 0 {
+			continue
+		}
+
+		// Sort font colors by occurrences
+		var fcs []color.RGBA
+		for f := range fonts {
+			fcs = append(fcs, f)
+		}
+		sort.Slice(fcs, func(i, j int) bool { return fonts[fcs[i]] > fonts[fcs[j]] })
+		if len(fcs) > profileMaxFontColors {
+			fcs = fcs[:profileMaxFontColors]
+		}
+
+		// Add color
+		c := ConfigurationColor{Background: *astiimage.NewRGBA(background.R, background.G, background.B, background.A)}
+		for _, f := range fcs {
+			c.Fonts = append(c.Fonts, *astiimage.NewRGBA(f.R, f.G, f.B, f.A))
+		}
+		p.Colors = append(p.Colors, c)
+	}
+
+	// Font sizes
+	if len(fontSizes) > 0 {
+		sort.Ints(fontSizes)
+		p.FontSizeMin = fontSizes[len(fontSizes)/10]
+		p.FontSizeMax = fontSizes[len(fontSizes)*9/10]
+	}
+
+	// Noise
+	sort.Float64s(noiseSigmas)
+	p.NoiseSigma = noiseSigmas[len(noiseSigmas)/2]
+	return
+}
+
+func decodeImageFile(p string) (img image.Image, err error) {
+	// Open file
+	var f *os.File
+	if f, err = os.Open(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Decode
+	if img, _, err = image.Decode(f); err != nil {
+		err = errors.Wrapf(decodeError(err), "astiocr: decoding %s failed", p)
+		return
+	}
+	return
+}
+
+// profileColors returns the most frequent color as the background color, and the most frequent colors
+// contrasting enough with it as the font colors
+func profileColors(img image.Image) (background color.RGBA, fonts []color.RGBA) {
+	// Build histogram
+	h := make(map[color.RGBA]int)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			h[quantizeColor(img.At(x, y))]++
+		}
+	}
+
+	// Sort colors by occurrences
+	var cs []color.RGBA
+	for c := range h {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return h[cs[i]] > h[cs[j]] })
+
+	// Get colors
+	background = cs[0]
+	for _, c := range cs[1:] {
+		if len(fonts) >= profileMaxFontColors {
+			break
+		}
+		if math.Abs(luminance(c)-luminance(background)) >= profileMinContrast {
+			fonts = append(fonts, c)
+		}
+	}
+	return
+}
+
+func quantizeColor(c color.Color) color.RGBA {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	shift := uint(8 - profileColorBits)
+	q := func(v uint8) uint8 { return v>>shift<<shift | 1<<(shift-1) }
+	return color.RGBA{R: q(rgba.R), G: q(rgba.G), B: q(rgba.B), A: 0xff}
+}
+
+// luminance returns the relative luminance of a color between 0 and 1
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / 0xffff
+}
+
+// profileFontSizes estimates font sizes using the height of text lines found in the horizontal
+// projection of pixels contrasting with the background
+func profileFontSizes(img image.Image, background color.RGBA) (sizes []int) {
+	// Loop through rows
+	b := img.Bounds()
+	bl := luminance(background)
+	var lineHeight int
+	for y := b.Min.Y; y <= b.Max.Y; y++ {
+		// Count foreground pixels
+		var count int
+		if y < b.Max.Y {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if math.Abs(luminance(img.At(x, y))-bl) >= profileMinContrast {
+					count++
+				}
+			}
+		}
+
+		// Row is part of a text line
+		if float64(count)/float64(b.Dx()) >= profileMinRowCoverage {
+			lineHeight++
+			continue
+		}
+
+		// End of a text line. Lines too small are most likely noise and lines too big most likely
+		// aren't text.
+		if lineHeight >= 6 && lineHeight <= b.Dy()/4 {
+			sizes = append(sizes, lineHeight)
+		}
+		lineHeight = 0
+	}
+	return
+}
+
+// profileNoiseSigma estimates the standard deviation of the noise using Immerkaer's method
+func profileNoiseSigma(img image.Image) float64 {
+	// Image is too small
+	b := img.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return 0
+	}
+
+	// Convolve with the noise estimation kernel
+	l := func(x, y int) float64 { return luminance(img.At(x, y)) * 255 }
+	var sum float64
+	for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+		for x := b.Min.X + 1; x < b.Max.X-1; x++ {
+			sum += math.Abs(l(x-1, y-1) - 2*l(x, y-1) + l(x+1, y-1) -
+				2*l(x-1, y) + 4*l(x, y) - 2*l(x+1, y) +
+				l(x-1, y+1) - 2*l(x, y+1) + l(x+1, y+1))
+		}
+	}
+	return sum * math.Sqrt(math.Pi/2) / (6 * float64(b.Dx()-2) * float64(b.Dy()-2))
+}
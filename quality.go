@@ -0,0 +1,138 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Quality modes
+const (
+	qualityModeFlag   = "flag"
+	qualityModeReject = "reject"
+)
+
+// ConfigurationQuality represents a quality gate configuration
+// A zero value disables the constraint.
+type ConfigurationQuality struct {
+	// Exposure is the mean luminance between 0 and 1
+	MaxExposure float64 `toml:"max_exposure"`
+	MinExposure float64 `toml:"min_exposure"`
+
+	// Dimensions in pixels
+	MinHeight int `toml:"min_height"`
+	MinWidth  int `toml:"min_width"`
+
+	// Sharpness is the variance of the laplacian of the luminance between 0 and 255. Blurry images have
+	// a low sharpness.
+	MinSharpness float64 `toml:"min_sharpness"`
+
+	// What to do with images failing the quality gate: "flag" logs a warning and proceeds with the
+	// detection, setting the reasons in the QualityFlags of every result, "reject" returns an
+	// *ErrLowQualityInput. Default is "reject".
+	Mode string `toml:"mode"`
+}
+
+// QualityMetrics represents image quality metrics
+type QualityMetrics struct {
+	Exposure  float64
+	Height    int
+	Sharpness float64
+	Width     int
+}
+
+// ErrLowQualityInput is returned when an image fails the quality gate. Since it may be wrapped, use
+// errors.Cause to retrieve it.
+type ErrLowQualityInput struct {
+	Metrics QualityMetrics
+	Reasons []string
+}
+
+// Error implements the error interface
+func (e *ErrLowQualityInput) Error() string {
+	return fmt.Sprintf("astiocr: low quality input: %s", strings.Join(e.Reasons, ", "))
+}
+
+// AssessQuality computes quality metrics of the image
+func AssessQuality(img image.Image) (m QualityMetrics) {
+	// Dimensions
+	b := img.Bounds()
+	m.Height, m.Width = b.Dy(), b.Dx()
+	if m.Height == 0 || m.Width == 0 {
+		return
+	}
+
+	// Exposure
+	var sum float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sum += luminance(img.At(x, y))
+		}
+	}
+	m.Exposure = sum / float64(m.Width*m.Height)
+
+	// Image is too small to compute the laplacian
+	if m.Height < 3 || m.Width < 3 {
+		return
+	}
+
+	// Sharpness
+	l := func(x, y int) float64 { return luminance(img.At(x, y)) * 255 }
+	var vs []float64
+	var mean float64
+	for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+		for x := b.Min.X + 1; x < b.Max.X-1; x++ {
+			v := l(x-1, y) + l(x+1, y) + l(x, y-1) + l(x, y+1) - 4*l(x, y)
+			vs = append(vs, v)
+			mean += v
+		}
+	}
+	mean /= float64(len(vs))
+	for _, v := range vs {
+		m.Sharpness += (v - mean) * (v - mean)
+	}
+	m.Sharpness /= float64(len(vs))
+	return
+}
+
+// check returns an *ErrLowQualityInput if the image fails the quality gate in reject mode, and the
+// reasons it failed in flag mode
+func (c ConfigurationQuality) check(img image.Image, l Logger) (flags []string, err error) {
+	// Gate is disabled
+	if c.MaxExposure <= 0 && c.MinExposure <= 0 && c.MinHeight <= 0 && c.MinWidth <= 0 && c.MinSharpness <= 0 {
+		return
+	}
+
+	// Check metrics
+	m := AssessQuality(img)
+	var rs []string
+	if c.MinWidth > 0 && m.Width < c.MinWidth {
+		rs = append(rs, fmt.Sprintf("width %d < %d", m.Width, c.MinWidth))
+	}
+	if c.MinHeight > 0 && m.Height < c.MinHeight {
+		rs = append(rs, fmt.Sprintf("height %d < %d", m.Height, c.MinHeight))
+	}
+	if c.MinExposure > 0 && m.Exposure < c.MinExposure {
+		rs = append(rs, fmt.Sprintf("exposure %.2f < %.2f", m.Exposure, c.MinExposure))
+	}
+	if c.MaxExposure > 0 && m.Exposure > c.MaxExposure {
+		rs = append(rs, fmt.Sprintf("exposure %.2f > %.2f", m.Exposure, c.MaxExposure))
+	}
+	if c.MinSharpness > 0 && m.Sharpness < c.MinSharpness {
+		rs = append(rs, fmt.Sprintf("sharpness %.2f < %.2f", m.Sharpness, c.MinSharpness))
+	}
+	if len(rs) == 0 {
+		return
+	}
+
+	// Flag or reject
+	metricLowQualityInputs.Inc()
+	e := &ErrLowQualityInput{Metrics: m, Reasons: rs}
+	if c.Mode == qualityModeFlag {
+		l.Warn(e)
+		flags = rs
+		return
+	}
+	err = e
+	return
+}
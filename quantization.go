@@ -0,0 +1,48 @@
+package astiocr
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ConfigurationQuantization represents the quantization parameters of an output tensor
+// Real values are computed as scale * (quantized value - zero point). A zero scale means the output is
+// not quantized.
+type ConfigurationQuantization struct {
+	Scale     float64 `toml:"scale"`
+	ZeroPoint float64 `toml:"zero_point"`
+}
+
+func (q ConfigurationQuantization) dequantize(v float64) float64 {
+	if q.Scale == 0 {
+		return v
+	}
+	return q.Scale * (v - q.ZeroPoint)
+}
+
+// flattenTensorValue flattens a tensor value of any numeric type and any number of dimensions, and
+// dequantizes it
+func flattenTensorValue(v interface{}, q ConfigurationQuantization) (fs []float32, err error) {
+	var walk func(v reflect.Value) error
+	walk = func(v reflect.Value) error {
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			for idx := 0; idx < v.Len(); idx++ {
+				if err := walk(v.Index(idx)); err != nil {
+					return err
+				}
+			}
+		case reflect.Float32, reflect.Float64:
+			fs = append(fs, float32(q.dequantize(v.Float())))
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fs = append(fs, float32(q.dequantize(float64(v.Int()))))
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fs = append(fs, float32(q.dequantize(float64(v.Uint()))))
+		default:
+			return fmt.Errorf("astiocr: invalid tensor value kind %s", v.Kind())
+		}
+		return nil
+	}
+	err = walk(reflect.ValueOf(v))
+	return
+}
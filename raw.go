@@ -0,0 +1,67 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/pkg/errors"
+)
+
+// RawOutput represents the raw outputs of a model, dequantized if needed. It contains all detections, even
+// the least probable ones, which is handy for custom decoding (e.g. ensembling).
+type RawOutput struct {
+	// Normalized boxes as [y1, x1, y2, x2]
+	Boxes         [][]float32
+	Classes       []float32
+	NumDetections int
+	Scores        []float32
+}
+
+// RawBackend represents a backend capable of returning its raw outputs
+type RawBackend interface {
+	DetectRaw(ctx context.Context, img image.Image) ([]DetectionResult, RawOutput, error)
+}
+
+// DetectRaw detects OCR on an image with the provided model ("" being the default model) and returns both
+// parsed results and the raw outputs of the model. Since raw outputs are the ones of a single inference,
+// the image is fed as is to the backend: preprocessing, regions, scales, tiles and post-processing are
+// skipped.
+func (d *Detector) DetectRaw(ctx context.Context, model string, img image.Image) (rs []DetectionResult, o RawOutput, err error) {
+	// Acquire backend
+	var b *backendRef
+	if b, err = d.acquireBackend(model); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
+		return
+	}
+	defer b.release()
+
+	// Detect
+	if rs, o, err = backendDetectRaw(ctx, b.b, d.limitInputSize(img)); err != nil {
+		err = inferenceError(err)
+		return
+	}
+	return
+}
+
+func backendDetectRaw(ctx context.Context, b Backend, img image.Image) ([]DetectionResult, RawOutput, error) {
+	rb, ok := b.(RawBackend)
+	if !ok {
+		return nil, RawOutput{}, fmt.Errorf("astiocr: backend %T doesn't return raw outputs", b)
+	}
+	return rb.DetectRaw(ctx, img)
+}
+
+// DetectRaw implements the RawBackend interface
+func (b *idleBackend) DetectRaw(ctx context.Context, img image.Image) (rs []DetectionResult, o RawOutput, err error) {
+	// Acquire backend
+	var bk Backend
+	if bk, err = b.acquire(); err != nil {
+		err = errors.Wrap(err, "astiocr: acquiring backend failed")
+		return
+	}
+	defer b.release()
+
+	// Detect
+	return backendDetectRaw(ctx, bk, img)
+}
@@ -0,0 +1,240 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	ft "golang.org/x/image/font"
+)
+
+// Receipt templates
+const (
+	receiptTemplateInvoice = "invoice"
+	receiptTemplateReceipt = "receipt"
+)
+
+// Receipt layout constants
+const (
+	// Maximum number of items
+	receiptMaxItems = 15
+	// Minimum number of items
+	receiptMinItems = 2
+	// Extra spacing between lines, as a proportion of the font size
+	receiptLineSpacing = 0.25
+	// Length of the dashes of separators, as a proportion of the font size
+	receiptDashLength = 0.3
+)
+
+// Receipt vocabularies
+var (
+	receiptCurrencies   = []string{"", "$", "€", "£"}
+	receiptDateFormats  = []string{"2006-01-02", "02/01/2006", "01/02/2006", "02.01.2006", "Jan 2, 2006"}
+	receiptInvoiceItems = []string{"Consulting", "Design", "Hosting", "Installation", "License", "Maintenance",
+		"Shipping", "Support", "Training", "Translation"}
+	receiptItems = []string{"Apples", "Bananas", "Bread", "Butter", "Cheese", "Chicken", "Coffee", "Cookies",
+		"Eggs", "Juice", "Milk", "Onions", "Pasta", "Rice", "Salad", "Soap", "Sugar", "Tea", "Tomatoes", "Water",
+		"Yogurt"}
+	receiptStores = []string{"City Grocery", "Corner Market", "Daily Mart", "Fresh Foods", "Green Cafe",
+		"Main Street Deli", "Sunny Bakery"}
+)
+
+// receiptCell represents a text aligned inside a column of a receipt, columns being proportions of the
+// receipt width
+type receiptCell struct {
+	alignment string
+	from, to  float64
+	text      string
+}
+
+// receipt draws the rows of a receipt from top to bottom
+type receipt struct {
+	ascent, descent int
+	fc              *faceCache
+	font            *font
+	fontColor       color.Color
+	img             *image.RGBA
+	margin          int
+	si              *GatherSummaryImage
+	space           int
+	t               *Trainer
+	upper           bool
+	width           int
+	y               int
+}
+
+// fits checks whether the number of rows starting at the current one fit in the image
+func (r *receipt) fits(rows int) bool {
+	return r.y+(rows-1)*r.lineHeight()+r.descent <= r.si.Height-r.margin
+}
+
+// lineHeight returns the distance between 2 rows
+func (r *receipt) lineHeight() int {
+	return r.ascent + r.descent + int(float64(r.fc.fontSize)*receiptLineSpacing)
+}
+
+// next moves to the next row
+func (r *receipt) next() {
+	r.y += r.lineHeight()
+}
+
+// row draws the cells on the current row, if it fits, and moves to the next one
+func (r *receipt) row(cs ...receiptCell) {
+	// Row doesn't fit
+	if !r.fits(1) {
+		return
+	}
+
+	// Loop through cells
+	for _, c := range cs {
+		// Get words
+		var ws []spacedWord
+		for _, w := range strings.Fields(c.text) {
+			if r.upper {
+				w = strings.ToUpper(w)
+			}
+			if w = r.t.filterCharset(w); w != "" {
+				ws = append(ws, r.t.spaceWord(r.fc, w, r.t.wordFonts(r.font, w)))
+			}
+		}
+
+		// Only keep the words fitting in the column
+		x, width := r.margin+int(c.from*float64(r.width)), int((c.to-c.from)*float64(r.width))
+		if ls := wrapWords(ws, width, r.space); len(ls) > 0 {
+			r.t.drawLine(r.img, r.fc, r.fontColor, x, r.y, width, r.space, ls[0], c.alignment, true, r.si)
+		}
+	}
+	r.next()
+}
+
+// separator draws a dashed line through the middle of the current row, if it fits, and moves to the next
+// one
+func (r *receipt) separator() {
+	// Row doesn't fit
+	if !r.fits(1) {
+		return
+	}
+
+	// Loop through dashes
+	y := float64(r.y-r.ascent/2) + 0.5
+	dash := math.Max(1, float64(r.fc.fontSize)*receiptDashLength)
+	thickness := math.Max(1, float64(r.fc.fontSize)*decorationThickness)
+	for x := float64(r.margin); x+dash <= float64(r.margin+r.width); x += 2 * dash {
+		ax, ay, bx, by := x, y, x+dash, y
+		if r.t.rotates() {
+			ax, ay = rotatePoint(ax, ay, r.si)
+			bx, by = rotatePoint(bx, by, r.si)
+		}
+		drawLine(r.img, ax, ay, bx, by, thickness, inkColor(r.fontColor, r.si))
+	}
+	r.next()
+}
+
+// createImageStrategyReceipt draws a receipt or an invoice with columns of items, quantities and prices,
+// dates and totals
+func (t *Trainer) createImageStrategyReceipt(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
+	fc := newFaceCache(fontSize)
+	ascent, descent := t.lineMetrics(fc, font)
+
+	// Create image
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
+	r := &receipt{
+		ascent:    ascent,
+		descent:   descent,
+		fc:        fc,
+		font:      font,
+		fontColor: fontColor,
+		img:       img,
+		margin:    fontSize,
+		si:        &si,
+		space:     ft.MeasureString(fc.face(font), " ").Ceil(),
+		t:         t,
+		upper:     rand.Intn(2) == 0,
+		width:     t.image.Width - 2*fontSize,
+		y:         fontSize + ascent,
+	}
+	if r.width <= 0 {
+		return
+	}
+
+	// Pick formats
+	currency := receiptCurrencies[rand.Intn(len(receiptCurrencies))]
+	decimal := []string{".", ","}[rand.Intn(2)]
+	price := func(cents int) string {
+		return currency + strings.Replace(fmt.Sprintf("%d.%02d", cents/100, cents%100), ".", decimal, 1)
+	}
+	date := time.Date(2000+rand.Intn(30), time.Month(1+rand.Intn(12)), 1+rand.Intn(28), rand.Intn(24), rand.Intn(60), 0, 0, time.UTC)
+	store := receiptStores[rand.Intn(len(receiptStores))]
+
+	// Draw header
+	template := []string{receiptTemplateInvoice, receiptTemplateReceipt}[rand.Intn(2)]
+	switch template {
+	case receiptTemplateInvoice:
+		r.row(receiptCell{alignment: alignmentLeft, to: 0.6, text: store}, receiptCell{alignment: alignmentRight, from: 0.6, to: 1, text: "Invoice"})
+		r.row(receiptCell{alignment: alignmentLeft, to: 0.6, text: fmt.Sprintf("No %06d", rand.Intn(1000000))}, receiptCell{alignment: alignmentRight, from: 0.6, to: 1, text: date.Format(receiptDateFormats[rand.Intn(len(receiptDateFormats))])})
+		r.next()
+		r.row(
+			receiptCell{alignment: alignmentLeft, to: 0.4, text: "Description"},
+			receiptCell{alignment: alignmentRight, from: 0.4, to: 0.5, text: "Qty"},
+			receiptCell{alignment: alignmentRight, from: 0.5, to: 0.75, text: "Price"},
+			receiptCell{alignment: alignmentRight, from: 0.75, to: 1, text: "Amount"},
+		)
+	default:
+		r.row(receiptCell{alignment: alignmentCenter, to: 1, text: store})
+		r.row(receiptCell{alignment: alignmentLeft, to: 0.6, text: date.Format(receiptDateFormats[rand.Intn(len(receiptDateFormats))])}, receiptCell{alignment: alignmentRight, from: 0.6, to: 1, text: date.Format("15:04")})
+	}
+	r.separator()
+
+	// Loop through items while keeping room for the separator and the totals
+	var subtotal int
+	for idx, count := 0, receiptMinItems+rand.Intn(receiptMaxItems-receiptMinItems+1); idx < count && r.fits(5); idx++ {
+		quantity, cents := 1+rand.Intn(5), 50+rand.Intn(5000)
+		switch template {
+		case receiptTemplateInvoice:
+			// Services are more expensive than groceries
+			cents *= 10
+			r.row(
+				receiptCell{alignment: alignmentLeft, to: 0.4, text: receiptInvoiceItems[rand.Intn(len(receiptInvoiceItems))]},
+				receiptCell{alignment: alignmentRight, from: 0.4, to: 0.5, text: fmt.Sprintf("%d", quantity)},
+				receiptCell{alignment: alignmentRight, from: 0.5, to: 0.75, text: price(cents)},
+				receiptCell{alignment: alignmentRight, from: 0.75, to: 1, text: price(quantity * cents)},
+			)
+		default:
+			name := receiptItems[rand.Intn(len(receiptItems))]
+			if quantity > 1 {
+				name = fmt.Sprintf("%d x %s", quantity, name)
+			}
+			r.row(receiptCell{alignment: alignmentLeft, to: 0.7, text: name}, receiptCell{alignment: alignmentRight, from: 0.7, to: 1, text: price(quantity * cents)})
+		}
+		subtotal += quantity * cents
+	}
+
+	// Draw totals
+	r.separator()
+	rate := []int{5, 10, 20}[rand.Intn(3)]
+	tax := subtotal * rate / 100
+	for _, l := range [][2]string{
+		{"Subtotal", price(subtotal)},
+		{fmt.Sprintf("Tax %d%%", rate), price(tax)},
+		{"Total", price(subtotal + tax)},
+	} {
+		r.row(receiptCell{alignment: alignmentLeft, from: 0.3, to: 0.7, text: l[0]}, receiptCell{alignment: alignmentRight, from: 0.7, to: 1, text: l[1]})
+	}
+	return
+}
+
+// filterCharset removes the characters of the string that are not in the charset
+func (t *Trainer) filterCharset(s string) string {
+	return strings.Map(func(r rune) rune {
+		if t.labelIndex(r) == 0 {
+			return -1
+		}
+		return r
+	}, s)
+}
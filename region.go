@@ -0,0 +1,87 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigurationRegion represents a region of interest
+// Coordinates are normalized unless Pixels is true, in which case they are in pixels.
+type ConfigurationRegion struct {
+	Pixels bool    `toml:"pixels"`
+	X1     float64 `toml:"x1"`
+	X2     float64 `toml:"x2"`
+	Y1     float64 `toml:"y1"`
+	Y2     float64 `toml:"y2"`
+}
+
+func (r ConfigurationRegion) validate() error {
+	if r.X1 < 0 || r.Y1 < 0 || r.X1 >= r.X2 || r.Y1 >= r.Y2 || (!r.Pixels && (r.X2 > 1 || r.Y2 > 1)) {
+		return fmt.Errorf("astiocr: invalid region coordinates %v, %v, %v, %v", r.X1, r.X2, r.Y1, r.Y2)
+	}
+	return nil
+}
+
+// rect returns the region pixel rectangle within the bounds
+func (r ConfigurationRegion) rect(b image.Rectangle) image.Rectangle {
+	x1, x2, y1, y2 := r.X1, r.X2, r.Y1, r.Y2
+	if !r.Pixels {
+		x1, x2 = x1*float64(b.Dx()), x2*float64(b.Dx())
+		y1, y2 = y1*float64(b.Dy()), y2*float64(b.Dy())
+	}
+	return image.Rect(
+		b.Min.X+int(math.Floor(x1)),
+		b.Min.Y+int(math.Floor(y1)),
+		b.Min.X+int(math.Ceil(x2)),
+		b.Min.Y+int(math.Ceil(y2)),
+	).Intersect(b)
+}
+
+// detectRegions only analyzes the configured regions of the image and returns boxes relative to the
+// full image. The whole image is analyzed if no region is configured.
+func (d *Detector) detectRegions(ctx context.Context, bk Backend, img image.Image) (rs []DetectionResult, err error) {
+	// No regions
+	if len(d.regions) == 0 {
+		return d.detectImage(ctx, bk, img)
+	}
+
+	// Loop through regions
+	b := img.Bounds()
+	for _, region := range d.regions {
+		// Get rectangle
+		r := region.rect(b)
+		if r.Empty() {
+			continue
+		}
+
+		// Detect
+		var srs []DetectionResult
+		if srs, err = d.detectImage(ctx, bk, subImage(img, r)); err != nil {
+			err = errors.Wrapf(err, "astiocr: detecting in region %s failed", r)
+			return
+		}
+
+		// Make boxes relative to the full image
+		x1, y1 := float64(r.Min.X-b.Min.X)/float64(b.Dx()), float64(r.Min.Y-b.Min.Y)/float64(b.Dy())
+		w, h := float64(r.Dx())/float64(b.Dx()), float64(r.Dy())/float64(b.Dy())
+		for _, sr := range srs {
+			sr.Box = DetectionBox{
+				X1: x1 + sr.Box.X1*w,
+				X2: x1 + sr.Box.X2*w,
+				Y1: y1 + sr.Box.Y1*h,
+				Y2: y1 + sr.Box.Y2*h,
+			}
+			rs = append(rs, sr)
+		}
+	}
+
+	// Regions may overlap
+	if len(d.regions) > 1 {
+		rs = mergeResults(rs, mergeIoUThreshold)
+	}
+	return
+}
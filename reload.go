@@ -0,0 +1,143 @@
+package astiocr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// backendRef keeps track of detections using a backend so that it is only closed once they're done
+type backendRef struct {
+	b  Backend
+	wg *sync.WaitGroup
+}
+
+func newBackendRef(b Backend) *backendRef {
+	return &backendRef{
+		b:  b,
+		wg: &sync.WaitGroup{},
+	}
+}
+
+func (d *Detector) acquireBackend(model string) (r *backendRef, err error) {
+	// Lock
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	// Get backend
+	r = d.r
+	if len(model) > 0 {
+		var ok bool
+		if r, ok = d.named[model]; !ok {
+			err = withKind(ErrModelNotFound, fmt.Errorf("astiocr: unknown model %s", model))
+			return
+		}
+	}
+	r.wg.Add(1)
+	return
+}
+
+func (r *backendRef) release() {
+	r.wg.Done()
+}
+
+// Reload loads the model located at modelPath, which is downloaded first if it is an http(s) url, or the
+// current model if modelPath is empty, and atomically swaps it with the current default model. The model is
+// checked against i, or against the integrity of the current model if modelPath and i are empty.
+// Detections in progress finish with the previous model which is closed once they're done. Reload returns
+// once the previous model is closed or the context is done, in which case the previous model is still
+// closed in the background.
+func (d *Detector) Reload(ctx context.Context, modelPath string, i ConfigurationIntegrity) error {
+	return d.ReloadModel(ctx, "", modelPath, i)
+}
+
+// ReloadModel is the same as Reload for the named model. An empty name selects the default model.
+func (d *Detector) ReloadModel(ctx context.Context, name, modelPath string, i ConfigurationIntegrity) (err error) {
+	// Get configuration
+	d.m.Lock()
+	c := d.c
+	_, ok := d.named[name]
+	d.m.Unlock()
+	if len(name) > 0 && !ok {
+		err = withKind(ErrModelNotFound, fmt.Errorf("astiocr: unknown model %s", name))
+		return
+	}
+
+	// Get the configuration of the model. Named models don't use the ensemble, see NewDetector.
+	mc := c
+	if len(name) > 0 {
+		mc.Ensemble = ConfigurationEnsemble{}
+		mc.ModelIntegrity = c.ModelsIntegrity[name]
+		mc.ModelPath = c.Models[name]
+	}
+
+	// Update configuration
+	if len(modelPath) > 0 {
+		mc.ModelPath = modelPath
+		mc.ModelIntegrity = i
+	} else if i.enabled() {
+		mc.ModelIntegrity = i
+	}
+
+	// Prepare model
+	if mc.ModelPath, err = prepareModel(ctx, d.l, mc, mc.ModelPath, mc.ModelIntegrity); err != nil {
+		err = errors.Wrap(err, "astiocr: preparing model failed")
+		return
+	}
+
+	// Create backend
+	var b Backend
+	if b, err = newDetectorBackend(mc); err != nil {
+		err = errors.Wrapf(err, "astiocr: creating backend for %s failed", mc.ModelPath)
+		return
+	}
+
+	// Swap. Maps are copied since the previous configuration may still be read.
+	d.m.Lock()
+	var old *backendRef
+	if len(name) > 0 {
+		old = d.named[name]
+		d.named[name] = newBackendRef(b)
+		ms := make(map[string]string, len(d.c.Models))
+		for n, p := range d.c.Models {
+			ms[n] = p
+		}
+		ms[name] = mc.ModelPath
+		is := make(map[string]ConfigurationIntegrity, len(d.c.ModelsIntegrity)+1)
+		for n, i := range d.c.ModelsIntegrity {
+			is[n] = i
+		}
+		is[name] = mc.ModelIntegrity
+		d.c.Models = ms
+		d.c.ModelsIntegrity = is
+	} else {
+		old = d.r
+		d.c.ModelIntegrity = mc.ModelIntegrity
+		d.c.ModelPath = mc.ModelPath
+		d.r = newBackendRef(b)
+	}
+	d.m.Unlock()
+	d.l.Debugf("astiocr: reloaded model %s", mc.ModelPath)
+
+	// Close previous backend once detections in progress are done
+	done := make(chan error, 1)
+	go func() {
+		old.wg.Wait()
+		done <- old.b.Close()
+	}()
+
+	// Wait
+	select {
+	case err = <-done:
+		if err != nil {
+			err = errors.Wrap(err, "astiocr: closing previous backend failed")
+			return
+		}
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "astiocr: context error")
+		return
+	}
+	return
+}
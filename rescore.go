@@ -0,0 +1,175 @@
+package astiocr
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Default proportion of results that must differ for an image to be considered as changed
+const defaultRescoreThreshold = 0.1
+
+// DetectionReport represents stored detection results of an image
+type DetectionReport struct {
+	Image   string            `json:"image"`
+	Results []DetectionResult `json:"results"`
+}
+
+// MigrationReport represents the outcome of a rescoring
+type MigrationReport struct {
+	Changed   []MigrationReportImage `json:"changed"`
+	Failed    map[string]string      `json:"failed,omitempty"`
+	Unchanged int                    `json:"unchanged"`
+}
+
+// MigrationReportImage represents an image whose results changed materially
+type MigrationReportImage struct {
+	// Number of new results not matching any previous result
+	Added int `json:"added"`
+	// Proportion of results that differ
+	Difference float64 `json:"difference"`
+	Image      string  `json:"image"`
+	// Number of previous results not matching any new result
+	Removed int    `json:"removed"`
+	Report  string `json:"report"`
+}
+
+// RescoreOptions represents rescore options
+type RescoreOptions struct {
+	// Proportion of results that must differ for an image to be considered as changed. Default is 0.1.
+	Threshold float64
+	// If true, reports of changed images are overwritten with the new results
+	Write bool
+}
+
+// Rescore runs the detector on the image of each detection report (json files) located in the
+// directory, and reports images whose results changed materially. Only reports of those images are
+// overwritten, which means downstream indexes only need to be updated for them.
+func Rescore(ctx context.Context, d *Detector, dirPath string, o RescoreOptions) (r MigrationReport, err error) {
+	// Default options
+	if o.Threshold <= 0 {
+		o.Threshold = defaultRescoreThreshold
+	}
+
+	// Read dir
+	var fis []os.FileInfo
+	if fis, err = ioutil.ReadDir(dirPath); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading dir %s failed", dirPath)
+		return
+	}
+
+	// Loop through reports
+	r.Failed = make(map[string]string)
+	for _, fi := range fis {
+		// Check context
+		if err = ctx.Err(); err != nil {
+			err = errors.Wrap(err, "astiocr: context error")
+			return
+		}
+
+		// Only process reports
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+
+		// Rescore
+		p := filepath.Join(dirPath, fi.Name())
+		var i MigrationReportImage
+		var changed bool
+		if i, changed, err = rescoreReport(ctx, d, p, o); err != nil {
+			// Context error
+			if ctx.Err() != nil {
+				err = errors.Wrap(ctx.Err(), "astiocr: context error")
+				return
+			}
+
+			// A single report failing shouldn't interrupt the whole rescoring
+			d.l.Error(errors.Wrapf(err, "astiocr: rescoring %s failed", p))
+			r.Failed[p] = err.Error()
+			err = nil
+			continue
+		}
+
+		// Update report
+		if changed {
+			r.Changed = append(r.Changed, i)
+		} else {
+			r.Unchanged++
+		}
+	}
+	return
+}
+
+func rescoreReport(ctx context.Context, d *Detector, p string, o RescoreOptions) (i MigrationReportImage, changed bool, err error) {
+	// Read report
+	var b []byte
+	if b, err = ioutil.ReadFile(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: reading %s failed", p)
+		return
+	}
+	var dr DetectionReport
+	if err = json.Unmarshal(b, &dr); err != nil {
+		err = errors.Wrapf(err, "astiocr: unmarshaling %s failed", p)
+		return
+	}
+
+	// Image paths are relative to the report
+	src := dr.Image
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(filepath.Dir(p), src)
+	}
+
+	// Detect
+	var rs []DetectionResult
+	if rs, err = d.Detect(ctx, src); err != nil {
+		err = errors.Wrapf(err, "astiocr: detecting in %s failed", src)
+		return
+	}
+
+	// Compare
+	i = MigrationReportImage{Image: dr.Image, Report: p}
+	i.Added, i.Removed = diffResults(dr.Results, rs)
+	if total := len(dr.Results) + len(rs); total > 0 {
+		i.Difference = float64(i.Added+i.Removed) / float64(total)
+	}
+	if changed = i.Difference >= o.Threshold; !changed || !o.Write {
+		return
+	}
+
+	// Write report
+	dr.Results = rs
+	if b, err = json.MarshalIndent(dr, "", "  "); err != nil {
+		err = errors.Wrap(err, "astiocr: marshaling report failed")
+		return
+	}
+	if err = ioutil.WriteFile(p, b, 0644); err != nil {
+		err = errors.Wrapf(err, "astiocr: writing %s failed", p)
+		return
+	}
+	return
+}
+
+// diffResults counts results with no match among the other results, results matching when they have the
+// same label and overlap above the merge threshold
+func diffResults(before, after []DetectionResult) (added, removed int) {
+	matched := make(map[int]bool)
+	for _, b := range before {
+		found := false
+		for idx, a := range after {
+			if !matched[idx] && a.Label == b.Label && a.Box.IoU(b.Box) > mergeIoUThreshold {
+				matched[idx] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed++
+		}
+	}
+	added = len(after) - len(matched)
+	return
+}
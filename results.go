@@ -0,0 +1,89 @@
+package astiocr
+
+import "sort"
+
+// Results represents detection results with chainable helpers. Helpers never modify the results they're
+// called on.
+type Results []DetectionResult
+
+// FilterByLabel keeps results whose label is one of the provided labels
+func (rs Results) FilterByLabel(labels ...string) (o Results) {
+	ls := make(map[string]bool)
+	for _, l := range labels {
+		ls[l] = true
+	}
+	o = Results{}
+	for _, r := range rs {
+		if ls[r.Label] {
+			o = append(o, r)
+		}
+	}
+	return
+}
+
+// FilterByProbability keeps results whose probability is at least p
+func (rs Results) FilterByProbability(p float64) (o Results) {
+	o = Results{}
+	for _, r := range rs {
+		if r.Probability >= p {
+			o = append(o, r)
+		}
+	}
+	return
+}
+
+// GroupByLine groups results into lines, sorted from top to bottom, whose results are sorted from left
+// to right. Lines are detected the same way as when assembling documents, but since the image dimensions
+// are unknown, the direction of tilted lines is estimated on normalized coordinates.
+func (rs Results) GroupByLine() (ls []Results) {
+	// Get boxes
+	var bs []DetectionBox
+	for _, r := range rs {
+		bs = append(bs, r.Box)
+	}
+
+	// Loop through lines
+	for _, idxs := range groupLineIndexes(bs, 1, 1) {
+		var l Results
+		for _, idx := range idxs {
+			l = append(l, rs[idx])
+		}
+		sort.SliceStable(l, func(i, j int) bool { return l[i].Box.X1 < l[j].Box.X1 })
+		ls = append(ls, l)
+	}
+	return
+}
+
+// SortByConfidence sorts results from the most to the least probable
+func (rs Results) SortByConfidence() (o Results) {
+	o = rs.copy()
+	sort.SliceStable(o, func(i, j int) bool { return o[i].Probability > o[j].Probability })
+	return
+}
+
+// SortByReadingOrder sorts results line by line from top to bottom, and from left to right within a line
+func (rs Results) SortByReadingOrder() (o Results) {
+	o = Results{}
+	for _, l := range rs.GroupByLine() {
+		o = append(o, l...)
+	}
+	return
+}
+
+// TopK keeps the k most probable results
+func (rs Results) TopK(k int) Results {
+	o := rs.SortByConfidence()
+	if k < 0 {
+		k = 0
+	}
+	if k < len(o) {
+		o = o[:k]
+	}
+	return o
+}
+
+func (rs Results) copy() Results {
+	o := make(Results, len(rs))
+	copy(o, rs)
+	return o
+}
@@ -0,0 +1,79 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"golang.org/x/image/draw"
+	ft "golang.org/x/image/font"
+)
+
+// rotates checks whether text is rotated
+func (t *Trainer) rotates() bool {
+	return t.rotation != ConfigurationRotation{}
+}
+
+// randomImageAngle returns a random rotation of the text of an image, in degrees counterclockwise
+func (t *Trainer) randomImageAngle() float64 {
+	return t.rotation.ImageMin + rand.Float64()*(t.rotation.ImageMax-t.rotation.ImageMin)
+}
+
+// rotatePoint rotates the point around the image center by the image angle
+func rotatePoint(x, y float64, si *GatherSummaryImage) (float64, float64) {
+	sin, cos := math.Sincos(-si.Angle * math.Pi / 180)
+	cx, cy := float64(si.Width)/2, float64(si.Height)/2
+	dx, dy := x-cx, y-cy
+	return cx + cos*dx - sin*dy, cy + sin*dx + cos*dy
+}
+
+// rotateGlyph rotates the glyph around the image center by the image angle, and rotates it around its
+// own baseline middle by a random character angle
+func (t *Trainer) rotateGlyph(g rotatedGlyph, si *GatherSummaryImage) rotatedGlyph {
+	// Rotate position
+	g.x, g.y = rotatePoint(g.x, g.y, si)
+
+	// Rotate glyph
+	g.angle += -si.Angle*math.Pi/180 - (t.rotation.CharacterMin+rand.Float64()*(t.rotation.CharacterMax-t.rotation.CharacterMin))*math.Pi/180
+	return g
+}
+
+// drawGlyph draws the glyph and returns its tight box, unless it's not entirely inside the image in which
+// case nothing is drawn since its box would be truncated
+func drawGlyph(img draw.Image, g rotatedGlyph, fontColor color.Color, si *GatherSummaryImage) image.Rectangle {
+	if !g.bounds().In(img.Bounds()) {
+		return image.Rectangle{}
+	}
+	return g.draw(img, fontColor, si)
+}
+
+// drawRotatedCharacter draws the character of the charset at the index at the same position as
+// drawCharacter, but rotated
+func (t *Trainer) drawRotatedCharacter(img draw.Image, fontColor color.Color, font *font, fontSize, col, row, charIdx int, si *GatherSummaryImage) (char string, b image.Rectangle, angle float64) {
+	// Get character
+	r := t.charset[charIdx]
+	char = string(r)
+
+	// Get glyph
+	face := newFace(font, fontSize)
+	dot := characterDot(font, fontSize, col, row)
+	g := t.rotatedGlyph(face, font, r, float64(dot.X)/64, float64(dot.Y)/64, si)
+
+	// Draw glyph
+	b = drawGlyph(img, g, fontColor, si)
+	angle = -g.angle * 180 / math.Pi
+	return
+}
+
+// rotatedGlyph returns the glyph whose dot is located at (x, y) before rotation, rotated
+func (t *Trainer) rotatedGlyph(face ft.Face, font *font, r rune, x, y float64, si *GatherSummaryImage) rotatedGlyph {
+	adv, _ := face.GlyphAdvance(r)
+	return t.rotateGlyph(rotatedGlyph{
+		face: face,
+		font: font,
+		r:    r,
+		x:    x + float64(adv)/64/2,
+		y:    y,
+	}, si)
+}
@@ -31,7 +31,7 @@ def create_tf_record(image):
         xmaxs.append(float(box['x1'] / width))
         ymins.append(float(box['y0'] / height))
         ymaxs.append(float(box['y1'] / height))
-        classes_text.append(box['label'].encode())
+        classes_text.append(box['label'].encode('utf8'))
         classes.append(box['label_index'])
 
     # Generate record
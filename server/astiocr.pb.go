@@ -0,0 +1,585 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v3.21.12
+// source: astiocr.proto
+
+package server
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DetectRequest represents a detect request
+type DetectRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Id is sent back untouched in the response
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Image is the encoded image (bmp, jpeg or png)
+	Image []byte `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	// Model is the name of the model to use. The default model is used if empty.
+	Model string `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+}
+
+func (x *DetectRequest) Reset() {
+	*x = DetectRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DetectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetectRequest) ProtoMessage() {}
+
+func (x *DetectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetectRequest.ProtoReflect.Descriptor instead.
+func (*DetectRequest) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DetectRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DetectRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *DetectRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+// DetectResponse represents a detect response
+type DetectResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Results []*DetectionResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	// Error is only set in batch mode when a single image failed
+	Error string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DetectResponse) Reset() {
+	*x = DetectResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DetectResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetectResponse) ProtoMessage() {}
+
+func (x *DetectResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetectResponse.ProtoReflect.Descriptor instead.
+func (*DetectResponse) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DetectResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DetectResponse) GetResults() []*DetectionResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *DetectResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// DetectionResult represents a detection result
+type DetectionResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Box         *DetectionBox `protobuf:"bytes,1,opt,name=box,proto3" json:"box,omitempty"`
+	Label       string        `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Probability float64       `protobuf:"fixed64,3,opt,name=probability,proto3" json:"probability,omitempty"`
+	// Angle is the estimated rotation of the box content in degrees, counterclockwise
+	Angle float64 `protobuf:"fixed64,4,opt,name=angle,proto3" json:"angle,omitempty"`
+	// Quality flags are the reasons why the image failed the quality gate in flag mode
+	QualityFlags []string `protobuf:"bytes,5,rep,name=quality_flags,json=qualityFlags,proto3" json:"quality_flags,omitempty"`
+}
+
+func (x *DetectionResult) Reset() {
+	*x = DetectionResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DetectionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetectionResult) ProtoMessage() {}
+
+func (x *DetectionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetectionResult.ProtoReflect.Descriptor instead.
+func (*DetectionResult) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DetectionResult) GetBox() *DetectionBox {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+func (x *DetectionResult) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *DetectionResult) GetProbability() float64 {
+	if x != nil {
+		return x.Probability
+	}
+	return 0
+}
+
+func (x *DetectionResult) GetAngle() float64 {
+	if x != nil {
+		return x.Angle
+	}
+	return 0
+}
+
+func (x *DetectionResult) GetQualityFlags() []string {
+	if x != nil {
+		return x.QualityFlags
+	}
+	return nil
+}
+
+// DetectionBox represents a detection box
+type DetectionBox struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X1 float64 `protobuf:"fixed64,1,opt,name=x1,proto3" json:"x1,omitempty"`
+	X2 float64 `protobuf:"fixed64,2,opt,name=x2,proto3" json:"x2,omitempty"`
+	Y1 float64 `protobuf:"fixed64,3,opt,name=y1,proto3" json:"y1,omitempty"`
+	Y2 float64 `protobuf:"fixed64,4,opt,name=y2,proto3" json:"y2,omitempty"`
+}
+
+func (x *DetectionBox) Reset() {
+	*x = DetectionBox{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DetectionBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetectionBox) ProtoMessage() {}
+
+func (x *DetectionBox) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetectionBox.ProtoReflect.Descriptor instead.
+func (*DetectionBox) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DetectionBox) GetX1() float64 {
+	if x != nil {
+		return x.X1
+	}
+	return 0
+}
+
+func (x *DetectionBox) GetX2() float64 {
+	if x != nil {
+		return x.X2
+	}
+	return 0
+}
+
+func (x *DetectionBox) GetY1() float64 {
+	if x != nil {
+		return x.Y1
+	}
+	return 0
+}
+
+func (x *DetectionBox) GetY2() float64 {
+	if x != nil {
+		return x.Y2
+	}
+	return 0
+}
+
+// ReloadRequest represents a reload request
+type ReloadRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Model is the name of the model to reload. The default model is reloaded if empty.
+	Model string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+}
+
+func (x *ReloadRequest) Reset() {
+	*x = ReloadRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadRequest) ProtoMessage() {}
+
+func (x *ReloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadRequest.ProtoReflect.Descriptor instead.
+func (*ReloadRequest) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReloadRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+// ReloadResponse represents a reload response
+type ReloadResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReloadResponse) Reset() {
+	*x = ReloadResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_astiocr_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadResponse) ProtoMessage() {}
+
+func (x *ReloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_astiocr_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadResponse.ProtoReflect.Descriptor instead.
+func (*ReloadResponse) Descriptor() ([]byte, []int) {
+	return file_astiocr_proto_rawDescGZIP(), []int{5}
+}
+
+var File_astiocr_proto protoreflect.FileDescriptor
+
+var file_astiocr_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x22, 0x4b, 0x0a, 0x0d, 0x44, 0x65, 0x74, 0x65,
+	0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x22, 0x6a, 0x0a, 0x0e, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x32, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x73, 0x74, 0x69, 0x6f,
+	0x63, 0x72, 0x2e, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0xad, 0x01, 0x0a, 0x0f, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x27, 0x0a, 0x03, 0x62, 0x6f, 0x78, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x44, 0x65, 0x74,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x6f, 0x78, 0x52, 0x03, 0x62, 0x6f, 0x78, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c,
+	0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x70, 0x72, 0x6f, 0x62, 0x61,
+	0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6e, 0x67, 0x6c, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x61, 0x6e, 0x67, 0x6c, 0x65, 0x12, 0x23, 0x0a, 0x0d,
+	0x71, 0x75, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x5f, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0c, 0x71, 0x75, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x46, 0x6c, 0x61, 0x67,
+	0x73, 0x22, 0x4e, 0x0a, 0x0c, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x6f,
+	0x78, 0x12, 0x0e, 0x0a, 0x02, 0x78, 0x31, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x78,
+	0x31, 0x12, 0x0e, 0x0a, 0x02, 0x78, 0x32, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x78,
+	0x32, 0x12, 0x0e, 0x0a, 0x02, 0x79, 0x31, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x79,
+	0x31, 0x12, 0x0e, 0x0a, 0x02, 0x79, 0x32, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x79,
+	0x32, 0x22, 0x25, 0x0a, 0x0d, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x22, 0x10, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x6f,
+	0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xca, 0x01, 0x0a, 0x08, 0x44,
+	0x65, 0x74, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x3b, 0x0a, 0x06, 0x44, 0x65, 0x74, 0x65, 0x63,
+	0x74, 0x12, 0x16, 0x2e, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x44, 0x65, 0x74, 0x65,
+	0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x61, 0x73, 0x74, 0x69,
+	0x6f, 0x63, 0x72, 0x2e, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x44, 0x0a, 0x0b, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x12, 0x16, 0x2e, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x44, 0x65,
+	0x74, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x61, 0x73,
+	0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x12, 0x3b, 0x0a, 0x06, 0x52, 0x65,
+	0x6c, 0x6f, 0x61, 0x64, 0x12, 0x16, 0x2e, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x61,
+	0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2e, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x73, 0x74, 0x69, 0x63, 0x6f, 0x64, 0x65, 0x2f, 0x67,
+	0x6f, 0x2d, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x63, 0x72, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_astiocr_proto_rawDescOnce sync.Once
+	file_astiocr_proto_rawDescData = file_astiocr_proto_rawDesc
+)
+
+func file_astiocr_proto_rawDescGZIP() []byte {
+	file_astiocr_proto_rawDescOnce.Do(func() {
+		file_astiocr_proto_rawDescData = protoimpl.X.CompressGZIP(file_astiocr_proto_rawDescData)
+	})
+	return file_astiocr_proto_rawDescData
+}
+
+var file_astiocr_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_astiocr_proto_goTypes = []any{
+	(*DetectRequest)(nil),   // 0: astiocr.DetectRequest
+	(*DetectResponse)(nil),  // 1: astiocr.DetectResponse
+	(*DetectionResult)(nil), // 2: astiocr.DetectionResult
+	(*DetectionBox)(nil),    // 3: astiocr.DetectionBox
+	(*ReloadRequest)(nil),   // 4: astiocr.ReloadRequest
+	(*ReloadResponse)(nil),  // 5: astiocr.ReloadResponse
+}
+var file_astiocr_proto_depIdxs = []int32{
+	2, // 0: astiocr.DetectResponse.results:type_name -> astiocr.DetectionResult
+	3, // 1: astiocr.DetectionResult.box:type_name -> astiocr.DetectionBox
+	0, // 2: astiocr.Detector.Detect:input_type -> astiocr.DetectRequest
+	0, // 3: astiocr.Detector.DetectBatch:input_type -> astiocr.DetectRequest
+	4, // 4: astiocr.Detector.Reload:input_type -> astiocr.ReloadRequest
+	1, // 5: astiocr.Detector.Detect:output_type -> astiocr.DetectResponse
+	1, // 6: astiocr.Detector.DetectBatch:output_type -> astiocr.DetectResponse
+	5, // 7: astiocr.Detector.Reload:output_type -> astiocr.ReloadResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_astiocr_proto_init() }
+func file_astiocr_proto_init() {
+	if File_astiocr_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_astiocr_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*DetectRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_astiocr_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*DetectResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_astiocr_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*DetectionResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_astiocr_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*DetectionBox); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_astiocr_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*ReloadRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_astiocr_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*ReloadResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_astiocr_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_astiocr_proto_goTypes,
+		DependencyIndexes: file_astiocr_proto_depIdxs,
+		MessageInfos:      file_astiocr_proto_msgTypes,
+	}.Build()
+	File_astiocr_proto = out.File
+	file_astiocr_proto_rawDesc = nil
+	file_astiocr_proto_goTypes = nil
+	file_astiocr_proto_depIdxs = nil
+}
@@ -0,0 +1,222 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: astiocr.proto
+
+package server
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Detector_Detect_FullMethodName      = "/astiocr.Detector/Detect"
+	Detector_DetectBatch_FullMethodName = "/astiocr.Detector/DetectBatch"
+	Detector_Reload_FullMethodName      = "/astiocr.Detector/Reload"
+)
+
+// DetectorClient is the client API for Detector service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DetectorClient interface {
+	// Detect detects OCR on a single image
+	Detect(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error)
+	// DetectBatch detects OCR on a stream of images and streams back results in the same order
+	DetectBatch(ctx context.Context, opts ...grpc.CallOption) (Detector_DetectBatchClient, error)
+	// Reload reloads a model from its configured path, detections in progress finishing with the previous model
+	Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error)
+}
+
+type detectorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDetectorClient(cc grpc.ClientConnInterface) DetectorClient {
+	return &detectorClient{cc}
+}
+
+func (c *detectorClient) Detect(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error) {
+	out := new(DetectResponse)
+	err := c.cc.Invoke(ctx, Detector_Detect_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *detectorClient) DetectBatch(ctx context.Context, opts ...grpc.CallOption) (Detector_DetectBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Detector_ServiceDesc.Streams[0], Detector_DetectBatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &detectorDetectBatchClient{stream}
+	return x, nil
+}
+
+type Detector_DetectBatchClient interface {
+	Send(*DetectRequest) error
+	Recv() (*DetectResponse, error)
+	grpc.ClientStream
+}
+
+type detectorDetectBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *detectorDetectBatchClient) Send(m *DetectRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *detectorDetectBatchClient) Recv() (*DetectResponse, error) {
+	m := new(DetectResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *detectorClient) Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error) {
+	out := new(ReloadResponse)
+	err := c.cc.Invoke(ctx, Detector_Reload_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DetectorServer is the server API for Detector service.
+// All implementations must embed UnimplementedDetectorServer
+// for forward compatibility
+type DetectorServer interface {
+	// Detect detects OCR on a single image
+	Detect(context.Context, *DetectRequest) (*DetectResponse, error)
+	// DetectBatch detects OCR on a stream of images and streams back results in the same order
+	DetectBatch(Detector_DetectBatchServer) error
+	// Reload reloads a model from its configured path, detections in progress finishing with the previous model
+	Reload(context.Context, *ReloadRequest) (*ReloadResponse, error)
+	mustEmbedUnimplementedDetectorServer()
+}
+
+// UnimplementedDetectorServer must be embedded to have forward compatible implementations.
+type UnimplementedDetectorServer struct {
+}
+
+func (UnimplementedDetectorServer) Detect(context.Context, *DetectRequest) (*DetectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Detect not implemented")
+}
+func (UnimplementedDetectorServer) DetectBatch(Detector_DetectBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method DetectBatch not implemented")
+}
+func (UnimplementedDetectorServer) Reload(context.Context, *ReloadRequest) (*ReloadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
+}
+func (UnimplementedDetectorServer) mustEmbedUnimplementedDetectorServer() {}
+
+// UnsafeDetectorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DetectorServer will
+// result in compilation errors.
+type UnsafeDetectorServer interface {
+	mustEmbedUnimplementedDetectorServer()
+}
+
+func RegisterDetectorServer(s grpc.ServiceRegistrar, srv DetectorServer) {
+	s.RegisterService(&Detector_ServiceDesc, srv)
+}
+
+func _Detector_Detect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetectorServer).Detect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Detector_Detect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetectorServer).Detect(ctx, req.(*DetectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Detector_DetectBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DetectorServer).DetectBatch(&detectorDetectBatchServer{stream})
+}
+
+type Detector_DetectBatchServer interface {
+	Send(*DetectResponse) error
+	Recv() (*DetectRequest, error)
+	grpc.ServerStream
+}
+
+type detectorDetectBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *detectorDetectBatchServer) Send(m *DetectResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *detectorDetectBatchServer) Recv() (*DetectRequest, error) {
+	m := new(DetectRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Detector_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetectorServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Detector_Reload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetectorServer).Reload(ctx, req.(*ReloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Detector_ServiceDesc is the grpc.ServiceDesc for Detector service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Detector_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "astiocr.Detector",
+	HandlerType: (*DetectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Detect",
+			Handler:    _Detector_Detect_Handler,
+		},
+		{
+			MethodName: "Reload",
+			Handler:    _Detector_Reload_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DetectBatch",
+			Handler:       _Detector_DetectBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "astiocr.proto",
+}
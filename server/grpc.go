@@ -0,0 +1,201 @@
+package server
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative astiocr.proto
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astiocr"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPC represents a gRPC detection server
+type GRPC struct {
+	UnimplementedDetectorServer
+	d *astiocr.Detector
+	s *grpc.Server
+}
+
+// NewGRPC creates a new gRPC detection server
+func NewGRPC(d *astiocr.Detector) (g *GRPC) {
+	g = &GRPC{
+		d: d,
+		s: grpc.NewServer(),
+	}
+	RegisterDetectorServer(g.s, g)
+	return
+}
+
+// Serve listens on addr and serves requests until the context is cancelled, in which case in-flight
+// requests are allowed to finish before returning
+func (g *GRPC) Serve(ctx context.Context, addr string) (err error) {
+	// Listen
+	var l net.Listener
+	if l, err = net.Listen("tcp", addr); err != nil {
+		err = errors.Wrapf(err, "astiocr: listening on %s failed", addr)
+		return
+	}
+
+	// Gracefully stop once the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			astilog.Debugf("astiocr: gracefully stopping grpc server on %s", addr)
+			g.s.GracefulStop()
+		case <-done:
+		}
+	}()
+
+	// Serve
+	astilog.Debugf("astiocr: serving grpc on %s", addr)
+	if err = g.s.Serve(l); err != nil {
+		err = errors.Wrapf(err, "astiocr: serving grpc on %s failed", addr)
+		return
+	}
+	return
+}
+
+// Detect implements the DetectorServer interface
+func (g *GRPC) Detect(ctx context.Context, req *DetectRequest) (resp *DetectResponse, err error) {
+	// Check request
+	if err = g.checkRequest(req); err != nil {
+		return
+	}
+
+	// Detect
+	if resp, err = g.detect(ctx, req); err != nil {
+		code := codes.Internal
+		switch c := errors.Cause(err); {
+		case c == astiocr.ErrModelNotFound, c == astiocr.ErrUnsupportedImageFormat:
+			code = codes.InvalidArgument
+		default:
+			if _, ok := c.(*astiocr.ErrLowQualityInput); ok {
+				code = codes.InvalidArgument
+			}
+		}
+		err = status.Error(code, err.Error())
+		return
+	}
+	return
+}
+
+// DetectBatch implements the DetectorServer interface
+func (g *GRPC) DetectBatch(stream Detector_DetectBatchServer) (err error) {
+	for {
+		// Receive
+		var req *DetectRequest
+		if req, err = stream.Recv(); err != nil {
+			if err == io.EOF {
+				err = nil
+				return
+			}
+			return
+		}
+
+		// Check request
+		if err = g.checkRequest(req); err != nil {
+			// A single invalid image shouldn't interrupt the whole batch
+			if err = stream.Send(&DetectResponse{
+				Error: status.Convert(err).Message(),
+				Id:    req.Id,
+			}); err != nil {
+				return
+			}
+			continue
+		}
+
+		// Detect
+		var resp *DetectResponse
+		if resp, err = g.detect(stream.Context(), req); err != nil {
+			// Context error
+			if stream.Context().Err() != nil {
+				err = status.FromContextError(stream.Context().Err()).Err()
+				return
+			}
+
+			// A single image failing shouldn't interrupt the whole batch
+			resp = &DetectResponse{
+				Error: err.Error(),
+				Id:    req.Id,
+			}
+		}
+
+		// Send
+		if err = stream.Send(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Reload implements the DetectorServer interface
+func (g *GRPC) Reload(ctx context.Context, req *ReloadRequest) (resp *ReloadResponse, err error) {
+	// Check model
+	if len(req.Model) > 0 && !hasModel(g.d, req.Model) {
+		err = status.Errorf(codes.InvalidArgument, "astiocr: unknown model %s", req.Model)
+		return
+	}
+
+	// Reload
+	if err = g.d.ReloadModel(ctx, req.Model, "", astiocr.ConfigurationIntegrity{}); err != nil {
+		err = status.Error(codes.Internal, errors.Wrap(err, "astiocr: reloading failed").Error())
+		return
+	}
+	resp = &ReloadResponse{}
+	return
+}
+
+// checkRequest returns an InvalidArgument error if the request has no image or an unknown model
+func (g *GRPC) checkRequest(req *DetectRequest) error {
+	// Check image
+	if len(req.Image) == 0 {
+		return status.Error(codes.InvalidArgument, "astiocr: no image provided")
+	}
+
+	// Check model
+	if len(req.Model) > 0 && !hasModel(g.d, req.Model) {
+		return status.Errorf(codes.InvalidArgument, "astiocr: unknown model %s", req.Model)
+	}
+	return nil
+}
+
+func (g *GRPC) detect(ctx context.Context, req *DetectRequest) (resp *DetectResponse, err error) {
+	// Metrics
+	metricQueueDepth.WithLabelValues("grpc").Inc()
+	defer func() {
+		metricQueueDepth.WithLabelValues("grpc").Dec()
+		metricRequests.WithLabelValues("grpc", metricStatus(err)).Inc()
+	}()
+
+	// Detect
+	var rs []astiocr.DetectionResult
+	if rs, err = g.d.DetectBytesWith(ctx, req.Model, req.Image); err != nil {
+		err = errors.Wrapf(err, "astiocr: detecting in image %s failed", req.Id)
+		return
+	}
+
+	// Build response
+	resp = &DetectResponse{Id: req.Id}
+	for _, r := range rs {
+		resp.Results = append(resp.Results, &DetectionResult{
+			Angle: r.Angle,
+			Box: &DetectionBox{
+				X1: r.Box.X1,
+				X2: r.Box.X2,
+				Y1: r.Box.Y1,
+				Y2: r.Box.Y2,
+			},
+			Label:        r.Label,
+			Probability:  r.Probability,
+			QualityFlags: r.QualityFlags,
+		})
+	}
+	return
+}
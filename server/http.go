@@ -0,0 +1,440 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astiocr"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Max size of an uploaded image
+const maxImageSize = 32 << 20
+
+// Maximum size of request bodies, which leaves room for the other form fields and the multipart encoding
+const maxRequestSize = maxImageSize + 1<<20
+
+// Time allowed to read request headers
+const readHeaderTimeout = 10 * time.Second
+
+// ConfigurationHTTP represents an HTTP server configuration
+type ConfigurationHTTP struct {
+	// Origins, such as "https://example.com", allowed to open websockets besides the server's own origin
+	AllowedOrigins []string `toml:"allowed_origins"`
+	// Time allowed to read a request, in seconds. Default is 60.
+	ReadTimeout int                  `toml:"read_timeout"`
+	URL         ConfigurationHTTPURL `toml:"url"`
+	// Time allowed to write a response, detection included, in seconds. Default is 300.
+	WriteTimeout int `toml:"write_timeout"`
+}
+
+// ConfigurationHTTPURL represents the configuration of images downloaded from the "url" form field
+// Downloads are disabled unless allowed hosts are set.
+type ConfigurationHTTPURL struct {
+	// Hosts images can be downloaded from, such as "images.example.com", or "*.example.com" for its
+	// subdomains
+	AllowedHosts []string `toml:"allowed_hosts"`
+	// Max size of downloaded images in bytes. Default is 32MB.
+	MaxSize int64 `toml:"max_size"`
+	// Time allowed to download an image, in seconds. Default is 10.
+	Timeout int `toml:"timeout"`
+}
+
+// HTTP represents an HTTP detection server
+type HTTP struct {
+	c   *http.Client
+	cfg ConfigurationHTTP
+	d   *astiocr.Detector
+	m   *http.ServeMux
+	u   websocket.Upgrader
+}
+
+// NewHTTP creates a new HTTP detection server
+func NewHTTP(d *astiocr.Detector, c ConfigurationHTTP) (h *HTTP) {
+	// Default options
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 60
+	}
+	if c.URL.MaxSize <= 0 {
+		c.URL.MaxSize = maxImageSize
+	}
+	if c.URL.Timeout <= 0 {
+		c.URL.Timeout = 10
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 300
+	}
+
+	// Create server
+	h = &HTTP{
+		cfg: c,
+		d:   d,
+		m:   http.NewServeMux(),
+	}
+	h.c = &http.Client{
+		// Redirections must stay on allowed hosts
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("astiocr: stopped after 10 redirects")
+			}
+			return h.checkURL(r.URL)
+		},
+		Timeout: time.Duration(c.URL.Timeout) * time.Second,
+	}
+	h.u = websocket.Upgrader{
+		CheckOrigin:     h.checkOrigin,
+		ReadBufferSize:  1 << 16,
+		WriteBufferSize: 1 << 16,
+	}
+	h.m.HandleFunc("/detect", h.handleDetect)
+	h.m.Handle("/metrics", promhttp.Handler())
+	h.m.HandleFunc("/model", h.handleModel)
+	h.m.HandleFunc("/reload", h.handleReload)
+	h.m.HandleFunc("/ws", h.handleWebSocket)
+	return
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *HTTP) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	h.m.ServeHTTP(rw, r)
+}
+
+// Serve listens on addr and serves requests until the context is cancelled, in which case in-flight
+// requests are allowed to finish before returning
+func (h *HTTP) Serve(ctx context.Context, addr string) (err error) {
+	// Listen
+	var l net.Listener
+	if l, err = net.Listen("tcp", addr); err != nil {
+		err = errors.Wrapf(err, "astiocr: listening on %s failed", addr)
+		return
+	}
+
+	// Gracefully stop once the context is done
+	s := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       time.Duration(h.cfg.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(h.cfg.WriteTimeout) * time.Second,
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			astilog.Debugf("astiocr: gracefully stopping http server on %s", addr)
+			if err := s.Shutdown(context.Background()); err != nil {
+				astilog.Error(errors.Wrapf(err, "astiocr: shutting down http server on %s failed", addr))
+			}
+		case <-done:
+		}
+	}()
+
+	// Serve
+	astilog.Debugf("astiocr: serving http on %s", addr)
+	if err = s.Serve(l); err != nil && err != http.ErrServerClosed {
+		err = errors.Wrapf(err, "astiocr: serving http on %s failed", addr)
+		return
+	}
+	err = nil
+	return
+}
+
+// HTTPDetectResponse represents an HTTP detect response
+type HTTPDetectResponse struct {
+	Results []astiocr.DetectionResult `json:"results"`
+}
+
+// HTTPModelInfo represents an HTTP model info
+type HTTPModelInfo struct {
+	Backend  string            `json:"backend"`
+	Classes  int               `json:"classes"`
+	Input    HTTPModelTensor   `json:"input"`
+	Labels   []string          `json:"labels,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Outputs  []string          `json:"outputs,omitempty"`
+}
+
+// HTTPModelTensor represents an HTTP model tensor
+type HTTPModelTensor struct {
+	DataType string  `json:"data_type,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Shape    []int64 `json:"shape,omitempty"`
+}
+
+// HTTPError represents an HTTP error
+type HTTPError struct {
+	Message string `json:"message"`
+}
+
+// handleDetect accepts either a multipart "image" file or an "url" form value, and an optional "model" form
+// value
+func (h *HTTP) handleDetect(rw http.ResponseWriter, r *http.Request) {
+	// Metrics
+	var err error
+	metricQueueDepth.WithLabelValues("http").Inc()
+	defer func() {
+		metricQueueDepth.WithLabelValues("http").Dec()
+		metricRequests.WithLabelValues("http", metricStatus(err)).Inc()
+	}()
+
+	// Check method
+	if r.Method != http.MethodPost {
+		err = fmt.Errorf("astiocr: method %s is not allowed", r.Method)
+		h.writeError(rw, http.StatusMethodNotAllowed, err)
+		return
+	}
+
+	// Get image
+	var b []byte
+	var code int
+	if b, code, err = h.image(rw, r); err != nil {
+		h.writeError(rw, code, errors.Wrap(err, "astiocr: getting image failed"))
+		return
+	}
+
+	// Get model
+	model := r.FormValue("model")
+	if len(model) > 0 && !hasModel(h.d, model) {
+		err = fmt.Errorf("astiocr: unknown model %s", model)
+		h.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	// Detect
+	var rs []astiocr.DetectionResult
+	if rs, err = h.d.DetectBytesWith(r.Context(), model, b); err != nil {
+		code = http.StatusInternalServerError
+		switch c := errors.Cause(err); {
+		case c == astiocr.ErrModelNotFound:
+			code = http.StatusBadRequest
+		case c == astiocr.ErrUnsupportedImageFormat:
+			code = http.StatusUnsupportedMediaType
+		default:
+			if _, ok := c.(*astiocr.ErrLowQualityInput); ok {
+				code = http.StatusUnprocessableEntity
+			}
+		}
+		h.writeError(rw, code, errors.Wrap(err, "astiocr: detecting failed"))
+		return
+	}
+
+	// Write
+	if rs == nil {
+		rs = []astiocr.DetectionResult{}
+	}
+	h.writeJSON(rw, http.StatusOK, HTTPDetectResponse{Results: rs})
+}
+
+// handleModel describes the model selected by the optional "model" query parameter
+func (h *HTTP) handleModel(rw http.ResponseWriter, r *http.Request) {
+	// Check method
+	if r.Method != http.MethodGet {
+		h.writeError(rw, http.StatusMethodNotAllowed, fmt.Errorf("astiocr: method %s is not allowed", r.Method))
+		return
+	}
+
+	// Get model
+	model := r.URL.Query().Get("model")
+	if len(model) > 0 && !hasModel(h.d, model) {
+		h.writeError(rw, http.StatusBadRequest, fmt.Errorf("astiocr: unknown model %s", model))
+		return
+	}
+
+	// Get info
+	i, err := h.d.ModelInfo(model)
+	if err != nil {
+		h.writeError(rw, http.StatusInternalServerError, errors.Wrap(err, "astiocr: getting model info failed"))
+		return
+	}
+
+	// Write
+	h.writeJSON(rw, http.StatusOK, HTTPModelInfo{
+		Backend: i.Backend,
+		Classes: i.Classes,
+		Input: HTTPModelTensor{
+			DataType: i.Input.DataType,
+			Name:     i.Input.Name,
+			Shape:    i.Input.Shape,
+		},
+		Labels:   i.Labels,
+		Metadata: i.Metadata,
+		Outputs:  i.Outputs,
+	})
+}
+
+// handleReload reloads the model selected by the optional "model" form value from its configured path
+func (h *HTTP) handleReload(rw http.ResponseWriter, r *http.Request) {
+	// Check method
+	if r.Method != http.MethodPost {
+		h.writeError(rw, http.StatusMethodNotAllowed, fmt.Errorf("astiocr: method %s is not allowed", r.Method))
+		return
+	}
+
+	// Get model
+	model := r.FormValue("model")
+	if len(model) > 0 && !hasModel(h.d, model) {
+		h.writeError(rw, http.StatusBadRequest, fmt.Errorf("astiocr: unknown model %s", model))
+		return
+	}
+
+	// Reload
+	if err := h.d.ReloadModel(r.Context(), model, "", astiocr.ConfigurationIntegrity{}); err != nil {
+		h.writeError(rw, http.StatusInternalServerError, errors.Wrap(err, "astiocr: reloading failed"))
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func hasModel(d *astiocr.Detector, model string) bool {
+	for _, m := range d.Models() {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HTTP) image(rw http.ResponseWriter, r *http.Request) (b []byte, code int, err error) {
+	// Parse form
+	code = http.StatusBadRequest
+	r.Body = http.MaxBytesReader(rw, r.Body, maxRequestSize)
+	if err = r.ParseMultipartForm(maxImageSize); err != nil && err != http.ErrNotMultipart {
+		var errMaxBytes *http.MaxBytesError
+		if errors.As(err, &errMaxBytes) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		err = errors.Wrap(err, "astiocr: parsing form failed")
+		return
+	}
+
+	// Uploaded image
+	if f, _, errFile := r.FormFile("image"); errFile == nil {
+		defer f.Close()
+		if b, err = ioutil.ReadAll(io.LimitReader(f, maxImageSize+1)); err != nil {
+			err = errors.Wrap(err, "astiocr: reading uploaded image failed")
+			return
+		} else if len(b) > maxImageSize {
+			code = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("astiocr: uploaded image size exceeds %d", maxImageSize)
+			return
+		}
+		return
+	}
+
+	// URL
+	u := r.FormValue("url")
+	if len(u) == 0 {
+		err = fmt.Errorf("astiocr: use either the image or the url field")
+		return
+	}
+
+	// Parse URL
+	var pu *url.URL
+	if pu, err = url.Parse(u); err != nil {
+		err = errors.Wrapf(err, "astiocr: parsing url %s failed", u)
+		return
+	}
+
+	// Check URL
+	if err = h.checkURL(pu); err != nil {
+		code = http.StatusForbidden
+		err = errors.Wrapf(err, "astiocr: checking url %s failed", u)
+		return
+	}
+
+	// Download
+	code = http.StatusBadGateway
+	if b, err = h.download(r.Context(), pu); err != nil {
+		err = errors.Wrapf(err, "astiocr: downloading %s failed", u)
+		return
+	}
+	return
+}
+
+// checkURL checks that images can be downloaded from the url
+func (h *HTTP) checkURL(u *url.URL) error {
+	// Downloads are disabled
+	if len(h.cfg.URL.AllowedHosts) == 0 {
+		return errors.New("astiocr: url downloads are disabled")
+	}
+
+	// Check scheme
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("astiocr: scheme %s is not allowed", u.Scheme)
+	}
+
+	// Check host
+	host := strings.ToLower(u.Hostname())
+	for _, a := range h.cfg.URL.AllowedHosts {
+		a = strings.ToLower(a)
+		if host == a || (strings.HasPrefix(a, "*.") && strings.HasSuffix(host, a[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("astiocr: host %s is not allowed", host)
+}
+
+func (h *HTTP) download(ctx context.Context, u *url.URL) (b []byte, err error) {
+	// Create request
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, u.String(), nil); err != nil {
+		err = errors.Wrap(err, "astiocr: creating request failed")
+		return
+	}
+	req = req.WithContext(ctx)
+
+	// Send request
+	var resp *http.Response
+	if resp, err = h.c.Do(req); err != nil {
+		err = errors.Wrap(err, "astiocr: sending request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("astiocr: invalid status code %d", resp.StatusCode)
+		return
+	}
+
+	// Check size
+	if resp.ContentLength > h.cfg.URL.MaxSize {
+		err = fmt.Errorf("astiocr: body size %d exceeds %d", resp.ContentLength, h.cfg.URL.MaxSize)
+		return
+	}
+
+	// Read
+	if b, err = ioutil.ReadAll(io.LimitReader(resp.Body, h.cfg.URL.MaxSize+1)); err != nil {
+		err = errors.Wrap(err, "astiocr: reading body failed")
+		return
+	} else if int64(len(b)) > h.cfg.URL.MaxSize {
+		err = fmt.Errorf("astiocr: body size exceeds %d", h.cfg.URL.MaxSize)
+		return
+	}
+	return
+}
+
+func (h *HTTP) writeError(rw http.ResponseWriter, code int, err error) {
+	astilog.Error(err)
+	h.writeJSON(rw, code, HTTPError{Message: err.Error()})
+}
+
+func (h *HTTP) writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		astilog.Error(errors.Wrap(err, "astiocr: writing json failed"))
+		return
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics are registered in the default prometheus registry
+var (
+	metricQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "astiocr_server_queue_depth",
+		Help: "Number of requests waiting for or being processed",
+	}, []string{"transport"})
+	metricRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "astiocr_server_requests_total",
+		Help: "Number of requests per transport and status",
+	}, []string{"transport", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricQueueDepth,
+		metricRequests,
+	)
+}
+
+func metricStatus(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
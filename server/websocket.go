@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astiocr"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// Websocket timeouts
+const (
+	// Time allowed to read the next pong message from the client
+	websocketPongWait = time.Minute
+	// Period at which pings are sent to the client, which must be less than the pong wait
+	websocketPingPeriod = websocketPongWait * 9 / 10
+	// Time allowed to write a message to the client
+	websocketWriteWait = 10 * time.Second
+)
+
+// HTTPFrameResponse represents the message sent back for each frame pushed through the websocket
+type HTTPFrameResponse struct {
+	Error   string                    `json:"error,omitempty"`
+	ID      int                       `json:"id"`
+	Results []astiocr.DetectionResult `json:"results"`
+}
+
+type frame struct {
+	b  []byte
+	id int
+}
+
+// handleWebSocket reads frames pushed as binary messages and sends back detection results as json text
+// messages. Frames are numbered in the order they are received. The "model" query parameter selects the
+// model. If frames are pushed faster than they can be processed, stale frames are dropped so that
+// results keep up with the live source.
+func (h *HTTP) handleWebSocket(rw http.ResponseWriter, r *http.Request) {
+	// Upgrade
+	c, err := h.u.Upgrade(rw, r, nil)
+	if err != nil {
+		astilog.Error(errors.Wrap(err, "astiocr: upgrading to websocket failed"))
+		return
+	}
+	defer c.Close()
+	c.SetReadLimit(maxImageSize)
+
+	// Dead connections are detected by the lack of pongs
+	c.SetReadDeadline(time.Now().Add(websocketPongWait))
+	c.SetPongHandler(func(string) error { return c.SetReadDeadline(time.Now().Add(websocketPongWait)) })
+
+	// Process frames and send pings
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	frames := make(chan frame, 1)
+	done := make(chan struct{}, 2)
+	go func() {
+		defer func() { done <- struct{}{} }()
+		h.processFrames(ctx, c, r.URL.Query().Get("model"), frames)
+	}()
+	go func() {
+		defer func() { done <- struct{}{} }()
+		pingWebSocket(ctx, c)
+	}()
+
+	// Read frames
+	for id := 1; ; id++ {
+		// Read message
+		var t int
+		var b []byte
+		if t, b, err = c.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				astilog.Error(errors.Wrap(err, "astiocr: reading websocket message failed"))
+			}
+			break
+		}
+
+		// Messages are as good as pongs to prove the client is alive
+		c.SetReadDeadline(time.Now().Add(websocketPongWait))
+
+		// Only binary messages are frames
+		if t != websocket.BinaryMessage {
+			continue
+		}
+
+		// Drop the stale frame if any
+		select {
+		case <-frames:
+		default:
+			metricQueueDepth.WithLabelValues("websocket").Inc()
+		}
+		frames <- frame{b: b, id: id}
+	}
+
+	// Wait for the processing and the pings to stop
+	cancel()
+	<-done
+	<-done
+
+	// Frame left in the queue
+	select {
+	case <-frames:
+		metricQueueDepth.WithLabelValues("websocket").Dec()
+	default:
+	}
+}
+
+func (h *HTTP) processFrames(ctx context.Context, c *websocket.Conn, model string, frames chan frame) {
+	for {
+		// Get next frame
+		var f frame
+		select {
+		case <-ctx.Done():
+			return
+		case f = <-frames:
+		}
+
+		// Detect
+		resp := HTTPFrameResponse{ID: f.id}
+		rs, err := h.d.DetectBytesWith(ctx, model, f.b)
+		metricQueueDepth.WithLabelValues("websocket").Dec()
+		metricRequests.WithLabelValues("websocket", metricStatus(err)).Inc()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			resp.Error = errors.Wrapf(err, "astiocr: detecting in frame %d failed", f.id).Error()
+		}
+		resp.Results = rs
+		if resp.Results == nil {
+			resp.Results = []astiocr.DetectionResult{}
+		}
+
+		// Write
+		c.SetWriteDeadline(time.Now().Add(websocketWriteWait))
+		if err = c.WriteJSON(resp); err != nil {
+			astilog.Error(errors.Wrap(err, "astiocr: writing websocket message failed"))
+			return
+		}
+	}
+}
+
+// pingWebSocket periodically sends pings to the client until the context is done
+func pingWebSocket(ctx context.Context, c *websocket.Conn) {
+	t := time.NewTicker(websocketPingPeriod)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteWait)); err != nil {
+				astilog.Error(errors.Wrap(err, "astiocr: writing websocket ping failed"))
+				return
+			}
+		}
+	}
+}
+
+// checkOrigin only allows browsers to open websockets from the server's own origin or from the allowed
+// origins. Requests without an origin don't come from browsers and are allowed.
+func (h *HTTP) checkOrigin(r *http.Request) bool {
+	// No origin
+	o := r.Header.Get("Origin")
+	if len(o) == 0 {
+		return true
+	}
+
+	// Same origin
+	if u, err := url.Parse(o); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	// Allowed origins
+	for _, a := range h.cfg.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), o) {
+			return true
+		}
+	}
+	return false
+}
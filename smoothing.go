@@ -0,0 +1,182 @@
+package astiocr
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Default strength of the confidence smoothing
+const defaultSmoothingStrength = 0.5
+
+// Dictionary represents a set of known words used to smooth character confidences
+type Dictionary struct {
+	byLength map[int][][]rune
+	words    map[string]bool
+}
+
+// NewDictionary creates a new dictionary containing the provided words. Words are case insensitive.
+func NewDictionary(words []string) (d *Dictionary) {
+	d = &Dictionary{
+		byLength: make(map[int][][]rune),
+		words:    make(map[string]bool),
+	}
+	for _, w := range words {
+		d.Add(w)
+	}
+	return
+}
+
+// LoadDictionary creates a new dictionary with the words of the file located at p, one per line
+func LoadDictionary(p string) (d *Dictionary, err error) {
+	// Open file
+	var f *os.File
+	if f, err = os.Open(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Loop through lines
+	d = NewDictionary(nil)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		d.Add(s.Text())
+	}
+	if err = s.Err(); err != nil {
+		err = errors.Wrapf(err, "astiocr: scanning %s failed", p)
+		return
+	}
+	return
+}
+
+// Add adds a word to the dictionary
+func (d *Dictionary) Add(w string) {
+	w = strings.ToLower(strings.TrimSpace(w))
+	if len(w) == 0 || d.words[w] {
+		return
+	}
+	d.words[w] = true
+	rs := []rune(w)
+	d.byLength[len(rs)] = append(d.byLength[len(rs)], rs)
+}
+
+// Contains checks whether the dictionary contains the word
+func (d *Dictionary) Contains(w string) bool {
+	return d.words[strings.ToLower(w)]
+}
+
+// closest returns the words of the same length with the fewest differing characters, as long as they
+// differ by at most max characters
+func (d *Dictionary) closest(w []rune, max int) (cs [][]rune) {
+	best := max + 1
+	for _, c := range d.byLength[len(w)] {
+		var n int
+		for idx := range w {
+			if w[idx] != c[idx] {
+				if n++; n > best {
+					break
+				}
+			}
+		}
+		if n < best {
+			best = n
+			cs = [][]rune{c}
+		} else if n == best {
+			cs = append(cs, c)
+		}
+	}
+	return
+}
+
+// SmoothConfidences adjusts character confidences based on their agreement with the dictionary and
+// recomputes word, line and block confidences. Characters of words found in the dictionary, and
+// characters agreeing with all the closest dictionary words, are boosted whereas characters disagreeing
+// with them are suppressed. Strength, between 0 and 1, indicates how much confidences are adjusted.
+// Raw confidences are left untouched.
+func (p *DocumentPage) SmoothConfidences(d *Dictionary, strength float64) {
+	for bi := range p.Blocks {
+		b := &p.Blocks[bi]
+		for li := range b.Lines {
+			l := &b.Lines[li]
+			for wi := range l.Words {
+				smoothWord(&l.Words[wi], d, strength)
+			}
+			l.Confidence = averageWordConfidence(l.Words)
+		}
+		b.Confidence = averageLineConfidence(b.Lines)
+	}
+}
+
+func smoothWord(w *DocumentWord, d *Dictionary, strength float64) {
+	// Get word
+	rs := []rune(strings.ToLower(w.Text()))
+
+	// Labels are not all single characters
+	if len(rs) != len(w.Chars) {
+		return
+	}
+
+	// Get candidates
+	var cs [][]rune
+	if d.Contains(string(rs)) {
+		cs = [][]rune{rs}
+	} else {
+		// Allow one differing character every 3 characters
+		max := len(rs) / 3
+		if max == 0 {
+			return
+		}
+		if cs = d.closest(rs, max); len(cs) == 0 {
+			return
+		}
+	}
+
+	// Loop through characters
+	var sum float64
+	for idx := range w.Chars {
+		c := &w.Chars[idx]
+
+		// Check agreement
+		agree := true
+		for _, cd := range cs {
+			if cd[idx] != rs[idx] {
+				agree = false
+				break
+			}
+		}
+
+		// Adjust
+		if agree {
+			c.Confidence = c.RawConfidence + (1-c.RawConfidence)*strength
+		} else {
+			c.Confidence = c.RawConfidence * (1 - strength)
+		}
+		sum += c.Confidence
+	}
+	w.Confidence = sum / float64(len(w.Chars))
+}
+
+func averageWordConfidence(ws []DocumentWord) float64 {
+	if len(ws) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, w := range ws {
+		sum += w.Confidence
+	}
+	return sum / float64(len(ws))
+}
+
+func averageLineConfidence(ls []DocumentLine) float64 {
+	if len(ls) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, l := range ls {
+		sum += l.Confidence
+	}
+	return sum / float64(len(ls))
+}
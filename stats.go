@@ -0,0 +1,112 @@
+package astiocr
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// DetectionStats represents the time spent in each stage of a detection. Stages processed several times
+// (e.g. inference with several scales or tiles) are summed.
+type DetectionStats struct {
+	Decode      time.Duration
+	Inference   time.Duration
+	Model       string
+	PostProcess time.Duration
+	Preprocess  time.Duration
+	Total       time.Duration
+}
+
+// Detection stages
+const (
+	stageDecode      = "decode"
+	stageInference   = "inference"
+	stagePostProcess = "postprocess"
+	stagePreprocess  = "preprocess"
+)
+
+// Label added to goroutines when profiling labels are enabled
+const profilingLabelStage = "astiocr_stage"
+
+type statsKey struct{}
+
+type detectionStats struct {
+	m *sync.Mutex
+	s DetectionStats
+}
+
+// OnStats sets a callback invoked with the stats of each detection, which helps diagnosing latency
+func (d *Detector) OnStats(f func(s DetectionStats)) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.onStats = f
+}
+
+// startStats attaches stats to the context unless it already has some, in which case the detection is
+// part of a bigger one and the returned func is a no-op. Otherwise, the returned func invokes the stats
+// callback.
+func (d *Detector) startStats(ctx context.Context, model string) (context.Context, func()) {
+	// Stats are already collected
+	if _, ok := ctx.Value(statsKey{}).(*detectionStats); ok {
+		return ctx, func() {}
+	}
+
+	// Attach stats
+	s := &detectionStats{
+		m: &sync.Mutex{},
+		s: DetectionStats{Model: model},
+	}
+	start := time.Now()
+	return context.WithValue(ctx, statsKey{}, s), func() {
+		// Get callback
+		d.m.Lock()
+		f := d.onStats
+		d.m.Unlock()
+		if f == nil {
+			return
+		}
+
+		// Invoke callback
+		s.m.Lock()
+		s.s.Total = time.Since(start)
+		ss := s.s
+		s.m.Unlock()
+		f(ss)
+	}
+}
+
+// startStage adds the time spent until the returned func is called to the stage stats and, if enabled,
+// labels the goroutine with the stage so that it shows up in pprof profiles
+func (d *Detector) startStage(ctx context.Context, stage string) func() {
+	// Label goroutine
+	if d.profilingLabels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(profilingLabelStage, stage)))
+	}
+
+	start := time.Now()
+	return func() {
+		// Restore labels
+		if d.profilingLabels {
+			pprof.SetGoroutineLabels(ctx)
+		}
+
+		// Update stats
+		s, ok := ctx.Value(statsKey{}).(*detectionStats)
+		if !ok {
+			return
+		}
+		s.m.Lock()
+		defer s.m.Unlock()
+		switch stage {
+		case stageDecode:
+			s.s.Decode += time.Since(start)
+		case stageInference:
+			s.s.Inference += time.Since(start)
+		case stagePostProcess:
+			s.s.PostProcess += time.Since(start)
+		case stagePreprocess:
+			s.s.Preprocess += time.Since(start)
+		}
+	}
+}
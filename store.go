@@ -0,0 +1,283 @@
+package astiocr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GatherManifest represents a gather manifest mapping generated image indexes to their hash in the store
+type GatherManifest struct {
+	Images []GatherManifestImage `json:"images"`
+}
+
+// GatherManifestImage represents a gather manifest image
+type GatherManifestImage struct {
+	Hash  string `json:"hash"`
+	Index int    `json:"index"`
+}
+
+// imageStore stores images by content hash so that identical images are stored only once. Images
+// generated from a known key (e.g. a seed) are indexed so that they can be reused without being
+// generated again.
+type imageStore struct {
+	dirPath string
+	keys    map[string]imageStoreEntry
+}
+
+type imageStoreEntry struct {
+	Hash  string             `json:"hash"`
+	Image GatherSummaryImage `json:"image"`
+}
+
+func newImageStore(dirPath string) (s *imageStore, err error) {
+	// Create store
+	s = &imageStore{
+		dirPath: dirPath,
+		keys:    make(map[string]imageStoreEntry),
+	}
+
+	// Create dir
+	if err = os.MkdirAll(dirPath, 0700); err != nil {
+		err = errors.Wrapf(err, "astiocr: mkdirall %s failed", dirPath)
+		return
+	}
+
+	// Read keys
+	p := s.keysPath()
+	var b []byte
+	if b, err = ioutil.ReadFile(p); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = errors.Wrapf(err, "astiocr: reading %s failed", p)
+		return
+	}
+	if err = json.Unmarshal(b, &s.keys); err != nil {
+		err = errors.Wrapf(err, "astiocr: unmarshaling %s failed", p)
+		return
+	}
+	return
+}
+
+func (s *imageStore) keysPath() string {
+	return filepath.Join(s.dirPath, "keys.json")
+}
+
+func (s *imageStore) path(hash string) string {
+	return filepath.Join(s.dirPath, hash[:2], hash+".png")
+}
+
+// get returns the image previously stored with the key, if it still exists
+func (s *imageStore) get(key string) (si GatherSummaryImage, hash string, ok bool) {
+	// Get entry
+	var e imageStoreEntry
+	if e, ok = s.keys[key]; !ok {
+		return
+	}
+
+	// Check file
+	if _, err := os.Stat(s.path(e.Hash)); err != nil {
+		ok = false
+		return
+	}
+	si = e.Image
+	si.Path = s.path(e.Hash)
+	hash = e.Hash
+	return
+}
+
+// put stores the image unless an identical one is already stored, and indexes it with the key if not empty
+func (s *imageStore) put(key string, img image.Image, si GatherSummaryImage) (p, hash string, err error) {
+	// Encode image
+	buf := &bytes.Buffer{}
+	if err = png.Encode(buf, img); err != nil {
+		err = errors.Wrap(err, "astiocr: encoding image failed")
+		return
+	}
+
+	// Get hash
+	h := sha256.Sum256(buf.Bytes())
+	hash = hex.EncodeToString(h[:])
+	p = s.path(hash)
+
+	// Write file
+	if _, errStat := os.Stat(p); os.IsNotExist(errStat) {
+		if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			err = errors.Wrapf(err, "astiocr: mkdirall %s failed", filepath.Dir(p))
+			return
+		}
+		if err = ioutil.WriteFile(p, buf.Bytes(), 0600); err != nil {
+			err = errors.Wrapf(err, "astiocr: writing %s failed", p)
+			return
+		}
+	}
+
+	// Index
+	if len(key) > 0 {
+		s.keys[key] = imageStoreEntry{
+			Hash:  hash,
+			Image: si,
+		}
+	}
+	return
+}
+
+// close writes the keys index
+func (s *imageStore) close() (err error) {
+	// Marshal
+	var b []byte
+	if b, err = json.Marshal(s.keys); err != nil {
+		err = errors.Wrap(err, "astiocr: marshaling keys failed")
+		return
+	}
+
+	// Write
+	p := s.keysPath()
+	if err = ioutil.WriteFile(p, b, 0600); err != nil {
+		err = errors.Wrapf(err, "astiocr: writing %s failed", p)
+		return
+	}
+	return
+}
+
+// generationFingerprint returns a hash of the parameters images are generated with, so that images
+// generated with different parameters are not reused. Files are fingerprinted by content so that files
+// edited in place are taken into account.
+func (t *Trainer) generationFingerprint() (f string, err error) {
+	// Get fonts
+	var fonts []string
+	for _, f := range t.fonts {
+		h := sha256.Sum256(f.body)
+		fonts = append(fonts, fmt.Sprintf("%s:%x:%v:%s:%d:%v:%d", f.name, h[:8], f.positionRatio, f.style, f.hinting, f.aliased, f.pixelation))
+	}
+
+	// Get background and composite images
+	var backgrounds, composites []string
+	if backgrounds, err = fileDigests(t.backgroundPaths...); err != nil {
+		err = errors.Wrap(err, "astiocr: getting background digests failed")
+		return
+	}
+	if composites, err = fileDigests(t.compositePaths...); err != nil {
+		err = errors.Wrap(err, "astiocr: getting composite digests failed")
+		return
+	}
+
+	// Get corpus
+	var corpus []string
+	if len(t.corpusPath) > 0 {
+		if corpus, err = fileDigests(t.corpusPath); err != nil {
+			err = errors.Wrap(err, "astiocr: getting corpus digests failed")
+			return
+		}
+	}
+
+	// Get wordlists
+	var wordlists []string
+	for _, w := range t.wordlists {
+		var ds []string
+		if ds, err = fileDigests(w.Path); err != nil {
+			err = errors.Wrap(err, "astiocr: getting wordlist digests failed")
+			return
+		}
+		wordlists = append(wordlists, fmt.Sprintf("%s:%v:%s", w.Domain, w.Weight, strings.Join(ds, ",")))
+	}
+
+	// Marshal parameters
+	var b []byte
+	if b, err = json.Marshal(map[string]interface{}{
+		"affine":                t.affine,
+		"augmentations":         t.augmentations,
+		"augmenters":            t.augmenterNames(),
+		"background_paths":      backgrounds,
+		"backgrounds":           t.backgrounds,
+		"blur":                  t.blur,
+		"box_jitter":            t.boxJitter,
+		"charset":               string(t.charset),
+		"colors":                t.colors,
+		"color_jitter":          t.colorJitter,
+		"composite_paths":       composites,
+		"corpus":                corpus,
+		"decorations":           t.decorations,
+		"elastic":               t.elastic,
+		"font_mixing":           t.fontMixing,
+		"font_size":             t.fontSize,
+		"fonts":                 fonts,
+		"gaussian_noise":        t.gaussianNoise,
+		"gradient":              t.gradient,
+		"hard_negatives":        t.hardNegatives,
+		"image":                 t.image,
+		"jpeg":                  t.jpeg,
+		"letter_spacing":        t.letterSpacing,
+		"lighting":              t.lighting,
+		"mirrored_proportion":   t.mirroredProportion,
+		"outline":               t.outline,
+		"perspective":           t.perspective,
+		"rotation":              t.rotation,
+		"salt_and_pepper_noise": t.saltAndPepperNoise,
+		"shadow":                t.shadow,
+		"show_box":              t.showBox,
+		"show_grid":             t.showGrid,
+		"strategies":            t.strategies.names,
+		"strategy_weights":      t.strategies.weights,
+		"test_strategies":       t.testStrategies.names,
+		"test_strategy_weights": t.testStrategies.weights,
+		"text_opacity":          t.textOpacity,
+		"texture":               t.texture,
+		"wordlists":             wordlists,
+	}); err != nil {
+		err = errors.Wrap(err, "astiocr: marshaling parameters failed")
+		return
+	}
+
+	// Hash
+	h := sha256.Sum256(b)
+	f = hex.EncodeToString(h[:8])
+	return
+}
+
+// fileDigests returns the paths of the files, files of directories included, followed by a hash of their
+// content
+func fileDigests(paths ...string) (ds []string, err error) {
+	for _, p := range paths {
+		if err = filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+			// Only regular files are hashed
+			if err != nil {
+				return err
+			} else if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			// Open file
+			f, err := os.Open(path)
+			if err != nil {
+				return errors.Wrapf(err, "astiocr: opening %s failed", path)
+			}
+			defer f.Close()
+
+			// Hash
+			h := sha256.New()
+			if _, err = io.Copy(h, f); err != nil {
+				return errors.Wrapf(err, "astiocr: hashing %s failed", path)
+			}
+			ds = append(ds, fmt.Sprintf("%s:%x", path, h.Sum(nil)[:8]))
+			return nil
+		}); err != nil {
+			err = errors.Wrapf(err, "astiocr: walking %s failed", p)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,198 @@
+package astiocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"sort"
+	"sync"
+
+	ft "golang.org/x/image/font"
+)
+
+// Strategy generates an image and its summary, whose boxes are the characters drawn in the image. Boxes
+// whose label index is 0 get the label index of their label, and images without boxes are skipped.
+type Strategy interface {
+	Generate(p StrategyParams) (*image.RGBA, GatherSummaryImage)
+}
+
+// StrategyParams represents the parameters picked randomly for each generated image
+type StrategyParams struct {
+	BackgroundColor color.RGBA
+	// Characters that are labeled
+	Charset string
+	// Face of a random font at the font size
+	Face      ft.Face
+	FontColor color.RGBA
+	FontSize  int
+	// Configured image size
+	Height int
+	Width  int
+	font   *font
+}
+
+// strategyFunc allows using a function as a strategy
+type strategyFunc func(p StrategyParams) (*image.RGBA, GatherSummaryImage)
+
+// Generate implements the Strategy interface
+func (fn strategyFunc) Generate(p StrategyParams) (*image.RGBA, GatherSummaryImage) { return fn(p) }
+
+// Strategies registered with RegisterStrategy
+var (
+	strategies      = map[string]Strategy{}
+	strategiesMutex = &sync.Mutex{}
+)
+
+// RegisterStrategy makes a strategy available under the provided name so that it can be selected through
+// ConfigurationTrainer.Strategy or ConfigurationTrainer.Strategies. It panics if the strategy is nil or if
+// the name is already used.
+func RegisterStrategy(name string, s Strategy) {
+	strategiesMutex.Lock()
+	defer strategiesMutex.Unlock()
+	if s == nil {
+		panic(fmt.Sprintf("astiocr: strategy %s is nil", name))
+	}
+	if _, ok := strategies[name]; ok || isBuiltinStrategy(name) {
+		panic(fmt.Sprintf("astiocr: strategy name %s is already used", name))
+	}
+	strategies[name] = s
+}
+
+// isBuiltinStrategy checks whether the name is a built-in strategy
+func isBuiltinStrategy(name string) bool {
+	switch name {
+	case strategyCharacter, strategyComposite, strategyCurved, strategyFree, strategyGrid, strategyParagraphs, strategyReceipt, strategyWords:
+		return true
+	}
+	return false
+}
+
+// strategy returns the strategy of the name, or nil
+func (t *Trainer) strategy(name string) Strategy {
+	switch name {
+	case strategyCharacter:
+		return strategyFunc(t.createImageStrategyCharacter)
+	case strategyComposite:
+		return strategyFunc(t.createImageStrategyComposite)
+	case strategyCurved:
+		return strategyFunc(t.createImageStrategyCurved)
+	case strategyFree:
+		return strategyFunc(t.createImageStrategyFree)
+	case strategyGrid:
+		return strategyFunc(t.createImageStrategyGrid)
+	case strategyParagraphs:
+		return strategyFunc(t.createImageStrategyParagraphs)
+	case strategyReceipt:
+		return strategyFunc(t.createImageStrategyReceipt)
+	case strategyWords:
+		return strategyFunc(t.createImageStrategyWords)
+	}
+	strategiesMutex.Lock()
+	defer strategiesMutex.Unlock()
+	return strategies[name]
+}
+
+// strategyMix represents strategies picked according to their weights
+type strategyMix struct {
+	names   []string
+	weights []float64
+}
+
+// newStrategyMix creates a strategy mix based on either a strategy or strategy weights. Names are sorted so
+// that picks are reproducible.
+func (t *Trainer) newStrategyMix(strategy string, weights map[string]float64) (m strategyMix, err error) {
+	// Get weights
+	if len(weights) == 0 {
+		if strategy == "" {
+			strategy = strategyGrid
+		}
+		weights = map[string]float64{strategy: 1}
+	} else if strategy != "" {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: strategy %s and strategy weights can't both be set", strategy))
+		return
+	}
+
+	// Sort names
+	var ns []string
+	for n := range weights {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+
+	// Loop through names
+	for _, n := range ns {
+		if t.strategy(n) == nil {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid strategy %s", n))
+			return
+		}
+		w := weights[n]
+		if w < 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid weight %v of strategy %s", w, n))
+			return
+		}
+		if w > 0 {
+			m.names = append(m.names, n)
+			m.weights = append(m.weights, w)
+		}
+	}
+	if len(m.names) == 0 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: no strategy has a positive weight"))
+		return
+	}
+	return
+}
+
+// uses checks whether the strategy can be picked
+func (m strategyMix) uses(name string) bool {
+	for _, n := range m.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// random returns the name of a strategy picked according to the weights
+func (m strategyMix) random() string {
+	// Only one strategy
+	if len(m.names) == 1 {
+		return m.names[0]
+	}
+
+	// Pick strategy
+	var total float64
+	for _, w := range m.weights {
+		total += w
+	}
+	r := rand.Float64() * total
+	for idx, w := range m.weights {
+		if r < w {
+			return m.names[idx]
+		}
+		r -= w
+	}
+	return m.names[len(m.names)-1]
+}
+
+// usesStrategy checks whether the strategy can be picked for either training or test images
+func (t *Trainer) usesStrategy(name string) bool {
+	return t.strategies.uses(name) || t.testStrategies.uses(name)
+}
+
+// randomStrategy returns the name of a strategy picked for the image of the index, test images being
+// generated after training images
+func (t *Trainer) randomStrategy(idx int) string {
+	if idx < t.trainingDataCount {
+		return t.strategies.random()
+	}
+	return t.testStrategies.random()
+}
+
+// newStrategyParams picks the parameters of an image randomly
+func (t *Trainer) newStrategyParams() (p StrategyParams) {
+	p.FontSize, p.BackgroundColor, p.FontColor, p.font = t.initParams()
+	p.Charset = string(t.charset)
+	p.Height, p.Width = t.image.Height, t.image.Width
+	return
+}
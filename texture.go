@@ -0,0 +1,136 @@
+package astiocr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// Texture kinds
+const (
+	textureKindCheckerboard = "checkerboard"
+	textureKindPerlin       = "perlin"
+	textureKindScanlines    = "scanlines"
+	textureKindStripes      = "stripes"
+)
+
+// Texture layout constants
+const (
+	// Maximum size of checkerboard cells, stripe periods and noise cells, in pixels
+	textureMaxPeriod = 48
+	// Minimum size of checkerboard cells, stripe periods and noise cells, in pixels
+	textureMinPeriod = 4
+	// Number of octaves of perlin noise
+	texturePerlinOctaves = 3
+	// Maximum proportion of the contrasting color mixed into the background color
+	textureMaxContrast = 0.5
+	// Minimum proportion of the contrasting color mixed into the background color
+	textureMinContrast = 0.15
+)
+
+// ConfigurationTexture represents a procedural texture backgrounds configuration
+// The background of the proportion of images is a procedural texture mixing the image background color
+// with a contrasting version of it, so that the model doesn't learn that text only appears on flat colors.
+type ConfigurationTexture struct {
+	// "checkerboard", "perlin", "scanlines" or "stripes". Default is all of them.
+	Kinds      []string `toml:"kinds"`
+	Proportion float64  `toml:"proportion"`
+}
+
+// drawTexture covers the image with a random texture and returns its kind
+func (t *Trainer) drawTexture(img *image.RGBA, backgroundColor color.RGBA) (kind string) {
+	// Pick colors
+	ss := defaultGradientStops(backgroundColor)
+	contrast := textureMinContrast + rand.Float64()*(textureMaxContrast-textureMinContrast)
+
+	// Get intensity of each pixel, between 0 and 1
+	var intensity func(x, y float64) float64
+	period := float64(textureMinPeriod + rand.Intn(textureMaxPeriod-textureMinPeriod+1))
+	switch kind = t.texture.Kinds[rand.Intn(len(t.texture.Kinds))]; kind {
+	case textureKindCheckerboard:
+		intensity = func(x, y float64) float64 {
+			return float64((int(math.Floor(x/period)) + int(math.Floor(y/period))) & 1)
+		}
+	case textureKindPerlin:
+		n := newPerlinNoise()
+		intensity = func(x, y float64) float64 {
+			var v, total float64
+			for o, a := 0, 1.0; o < texturePerlinOctaves; o, a = o+1, a/2 {
+				f := float64(int(1) << uint(o))
+				v += a * n.at(x*f/period, y*f/period)
+				total += a
+			}
+			return math.Max(0, math.Min(1, (v/total+1)/2))
+		}
+	case textureKindScanlines:
+		// Scanlines are 1 pixel thick and a few pixels apart
+		gap := 2 + rand.Intn(3)
+		intensity = func(x, y float64) float64 {
+			if int(y)%gap == 0 {
+				return 1
+			}
+			return 0
+		}
+	default:
+		sin, cos := math.Sincos(rand.Float64() * math.Pi)
+		intensity = func(x, y float64) float64 {
+			if math.Mod(math.Abs(x*cos+y*sin), period) < period/2 {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	// Loop through pixels
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, gradientColor(ss, contrast*intensity(float64(x-r.Min.X), float64(y-r.Min.Y))))
+		}
+	}
+	return
+}
+
+// perlinNoise generates 2D gradient noise
+type perlinNoise struct {
+	perm [512]int
+}
+
+func newPerlinNoise() (n perlinNoise) {
+	p := rand.Perm(256)
+	for idx := range n.perm {
+		n.perm[idx] = p[idx%256]
+	}
+	return
+}
+
+// at returns the noise at the point, between -1 and 1
+func (n perlinNoise) at(x, y float64) float64 {
+	// Get cell
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0)&255, int(y0)&255
+	xf, yf := x-x0, y-y0
+
+	// Get gradients of the cell corners
+	grad := func(h int, x, y float64) float64 {
+		switch h & 3 {
+		case 0:
+			return x + y
+		case 1:
+			return -x + y
+		case 2:
+			return x - y
+		default:
+			return -x - y
+		}
+	}
+	aa, ab := n.perm[n.perm[xi]+yi], n.perm[n.perm[xi]+yi+1]
+	ba, bb := n.perm[n.perm[xi+1]+yi], n.perm[n.perm[xi+1]+yi+1]
+
+	// Interpolate
+	fade := func(t float64) float64 { return t * t * t * (t*(t*6-15) + 10) }
+	lerp := func(a, b, t float64) float64 { return a + (b-a)*t }
+	u, v := fade(xf), fade(yf)
+	return lerp(lerp(grad(aa, xf, yf), grad(ba, xf-1, yf), u), lerp(grad(ab, xf, yf-1), grad(bb, xf-1, yf-1), u), v)
+}
@@ -0,0 +1,169 @@
+package astiocr
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigurationTiles represents a tiles configuration
+// Images bigger than a tile are split into overlapping tiles processed independently, and results are
+// stitched back together afterwards. Dimensions are in pixels. A zero width or height disables tiling.
+type ConfigurationTiles struct {
+	Height  int `toml:"height"`
+	Overlap int `toml:"overlap"`
+	Width   int `toml:"width"`
+}
+
+// Below this distance to a tile border, in pixels, a box is considered as truncated by the tile
+const tileBorderMargin = 2
+
+type tileResult struct {
+	DetectionResult
+	tile      int
+	truncated bool
+}
+
+func (d *Detector) detectTiled(ctx context.Context, bk Backend, img image.Image) (rs []DetectionResult, err error) {
+	// No tiling needed
+	b := img.Bounds()
+	if d.tiles.Width <= 0 || d.tiles.Height <= 0 || (b.Dx() <= d.tiles.Width && b.Dy() <= d.tiles.Height) {
+		return d.detect(ctx, bk, img)
+	}
+
+	// Loop through tiles
+	var trs []tileResult
+	for idx, t := range tileRects(b, d.tiles) {
+		// Check context
+		if err = ctx.Err(); err != nil {
+			err = errors.Wrap(err, "astiocr: context error")
+			return
+		}
+
+		// Detect
+		var srs []DetectionResult
+		if srs, err = d.detect(ctx, bk, subImage(img, t)); err != nil {
+			err = errors.Wrapf(err, "astiocr: detecting in tile %s failed", t)
+			return
+		}
+
+		// Loop through results
+		for _, r := range srs {
+			// Get pixel coordinates
+			x1 := float64(t.Min.X) + r.Box.X1*float64(t.Dx())
+			x2 := float64(t.Min.X) + r.Box.X2*float64(t.Dx())
+			y1 := float64(t.Min.Y) + r.Box.Y1*float64(t.Dy())
+			y2 := float64(t.Min.Y) + r.Box.Y2*float64(t.Dy())
+
+			// Only tile borders that are not image borders can truncate a box
+			truncated := (t.Min.X > b.Min.X && x1-float64(t.Min.X) < tileBorderMargin) ||
+				(t.Max.X < b.Max.X && float64(t.Max.X)-x2 < tileBorderMargin) ||
+				(t.Min.Y > b.Min.Y && y1-float64(t.Min.Y) < tileBorderMargin) ||
+				(t.Max.Y < b.Max.Y && float64(t.Max.Y)-y2 < tileBorderMargin)
+
+			// Normalize coordinates in the full image
+			r.Box = DetectionBox{
+				X1: (x1 - float64(b.Min.X)) / float64(b.Dx()),
+				X2: (x2 - float64(b.Min.X)) / float64(b.Dx()),
+				Y1: (y1 - float64(b.Min.Y)) / float64(b.Dy()),
+				Y2: (y2 - float64(b.Min.Y)) / float64(b.Dy()),
+			}
+			trs = append(trs, tileResult{
+				DetectionResult: r,
+				tile:            idx,
+				truncated:       truncated,
+			})
+		}
+	}
+
+	// Stitch results across tiles and remove duplicates found in overlapping areas
+	for _, tr := range stitchTileResults(trs) {
+		rs = append(rs, tr.DetectionResult)
+	}
+	rs = mergeResults(rs, mergeIoUThreshold)
+	return
+}
+
+// tileRects splits the bounds into overlapping tiles
+func tileRects(b image.Rectangle, c ConfigurationTiles) (rs []image.Rectangle) {
+	// Get steps
+	stepX, stepY := c.Width-c.Overlap, c.Height-c.Overlap
+
+	// Loop through rows
+	for y := b.Min.Y; ; y += stepY {
+		// Make sure the last row is a full tile
+		y0 := y
+		if y0+c.Height > b.Max.Y {
+			y0 = int(math.Max(float64(b.Min.Y), float64(b.Max.Y-c.Height)))
+		}
+
+		// Loop through columns
+		for x := b.Min.X; ; x += stepX {
+			// Make sure the last column is a full tile
+			x0 := x
+			if x0+c.Width > b.Max.X {
+				x0 = int(math.Max(float64(b.Min.X), float64(b.Max.X-c.Width)))
+			}
+			rs = append(rs, image.Rect(x0, y0, x0+c.Width, y0+c.Height).Intersect(b))
+			if x0+c.Width >= b.Max.X {
+				break
+			}
+		}
+		if y0+c.Height >= b.Max.Y {
+			break
+		}
+	}
+	return
+}
+
+// stitchTileResults unions boxes with the same label coming from different tiles when at least one
+// of them has been truncated by its tile border, until no more boxes can be stitched
+func stitchTileResults(trs []tileResult) []tileResult {
+	for {
+		var stitched bool
+		for i := 0; i < len(trs) && !stitched; i++ {
+			for j := i + 1; j < len(trs); j++ {
+				// Check whether results can be stitched
+				a, b := trs[i], trs[j]
+				if a.tile == b.tile || a.Label != b.Label || (!a.truncated && !b.truncated) || !a.Box.Overlaps(b.Box) {
+					continue
+				}
+
+				// Stitch
+				trs[i] = tileResult{
+					DetectionResult: DetectionResult{
+						Box: DetectionBox{
+							X1: math.Min(a.Box.X1, b.Box.X1),
+							X2: math.Max(a.Box.X2, b.Box.X2),
+							Y1: math.Min(a.Box.Y1, b.Box.Y1),
+							Y2: math.Max(a.Box.Y2, b.Box.Y2),
+						},
+						Label:       a.Label,
+						Probability: math.Max(a.Probability, b.Probability),
+					},
+					tile: a.tile,
+				}
+				trs = append(trs[:j], trs[j+1:]...)
+				stitched = true
+				break
+			}
+		}
+		if !stitched {
+			return trs
+		}
+	}
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, r, img, r.Min, draw.Src)
+	return dst
+}
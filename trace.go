@@ -0,0 +1,24 @@
+package astiocr
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Spans are created with the global tracer provider which is a no-op unless set by the caller
+var tracer = otel.Tracer("github.com/asticode/go-astiocr")
+
+// startSpan starts a span that is ended when the returned func is called, in which case the error,
+// if any, is recorded
+func startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -1,53 +1,217 @@
 package astiocr
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/asticode/go-astitools/image"
-	"github.com/golang/freetype/truetype"
 	"github.com/pkg/errors"
+	ft "golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
 )
 
 // ConfigurationTrainer represents a trainer configuration
 type ConfigurationTrainer struct {
+	// Affine options
+	Affine ConfigurationAffine `toml:"affine"`
+
+	// Anonymization options
+	Anonymization ConfigurationAnonymization `toml:"anonymization"`
+
+	// Augmentation steps applied to rendered images, in order. Default is all the augmentations with the
+	// proportions of their sections.
+	Augmentations []ConfigurationAugmentation `toml:"augmentations"`
+
 	// Path to the cache directory
 	CacheDirectoryPath string `toml:"cache_directory_path"`
 
+	// Background images options
+	Backgrounds ConfigurationBackgrounds `toml:"backgrounds"`
+
+	// Blur options
+	Blur ConfigurationBlur `toml:"blur"`
+
+	// Box jitter options
+	BoxJitter ConfigurationBoxJitter `toml:"box_jitter"`
+
+	// Characters that are drawn and labeled, the nth character being the label of class n+1. "[:lower:]",
+	// "[:upper:]", "[:digit:]" and "[:punct:]" are replaced with their characters. Default is letters,
+	// digits and common punctuation. Set it to train a model on digits only, hex or custom symbols.
+	Charset string `toml:"charset"`
+
 	// Number of images generated for both training and test purposes
 	Count int `toml:"count"`
 
 	// Color options
 	Colors []ConfigurationColor `toml:"colors"`
 
+	// Color jitter options
+	ColorJitter ConfigurationColorJitter `toml:"color_jitter"`
+
+	// Composite options
+	Composite ConfigurationComposite `toml:"composite"`
+
+	// Path to a text file, or to a directory of text files, whose sentences are drawn by the "paragraphs"
+	// strategy and whose words are drawn by the "words" strategy, which gives natural character
+	// frequencies. Characters outside the charset are removed and sentences made mostly of them are dropped.
+	CorpusPath string `toml:"corpus_path"`
+
+	// Decoration options
+	Decorations ConfigurationDecorations `toml:"decorations"`
+
+	// Elastic distortion options
+	Elastic ConfigurationElastic `toml:"elastic"`
+
+	// Whether fonts are mixed within images: "character" picks a random font per character and "word" per
+	// word (per character with the "grid" strategy), which matches UIs and composited video frames. Default
+	// is "none".
+	FontMixing string `toml:"font_mixing"`
+
+	// Font size options
+	FontSize ConfigurationFontSize `toml:"font_size"`
+
 	// Font options
 	Fonts []ConfigurationFont `toml:"fonts"`
 
+	// Gaussian noise options
+	GaussianNoise ConfigurationGaussianNoise `toml:"gaussian_noise"`
+
+	// Google fonts options
+	GoogleFonts ConfigurationGoogleFonts `toml:"google_fonts"`
+
+	// Gradient backgrounds options
+	Gradient ConfigurationGradient `toml:"gradient"`
+
+	// Hard negatives options
+	HardNegatives ConfigurationHardNegatives `toml:"hard_negatives"`
+
 	// Image options
 	Image ConfigurationImage `toml:"image"`
 
+	// JPEG artifacts options
+	JPEG ConfigurationJPEG `toml:"jpeg"`
+
+	// Letter spacing options
+	LetterSpacing ConfigurationLetterSpacing `toml:"letter_spacing"`
+
+	// Lighting options
+	Lighting ConfigurationLighting `toml:"lighting"`
+
+	// Logger, which can only be set programmatically. Nothing is logged by default.
+	Logger Logger `toml:"-"`
+
+	// The proportion of drawn characters that are mirrored or upside down. Those are not labeled which
+	// teaches the model not to detect reflected text. Characters that look like charset characters once
+	// flipped, such as "o" or "b" mirrored into "d", are left out.
+	MirroredProportion float64 `toml:"mirrored_proportion"`
+
+	// Outline options
+	Outline ConfigurationOutline `toml:"outline"`
+
 	// Path to the output directory
 	OutputDirectoryPath string `toml:"output_directory_path"`
 
+	// Perspective options
+	Perspective ConfigurationPerspective `toml:"perspective"`
+
+	// Path to a directory containing a sample of target images. If set, colors, font sizes and gaussian
+	// noise, unless font sizes or gaussian noise are configured, are derived from those images when
+	// gathering data.
+	ProfileDirectoryPath string `toml:"profile_directory_path"`
+
+	// Rotation options
+	Rotation ConfigurationRotation `toml:"rotation"`
+
 	// Path to the python binary
 	PythonBinaryPath string `toml:"python_binary_path"`
 
+	// Salt and pepper noise options
+	SaltAndPepperNoise ConfigurationSaltAndPepperNoise `toml:"salt_and_pepper_noise"`
+
 	// Path to the scripts directory
 	ScriptsDirectoryPath string `toml:"scripts_directory_path"`
 
+	// If set, the nth image is generated with seed + n, which makes gathering reproducible and lets images
+	// already in the store be reused instead of being generated again
+	Seed int64 `toml:"seed"`
+
+	// Drop shadow options
+	Shadow ConfigurationShadow `toml:"shadow"`
+
 	// Show box around labels
 	ShowBox bool `toml:"show_box"`
 
 	// Show entire grid
 	ShowGrid bool `toml:"show_grid"`
 
+	// Path to the directory where images are stored by content hash. Default is "<output_directory_path>/store".
+	StoreDirectoryPath string `toml:"store_directory_path"`
+
+	// Weights of the strategies used to generate images, indexed by name, each image being generated by a
+	// strategy picked randomly according to the weights. Use it instead of Strategy to mix strategies.
+	Strategies map[string]float64 `toml:"strategies"`
+
+	// Strategy used to generate images: "grid" draws isolated characters on a grid, "character" draws a
+	// single character per image, "words" draws lines of words, "paragraphs" lays out sentences in wrapped
+	// paragraphs, "curved" draws words along arcs and bezier curves, "free" scatters words of random sizes
+	// without overlaps, "composite" scatters them onto the composite images and "receipt" lays out receipts
+	// and invoices. Strategies registered with RegisterStrategy can be used as well. Default is "grid".
+	Strategy string `toml:"strategy"`
+
 	// Path to the tensorflow models directory
 	TensorFlowModelsDirectoryPath string `toml:"tensorflow_models_directory_path"`
 
 	// The proportion of test data in the generated images
 	TestDataProportion float64 `toml:"test_data_proportion"`
+
+	// Weights of the strategies used to generate test images, like Strategies. Default is the strategies of
+	// training images.
+	TestStrategies map[string]float64 `toml:"test_strategies"`
+
+	// Strategy used to generate test images, like Strategy. Default is the strategies of training images.
+	TestStrategy string `toml:"test_strategy"`
+
+	// Text opacity options
+	TextOpacity ConfigurationTextOpacity `toml:"text_opacity"`
+
+	// Procedural texture backgrounds options
+	Texture ConfigurationTexture `toml:"texture"`
+
+	// Integrity of the trained models archives, indexed by model name, which is checked after download
+	TrainedModelsIntegrity map[string]ConfigurationIntegrity `toml:"trained_models_integrity"`
+
+	// Path to a file containing words, whitespace separated, drawn by the "words" and "paragraphs"
+	// strategies. Words containing characters outside the charset are dropped. Default is words of the
+	// corpus, or random characters of the charset if there's no corpus.
+	WordlistPath string `toml:"wordlist_path"`
+
+	// Wordlists indexed by domain (e.g. player names, city names). Words are sampled from them, and from
+	// the wordlist path, the same way.
+	Wordlists map[string]ConfigurationWordlist `toml:"wordlists"`
+}
+
+// ConfigurationAnonymization represents an anonymization configuration
+type ConfigurationAnonymization struct {
+	// Path to the directory where the anonymized dataset is written. Default is "<output_directory_path>/anonymized".
+	OutputDirectoryPath string `toml:"output_directory_path"`
+
+	// Regexps matching sensitive strings. They are matched against the lines of each image, assembled from
+	// its boxes with words separated by a space, so that they can span several words.
+	Patterns []string `toml:"patterns"`
+}
+
+// ConfigurationBoxJitter represents a box jitter configuration
+// Jittering ground truth boxes simulates label noise which makes models robust to imperfect annotations.
+type ConfigurationBoxJitter struct {
+	// Maximum perturbation of each box edge, as a fraction of the box dimension
+	Max float64 `toml:"max"`
+
+	// The proportion of images whose boxes are all jittered
+	Proportion float64 `toml:"proportion"`
 }
 
 // ConfigurationColor represents a color configuration
@@ -56,10 +220,39 @@ type ConfigurationColor struct {
 	Fonts      []astiimage.RGBA `toml:"fonts"`
 }
 
+// ConfigurationDecorations represents a decorations configuration
+// Words drawn by the "words" and "paragraphs" strategies are struck through or underlined according to
+// the proportions, which teaches the model to ignore those lines instead of confusing them with glyph
+// strokes.
+type ConfigurationDecorations struct {
+	StrikethroughProportion float64 `toml:"strikethrough_proportion"`
+	UnderlineProportion     float64 `toml:"underline_proportion"`
+}
+
 // ConfigurationFont represents a font configuration
+// Glyphs are drawn with anti-aliasing unless aliased is true, without hinting unless hinting is "vertical" or
+// "full", and, if pixelation is greater than 1, at the font size divided by pixelation then upscaled by
+// pixelation without interpolation.
 type ConfigurationFont struct {
+	Aliased       bool    `toml:"aliased"`
 	File          string  `toml:"file"`
+	Hinting       string  `toml:"hinting"`
+	Pixelation    int     `toml:"pixelation"`
 	PositionRatio float64 `toml:"position_ratio"`
+	// Files of the other styles of the family, indexed by style (e.g. "bold", "italic")
+	Styles map[string]string `toml:"styles"`
+	// Styles synthesized from the main file: "bold", "oblique" or "bold_oblique"
+	SyntheticStyles []string `toml:"synthetic_styles"`
+}
+
+// ConfigurationFontSize represents a font size configuration
+// Font sizes are picked randomly between min and max, in pixels, following the distribution: "uniform",
+// "log_uniform" which picks small sizes more often, or "normal" which picks sizes close to the middle of
+// the range more often. Default is sizes between 12 and 17 following the "uniform" distribution.
+type ConfigurationFontSize struct {
+	Distribution string `toml:"distribution"`
+	Max          int    `toml:"max"`
+	Min          int    `toml:"min"`
 }
 
 // ConfigurationImage represents an image configuration
@@ -68,42 +261,183 @@ type ConfigurationImage struct {
 	Width  int `toml:"width"`
 }
 
+// ConfigurationOutline represents an outline configuration
+// Characters of the proportion of images are outlined, like in video captions and game HUDs, with a random
+// color and a random width between min and max, in pixels. Default colors are black and white, and default
+// width is 1.
+type ConfigurationOutline struct {
+	Colors     []astiimage.RGBA `toml:"colors"`
+	Proportion float64          `toml:"proportion"`
+	WidthMax   int              `toml:"width_max"`
+	WidthMin   int              `toml:"width_min"`
+}
+
+// ConfigurationRotation represents a rotation configuration
+// The text of each image is rotated around the image center by a random angle between image min and
+// image max, and each character is additionally rotated by a random angle between character min and
+// character max, in degrees counterclockwise, which makes the model tolerate slight camera tilt.
+type ConfigurationRotation struct {
+	CharacterMax float64 `toml:"character_max"`
+	CharacterMin float64 `toml:"character_min"`
+	ImageMax     float64 `toml:"image_max"`
+	ImageMin     float64 `toml:"image_min"`
+}
+
+// ConfigurationShadow represents a drop shadow configuration
+// A drop shadow is drawn behind the characters of the proportion of images, like in broadcast graphics and
+// subtitles, with random blur radius, offset and opacity between min and max. Blur and offsets are in
+// pixels, the offset being picked for both axes. Default is an offset between 1 and 3, no blur and an
+// opacity between 0.5 and 0.8.
+type ConfigurationShadow struct {
+	BlurMax    int     `toml:"blur_max"`
+	BlurMin    int     `toml:"blur_min"`
+	OffsetMax  int     `toml:"offset_max"`
+	OffsetMin  int     `toml:"offset_min"`
+	OpacityMax float64 `toml:"opacity_max"`
+	OpacityMin float64 `toml:"opacity_min"`
+	Proportion float64 `toml:"proportion"`
+}
+
+// ConfigurationTextOpacity represents a text opacity configuration
+// The text of the proportion of images is drawn at partial opacity over the background, like overlays and
+// watermarks, with a random opacity between min and max. Default is an opacity between 0.3 and 0.8.
+type ConfigurationTextOpacity struct {
+	Max        float64 `toml:"max"`
+	Min        float64 `toml:"min"`
+	Proportion float64 `toml:"proportion"`
+}
+
+// ConfigurationWordlist represents a wordlist configuration
+type ConfigurationWordlist struct {
+	// Path to a file containing words, whitespace separated
+	Path string `toml:"path"`
+
+	// Relative probability of sampling a word from this wordlist. Default is 1.
+	Weight float64 `toml:"weight"`
+}
+
+// ConfigurationLetterSpacing represents a letter spacing configuration
+// The extra spacing between consecutive characters of words is picked randomly between min and max, as a
+// fraction of the font size. Negative values make characters overlap, which teaches the model to separate
+// tightly packed text.
+type ConfigurationLetterSpacing struct {
+	Max float64 `toml:"max"`
+	Min float64 `toml:"min"`
+}
+
 // Trainer represents an object capable of training a model
 type Trainer struct {
-	cacheDirectoryPath            string
-	count                         int
-	colors                        []ConfigurationColor
-	fonts                         []*font
-	image                         ConfigurationImage
-	outputConfigDirectoryPath     string
-	outputDataDirectoryPath       string
-	outputDirectoryPath           string
-	outputOutputDirectoryPath      string
-	outputScriptsDirectoryPath    string
-	pythonBinaryPath              string
-	scriptsDirectoryPath          string
-	showBox                       bool
-	showGrid                      bool
-	tensorFlowModelsDirectoryPath string
-	testDataCount                 int
-	testDataProportion            float64
-	trainingDataCount             int
+	affine                           ConfigurationAffine
+	anonymizationOutputDirectoryPath string
+	anonymizationPatterns            []*regexp.Regexp
+	augmentations                    []ConfigurationAugmentation
+	augmenters                       []registeredAugmenter
+	backgroundPaths                  []string
+	backgrounds                      ConfigurationBackgrounds
+	blur                             ConfigurationBlur
+	boxJitter                        ConfigurationBoxJitter
+	cacheDirectoryPath               string
+	charset                          []rune
+	colorJitter                      ConfigurationColorJitter
+	colors                           []ConfigurationColor
+	compositePaths                   []string
+	corpusPath                       string
+	count                            int
+	decorations                      ConfigurationDecorations
+	elastic                          ConfigurationElastic
+	fontMixing                       string
+	fontSize                         ConfigurationFontSize
+	fontSizeConfigured               bool
+	fonts                            []*font
+	gaussianNoise                    ConfigurationGaussianNoise
+	googleFonts                      ConfigurationGoogleFonts
+	gradient                         ConfigurationGradient
+	gradientStops                    [][]gradientStop
+	hardNegatives                    ConfigurationHardNegatives
+	image                            ConfigurationImage
+	jpeg                             ConfigurationJPEG
+	l                                Logger
+	letterSpacing                    ConfigurationLetterSpacing
+	lighting                         ConfigurationLighting
+	mirroredProportion               float64
+	outline                          ConfigurationOutline
+	outputConfigDirectoryPath        string
+	outputDataDirectoryPath          string
+	outputDirectoryPath              string
+	outputOutputDirectoryPath        string
+	outputScriptsDirectoryPath       string
+	perspective                      ConfigurationPerspective
+	profileDirectoryPath             string
+	pythonBinaryPath                 string
+	rotation                         ConfigurationRotation
+	saltAndPepperNoise               ConfigurationSaltAndPepperNoise
+	scriptsDirectoryPath             string
+	seed                             int64
+	sentences                        [][]string
+	shadow                           ConfigurationShadow
+	showBox                          bool
+	showGrid                         bool
+	storeDirectoryPath               string
+	strategies                       strategyMix
+	tensorFlowModelsDirectoryPath    string
+	testDataCount                    int
+	testDataProportion               float64
+	testStrategies                   strategyMix
+	textOpacity                      ConfigurationTextOpacity
+	texture                          ConfigurationTexture
+	trainedModelsIntegrity           map[string]ConfigurationIntegrity
+	trainingDataCount                int
+	wordlists                        []wordlist
 }
 
 type font struct {
+	aliased       bool
 	body          []byte
-	font          *truetype.Font
+	bold          bool
+	font          *opentype.Font
+	hinting       ft.Hinting
 	name          string
+	oblique       bool
+	pixelation    int
 	positionRatio float64
+	style         string
 }
 
 // NewTrainer creates a new trainer
 func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 	// Init
 	t = &Trainer{
+		affine:                        c.Affine,
+		augmentations:                 append([]ConfigurationAugmentation(nil), c.Augmentations...),
+		backgrounds:                   c.Backgrounds,
+		blur:                          c.Blur,
+		boxJitter:                     c.BoxJitter,
+		colorJitter:                   c.ColorJitter,
+		corpusPath:                    c.CorpusPath,
+		decorations:                   c.Decorations,
+		elastic:                       c.Elastic,
+		fontSize:                      c.FontSize,
+		gaussianNoise:                 c.GaussianNoise,
+		googleFonts:                   c.GoogleFonts,
+		gradient:                      c.Gradient,
+		hardNegatives:                 c.HardNegatives,
+		jpeg:                          c.JPEG,
+		l:                             newLogger(c.Logger),
+		letterSpacing:                 c.LetterSpacing,
+		lighting:                      c.Lighting,
+		mirroredProportion:            c.MirroredProportion,
+		outline:                       c.Outline,
+		perspective:                   c.Perspective,
+		profileDirectoryPath:          c.ProfileDirectoryPath,
+		rotation:                      c.Rotation,
+		saltAndPepperNoise:            c.SaltAndPepperNoise,
+		seed:                          c.Seed,
+		shadow:                        c.Shadow,
 		showBox:                       c.ShowBox,
 		showGrid:                      c.ShowGrid,
 		tensorFlowModelsDirectoryPath: c.TensorFlowModelsDirectoryPath,
+		textOpacity:                   c.TextOpacity,
+		texture:                       c.Texture,
 	}
 
 	// Count
@@ -126,6 +460,263 @@ func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 		t.count++
 	}
 
+	// Charset
+	if t.charset, err = parseCharset(c.Charset); err != nil {
+		err = errors.Wrap(err, "astiocr: parsing charset failed")
+		return
+	}
+
+	// Backgrounds
+	if t.backgrounds.Proportion < 0 || t.backgrounds.Proportion > 100 || (t.backgrounds.Proportion > 0 && t.backgrounds.DirectoryPath == "") {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid backgrounds directory %s with proportion %v", t.backgrounds.DirectoryPath, t.backgrounds.Proportion))
+		return
+	}
+	if t.backgrounds.Proportion > 0 {
+		if t.backgroundPaths, err = listBackgrounds(t.backgrounds.DirectoryPath); err != nil {
+			err = errors.Wrap(err, "astiocr: listing backgrounds failed")
+			return
+		} else if len(t.backgroundPaths) == 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: no background image found in %s", t.backgrounds.DirectoryPath))
+			return
+		}
+	}
+
+	// Gradient
+	if len(t.gradient.Kinds) == 0 {
+		t.gradient.Kinds = []string{gradientKindLinear, gradientKindRadial}
+	}
+	for _, k := range t.gradient.Kinds {
+		if k != gradientKindLinear && k != gradientKindRadial {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid gradient kind %s", k))
+			return
+		}
+	}
+	for _, p := range t.gradient.Palettes {
+		var ss []gradientStop
+		if ss, err = parseGradientPalette(p); err != nil {
+			err = errors.Wrap(err, "astiocr: parsing gradient palette failed")
+			return
+		}
+		t.gradientStops = append(t.gradientStops, ss)
+	}
+	if t.gradient.Proportion < 0 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid gradient proportion %v", t.gradient.Proportion))
+		return
+	}
+
+	// Texture
+	if len(t.texture.Kinds) == 0 {
+		t.texture.Kinds = []string{textureKindCheckerboard, textureKindPerlin, textureKindScanlines, textureKindStripes}
+	}
+	for _, k := range t.texture.Kinds {
+		switch k {
+		case textureKindCheckerboard, textureKindPerlin, textureKindScanlines, textureKindStripes:
+		default:
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid texture kind %s", k))
+			return
+		}
+	}
+	if t.texture.Proportion < 0 || t.backgrounds.Proportion+t.gradient.Proportion+t.texture.Proportion > 100 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid background image, gradient and texture proportions %v, %v and %v", t.backgrounds.Proportion, t.gradient.Proportion, t.texture.Proportion))
+		return
+	}
+
+	// Gaussian noise
+	if err = t.gaussianNoise.validate(); err != nil {
+		return
+	}
+
+	// Blur
+	if err = t.blur.validate(); err != nil {
+		return
+	}
+
+	// Color jitter
+	if err = t.colorJitter.validate(); err != nil {
+		return
+	}
+
+	// Affine
+	if err = t.affine.validate(); err != nil {
+		return
+	}
+
+	// Elastic
+	if err = t.elastic.validate(); err != nil {
+		return
+	}
+
+	// JPEG
+	if err = t.jpeg.validate(); err != nil {
+		return
+	}
+
+	// Lighting
+	if err = t.lighting.validate(); err != nil {
+		return
+	}
+
+	// Perspective
+	if err = t.perspective.validate(); err != nil {
+		return
+	}
+
+	// Salt and pepper noise
+	if err = t.saltAndPepperNoise.validate(); err != nil {
+		return
+	}
+
+	// Augmentations
+	if err = validateAugmentations(t.augmentations); err != nil {
+		return
+	}
+
+	// Hard negatives
+	if t.hardNegatives.Characters == "" {
+		t.hardNegatives.Characters = defaultHardNegatives(t.charset)
+	}
+	for _, c := range t.hardNegatives.Characters {
+		for _, cc := range t.charset {
+			if c == cc {
+				err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: hard negative %q is in charset", c))
+				return
+			}
+		}
+	}
+	switch t.hardNegatives.Placement {
+	case "":
+		t.hardNegatives.Placement = hardNegativePlacementCell
+	case hardNegativePlacementCell, hardNegativePlacementTarget:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid hard negatives placement %s", t.hardNegatives.Placement))
+		return
+	}
+	if t.hardNegatives.Proportion < 0 || t.hardNegatives.Proportion > 100 || (t.hardNegatives.Proportion > 0 && t.hardNegatives.Characters == "") {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid hard negatives %q with proportion %v", t.hardNegatives.Characters, t.hardNegatives.Proportion))
+		return
+	}
+
+	// Strategies
+	if t.strategies, err = t.newStrategyMix(c.Strategy, c.Strategies); err != nil {
+		err = errors.Wrap(err, "astiocr: creating strategy mix failed")
+		return
+	}
+	t.testStrategies = t.strategies
+	if c.TestStrategy != "" || len(c.TestStrategies) > 0 {
+		if t.testStrategies, err = t.newStrategyMix(c.TestStrategy, c.TestStrategies); err != nil {
+			err = errors.Wrap(err, "astiocr: creating test strategy mix failed")
+			return
+		}
+	}
+
+	// Composite
+	if t.usesStrategy(strategyComposite) {
+		if len(c.Composite.DirectoryPath) == 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: composite directory path is required by the composite strategy"))
+			return
+		}
+		if t.compositePaths, err = listBackgrounds(c.Composite.DirectoryPath); err != nil {
+			err = errors.Wrap(err, "astiocr: listing composite images failed")
+			return
+		} else if len(t.compositePaths) == 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: no composite image found in %s", c.Composite.DirectoryPath))
+			return
+		}
+	}
+
+	// Font mixing
+	switch t.fontMixing = c.FontMixing; t.fontMixing {
+	case "":
+		t.fontMixing = fontMixingNone
+	case fontMixingCharacter, fontMixingNone, fontMixingWord:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid font mixing %s", t.fontMixing))
+		return
+	}
+
+	// Font size
+	switch t.fontSize.Distribution {
+	case "":
+		t.fontSize.Distribution = fontSizeDistributionUniform
+	case fontSizeDistributionLogUniform, fontSizeDistributionNormal, fontSizeDistributionUniform:
+	default:
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid font size distribution %s", t.fontSize.Distribution))
+		return
+	}
+	if t.fontSizeConfigured = t.fontSize.Min != 0 || t.fontSize.Max != 0; !t.fontSizeConfigured {
+		t.fontSize.Min, t.fontSize.Max = 12, 17
+	} else if t.fontSize.Min <= 0 || t.fontSize.Max < t.fontSize.Min {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid font size range [%d, %d]", t.fontSize.Min, t.fontSize.Max))
+		return
+	}
+
+	// Letter spacing
+	if t.letterSpacing.Min > t.letterSpacing.Max || t.letterSpacing.Min <= -1 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid letter spacing [%v, %v]", t.letterSpacing.Min, t.letterSpacing.Max))
+		return
+	}
+
+	// Rotation
+	if t.rotation.CharacterMin > t.rotation.CharacterMax || t.rotation.ImageMin > t.rotation.ImageMax {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid rotation: character range [%v, %v], image range [%v, %v]", t.rotation.CharacterMin, t.rotation.CharacterMax, t.rotation.ImageMin, t.rotation.ImageMax))
+		return
+	}
+
+	// Decorations
+	if t.decorations.StrikethroughProportion < 0 || t.decorations.UnderlineProportion < 0 || t.decorations.StrikethroughProportion+t.decorations.UnderlineProportion > 100 {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid decoration proportions %v and %v", t.decorations.StrikethroughProportion, t.decorations.UnderlineProportion))
+		return
+	}
+
+	// Outline
+	if len(t.outline.Colors) == 0 {
+		t.outline.Colors = []astiimage.RGBA{*astiimage.NewRGBA(0, 0, 0, 0xff), *astiimage.NewRGBA(0xff, 0xff, 0xff, 0xff)}
+	}
+	if t.outline.WidthMin == 0 && t.outline.WidthMax == 0 {
+		t.outline.WidthMin, t.outline.WidthMax = 1, 1
+	} else if t.outline.WidthMin <= 0 || t.outline.WidthMax < t.outline.WidthMin {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid outline width range [%d, %d]", t.outline.WidthMin, t.outline.WidthMax))
+		return
+	}
+
+	// Shadow
+	if t.shadow.OffsetMin == 0 && t.shadow.OffsetMax == 0 {
+		t.shadow.OffsetMin, t.shadow.OffsetMax = 1, 3
+	}
+	if t.shadow.OpacityMin == 0 && t.shadow.OpacityMax == 0 {
+		t.shadow.OpacityMin, t.shadow.OpacityMax = 0.5, 0.8
+	}
+	if t.shadow.BlurMin < 0 || t.shadow.BlurMax < t.shadow.BlurMin || t.shadow.OffsetMax < t.shadow.OffsetMin || t.shadow.OpacityMin < 0 || t.shadow.OpacityMax > 1 || t.shadow.OpacityMax < t.shadow.OpacityMin {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid shadow: blur range [%d, %d], offset range [%d, %d], opacity range [%v, %v]", t.shadow.BlurMin, t.shadow.BlurMax, t.shadow.OffsetMin, t.shadow.OffsetMax, t.shadow.OpacityMin, t.shadow.OpacityMax))
+		return
+	}
+
+	// Text opacity
+	if t.textOpacity.Min == 0 && t.textOpacity.Max == 0 {
+		t.textOpacity.Min, t.textOpacity.Max = 0.3, 0.8
+	}
+	if t.textOpacity.Proportion < 0 || t.textOpacity.Proportion > 100 || t.textOpacity.Min <= 0 || t.textOpacity.Max > 1 || t.textOpacity.Max < t.textOpacity.Min {
+		err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid text opacity proportion %v with range [%v, %v]", t.textOpacity.Proportion, t.textOpacity.Min, t.textOpacity.Max))
+		return
+	}
+
+	// Wordlists
+	if t.wordlists, err = t.loadWordlists(c.WordlistPath, c.Wordlists); err != nil {
+		err = errors.Wrap(err, "astiocr: loading wordlists failed")
+		return
+	}
+
+	// Corpus
+	if len(c.CorpusPath) > 0 {
+		if t.sentences, err = t.loadCorpus(c.CorpusPath); err != nil {
+			err = errors.Wrapf(err, "astiocr: loading corpus %s failed", c.CorpusPath)
+			return
+		} else if len(t.sentences) == 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: no sentence of %s is in the charset", c.CorpusPath))
+			return
+		}
+	}
+
 	// Colors
 	t.colors = c.Colors
 	if len(t.colors) == 0 {
@@ -145,31 +736,60 @@ func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 				return
 			}
 			nft := &font{
+				aliased:       f.Aliased,
 				body:          b,
 				name:          f.File,
+				pixelation:    f.Pixelation,
 				positionRatio: f.PositionRatio,
+				style:         fontStyleRegular,
 			}
 			if nft.positionRatio == 0 {
 				nft.positionRatio = 2.5
 			}
+			if nft.hinting, err = parseHinting(f.Hinting); err != nil {
+				err = errors.Wrapf(err, "astiocr: parsing hinting of font %s failed", f.File)
+				return
+			}
+			if nft.pixelation < 0 {
+				err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: pixelation of font %s must be positive", f.File))
+				return
+			}
 			t.fonts = append(t.fonts, nft)
+
+			// Add styles
+			var fs []*font
+			if fs, err = fontStyles(f, *nft); err != nil {
+				err = errors.Wrapf(err, "astiocr: getting styles of font %s failed", f.File)
+				return
+			}
+			t.fonts = append(t.fonts, fs...)
 		}
 	} else {
 		t.fonts = append(t.fonts, &font{
 			body:          gomono.TTF,
 			name:          "gomono",
 			positionRatio: 2.5,
+			style:         fontStyleRegular,
 		})
 	}
 
 	// Parse fonts
 	for _, f := range t.fonts {
-		if f.font, err = truetype.Parse(f.body); err != nil {
+		if f.font, err = parseFont(f.body); err != nil {
 			err = errors.Wrapf(err, "astiocr: parsing font %s failed", f.name)
 			return
 		}
 	}
 
+	// Make sure fonts contain the glyphs of all the charset characters, otherwise boxes would be labeled
+	// while nothing is drawn
+	for _, f := range t.fonts {
+		if missing := t.missingGlyphs(f); len(missing) > 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: font %s has no glyph for %q", f.name, missing))
+			return
+		}
+	}
+
 	// Image
 	t.image.Height = c.Image.Height
 	t.image.Width = c.Image.Width
@@ -180,6 +800,16 @@ func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 		t.image.Width = 640
 	}
 
+	// Anonymization patterns
+	for _, p := range c.Anonymization.Patterns {
+		var r *regexp.Regexp
+		if r, err = regexp.Compile(p); err != nil {
+			err = errors.Wrapf(err, "astiocr: compiling anonymization pattern %s failed", p)
+			return
+		}
+		t.anonymizationPatterns = append(t.anonymizationPatterns, r)
+	}
+
 	// Get current directory path
 	var cd string
 	if cd, err = os.Getwd(); err != nil {
@@ -197,6 +827,18 @@ func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 	t.outputOutputDirectoryPath = filepath.Join(t.outputDirectoryPath, "output")
 	t.outputScriptsDirectoryPath = filepath.Join(t.outputDirectoryPath, "scripts")
 
+	// Store directory path
+	t.storeDirectoryPath = c.StoreDirectoryPath
+	if len(t.storeDirectoryPath) == 0 {
+		t.storeDirectoryPath = filepath.Join(t.outputDirectoryPath, "store")
+	}
+
+	// Anonymization output directory path
+	t.anonymizationOutputDirectoryPath = c.Anonymization.OutputDirectoryPath
+	if len(t.anonymizationOutputDirectoryPath) == 0 {
+		t.anonymizationOutputDirectoryPath = filepath.Join(t.outputDirectoryPath, "anonymized")
+	}
+
 	// Python binary path
 	t.pythonBinaryPath = c.PythonBinaryPath
 	if len(t.pythonBinaryPath) == 0 {
@@ -209,6 +851,9 @@ func NewTrainer(c ConfigurationTrainer) (t *Trainer, err error) {
 		t.scriptsDirectoryPath = filepath.Join(cd, "scripts")
 	}
 
+	// Trained models integrity
+	t.trainedModelsIntegrity = c.TrainedModelsIntegrity
+
 	// Cache directory path
 	t.cacheDirectoryPath = c.CacheDirectoryPath
 	if len(t.cacheDirectoryPath) == 0 {
@@ -0,0 +1,308 @@
+package astiocr
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	ft "golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Length bounds of words made of random characters when no wordlist is provided
+const (
+	randomWordMaxLength = 8
+	randomWordMinLength = 2
+)
+
+// GatherSummaryWord represents a gather summary word whose characters are boxes of the summary image
+type GatherSummaryWord struct {
+	// Line drawn through or under the word, if any: "strikethrough" or "underline"
+	Decoration string `json:"decoration,omitempty"`
+	Label      string `json:"label"`
+	X0         int    `json:"x0"`
+	X1         int    `json:"x1"`
+	Y0         int    `json:"y0"`
+	Y1         int    `json:"y1"`
+}
+
+// loadWordlist reads the words of the file, whitespace separated, and drops words containing characters
+// outside the charset since they couldn't be labeled
+func (t *Trainer) loadWordlist(p string) (ws []string, err error) {
+	// Open file
+	var f *os.File
+	if f, err = os.Open(p); err != nil {
+		err = errors.Wrapf(err, "astiocr: opening %s failed", p)
+		return
+	}
+	defer f.Close()
+
+	// Loop through words
+	var dropped int
+	s := bufio.NewScanner(f)
+	s.Split(bufio.ScanWords)
+	for s.Scan() {
+		if w := s.Text(); t.inCharset(w) {
+			ws = append(ws, w)
+		} else {
+			dropped++
+		}
+	}
+	if err = s.Err(); err != nil {
+		err = errors.Wrapf(err, "astiocr: scanning %s failed", p)
+		return
+	}
+	t.l.Debugf("astiocr: %d words loaded from %s, %d dropped since they contain characters outside the charset", len(ws), p, dropped)
+	return
+}
+
+// inCharset checks whether all the characters of the string are in the charset
+func (t *Trainer) inCharset(s string) bool {
+	for _, r := range s {
+		if t.labelIndex(r) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// wordlist represents a domain wordlist. Fields are exported so that they're part of the generation
+// fingerprint.
+type wordlist struct {
+	Domain string
+	Path   string
+	Weight float64
+	Words  []string
+}
+
+// loadWordlists loads the wordlist located at the path, if any, and the domain wordlists, sorted by domain
+// so that sampling is reproducible
+func (t *Trainer) loadWordlists(p string, cs map[string]ConfigurationWordlist) (ws []wordlist, err error) {
+	// Add the wordlist located at the path to a copy of the domain wordlists
+	m := make(map[string]ConfigurationWordlist)
+	for d, c := range cs {
+		m[d] = c
+	}
+	if len(p) > 0 {
+		m[""] = ConfigurationWordlist{Path: p}
+	}
+	cs = m
+
+	// Sort domains
+	var ds []string
+	for d := range cs {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+
+	// Loop through domains
+	for _, d := range ds {
+		// Get weight
+		c := cs[d]
+		w := wordlist{
+			Domain: d,
+			Path:   c.Path,
+			Weight: c.Weight,
+		}
+		if w.Weight == 0 {
+			w.Weight = 1
+		} else if w.Weight < 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: invalid weight %v of wordlist %s", w.Weight, d))
+			return
+		}
+
+		// Load words
+		if w.Words, err = t.loadWordlist(c.Path); err != nil {
+			err = errors.Wrapf(err, "astiocr: loading wordlist %s failed", c.Path)
+			return
+		} else if len(w.Words) == 0 {
+			err = withKind(ErrConfigInvalid, fmt.Errorf("astiocr: no word of %s is in the charset", c.Path))
+			return
+		}
+		ws = append(ws, w)
+	}
+	return
+}
+
+// randomWord returns a random word of a wordlist picked according to the weights, or of the corpus if
+// there's no wordlist, or random characters of the charset if there's neither
+func (t *Trainer) randomWord() string {
+	// Wordlists
+	if len(t.wordlists) > 0 {
+		// Pick wordlist
+		var total float64
+		for _, w := range t.wordlists {
+			total += w.Weight
+		}
+		r := rand.Float64() * total
+		w := t.wordlists[len(t.wordlists)-1]
+		for _, l := range t.wordlists {
+			if r < l.Weight {
+				w = l
+				break
+			}
+			r -= l.Weight
+		}
+
+		// Pick word
+		return w.Words[rand.Intn(len(w.Words))]
+	}
+
+	// Corpus
+	if len(t.sentences) > 0 {
+		s := t.sentences[rand.Intn(len(t.sentences))]
+		return s[rand.Intn(len(s))]
+	}
+
+	// Random characters
+	rs := make([]rune, randomWordMinLength+rand.Intn(randomWordMaxLength-randomWordMinLength+1))
+	for idx := range rs {
+		rs[idx] = t.charset[rand.Intn(len(t.charset))]
+	}
+	return string(rs)
+}
+
+func (t *Trainer) createImageStrategyWords(p StrategyParams) (img *image.RGBA, si GatherSummaryImage) {
+	// Initialize parameters
+	fontSize, backgroundColor, fontColor, font := p.FontSize, p.BackgroundColor, p.FontColor, p.font
+	fc := newFaceCache(fontSize)
+	ascent, descent := t.lineMetrics(fc, font)
+	space := ft.MeasureString(fc.face(font), " ").Ceil()
+
+	// Create image
+	img, si = t.createImage(backgroundColor, font, t.image.Height, t.image.Width)
+
+	// Loop through lines separated by random gaps
+	margin := fontSize
+	for y := margin + ascent; y+descent <= t.image.Height-margin; y += (ascent + descent) * (1 + rand.Intn(3)) {
+		// Loop through words separated by random spaces
+		for x := margin + rand.Intn(4*space+1); ; x += space * (1 + rand.Intn(4)) {
+			// Word doesn't fit
+			rw := t.randomWord()
+			w := t.spaceWord(fc, rw, t.wordFonts(font, rw))
+			if x+w.width > t.image.Width-margin {
+				break
+			}
+
+			// Draw word
+			si.Words = append(si.Words, t.drawWord(img, fc, fontColor, x, y, w, &si))
+			x += w.width
+		}
+	}
+	return
+}
+
+// spacedWord represents a word whose characters are separated by extra spacings
+type spacedWord struct {
+	// Font of each character
+	fonts []*font
+	// Extra spacing after each character but the last one
+	spacings []fixed.Int26_6
+	width    int
+	word     string
+}
+
+// spaceWord picks random letter spacings, as configured, and measures the word drawn with the fonts
+func (t *Trainer) spaceWord(c *faceCache, w string, fs []*font) (sw spacedWord) {
+	// Loop through characters
+	sw.fonts = fs
+	sw.word = w
+	var a fixed.Int26_6
+	rs := []rune(w)
+	for idx, r := range rs {
+		// Kern and space
+		face := c.face(fs[idx])
+		if idx > 0 {
+			if fs[idx] == fs[idx-1] {
+				a += face.Kern(rs[idx-1], r)
+			}
+			s := fixed.Int26_6(math.Round((t.letterSpacing.Min + rand.Float64()*(t.letterSpacing.Max-t.letterSpacing.Min)) * float64(c.fontSize) * 64))
+			sw.spacings = append(sw.spacings, s)
+			a += s
+		}
+
+		// Advance
+		adv, _ := face.GlyphAdvance(r)
+		a += adv
+	}
+	sw.width = a.Ceil()
+	return
+}
+
+// drawWord draws the word with its baseline starting at (x, y), adds the tight box of each character
+// glyph to the summary and returns the word box which contains them all
+func (t *Trainer) drawWord(img draw.Image, c *faceCache, fontColor color.Color, x, y int, w spacedWord, si *GatherSummaryImage) (sw GatherSummaryWord) {
+	// Create drawer
+	d := &ft.Drawer{
+		Dst: img,
+		Src: image.NewUniform(inkColor(fontColor, si)),
+		Dot: fixed.P(x, y),
+	}
+
+	// Loop through characters
+	var wr image.Rectangle
+	rs := []rune(w.word)
+	for idx, r := range rs {
+		// Kern and space
+		d.Face = c.face(w.fonts[idx])
+		if idx > 0 {
+			if w.fonts[idx] == w.fonts[idx-1] {
+				d.Dot.X += d.Face.Kern(rs[idx-1], r)
+			}
+			d.Dot.X += w.spacings[idx-1]
+		}
+
+		// Draw character
+		var angle float64
+		var b image.Rectangle
+		if t.rotates() {
+			g := t.rotatedGlyph(d.Face, w.fonts[idx], r, float64(d.Dot.X)/64, float64(d.Dot.Y)/64, si)
+			b, angle = drawGlyph(img, g, fontColor, si), -g.angle*180/math.Pi
+			adv, _ := d.Face.GlyphAdvance(r)
+			d.Dot.X += adv
+		} else {
+			gb, _ := d.BoundString(string(r))
+			drawStringEffects(d, string(r), si)
+			d.DrawString(string(r))
+			b = glyphRect(gb).Intersect(img.Bounds())
+		}
+
+		// Glyph has no ink
+		if b.Empty() {
+			continue
+		}
+		wr = wr.Union(b)
+
+		// Show box
+		if t.showBox {
+			t.drawBox(b.Min.X, b.Max.X, b.Min.Y, b.Max.Y, img, fontColor)
+		}
+
+		// Add box to summary
+		sb := t.summaryBox(string(r), t.labelIndex(r), c.fontSize, b, w.fonts[idx])
+		sb.Angle = angle
+		si.Boxes = append(si.Boxes, sb)
+	}
+
+	// Get word box
+	sw = GatherSummaryWord{
+		Label: w.word,
+		X0:    wr.Min.X,
+		X1:    wr.Max.X,
+		Y0:    wr.Min.Y,
+		Y1:    wr.Max.Y,
+	}
+
+	// Decorate. Boxes remain tight around the glyphs so that the model learns to ignore decorations.
+	if sw.Decoration = t.randomDecoration(); len(sw.Decoration) > 0 {
+		t.drawDecoration(img, c, w.fonts[0], fontColor, sw.Decoration, x, x+w.width, y, si)
+	}
+	return
+}